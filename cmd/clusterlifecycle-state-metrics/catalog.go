@@ -0,0 +1,72 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
+)
+
+const catalogCommand = "metrics-catalog"
+
+// runCatalog writes the catalog of the registered metric families in Markdown
+// or JSON.
+func runCatalog(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(catalogCommand, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s %s: [flags]\n", os.Args[0], catalogCommand)
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "markdown", "The format of the catalog (markdown|json).")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	families := generators.RegisteredFamilies()
+	switch *format {
+	case "markdown":
+		return writeMarkdownCatalog(out, families)
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(families)
+	default:
+		return fmt.Errorf("unsupported format %q, expected markdown or json", *format)
+	}
+}
+
+// writeMarkdownCatalog writes a table of the metric families of each collector.
+func writeMarkdownCatalog(out io.Writer, families []generators.FamilyDesc) error {
+	b := &strings.Builder{}
+	collector := ""
+	for _, desc := range families {
+		if desc.Collector != collector {
+			if collector != "" {
+				b.WriteString("\n")
+			}
+			collector = desc.Collector
+			fmt.Fprintf(b, "### %s\n\n", collector)
+			b.WriteString("| Name | Type | Stability | Labels | Help |\n")
+			b.WriteString("| ---- | ---- | --------- | ------ | ---- |\n")
+		}
+
+		labels := []string{}
+		for _, label := range desc.Labels {
+			labels = append(labels, "`"+label+"`")
+		}
+		if desc.VariableLabels != "" {
+			labels = append(labels, desc.VariableLabels)
+		}
+		fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s |\n",
+			desc.Name, desc.Type, desc.Stability, strings.Join(labels, ", "), strings.ReplaceAll(desc.Help, "|", `\|`))
+	}
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
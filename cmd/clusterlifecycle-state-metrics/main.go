@@ -6,6 +6,7 @@ package main
 import (
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"io"
@@ -20,27 +21,36 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
 	koptions "k8s.io/kube-state-metrics/pkg/options"
 	"k8s.io/kube-state-metrics/pkg/whiteblacklist"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 	workv1 "open-cluster-management.io/api/work/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/stolostron/cluster-lifecycle-api/helpers/tlsprofile"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/auth"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/collectors"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/controllers"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/options"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/version"
 )
@@ -49,11 +59,16 @@ const (
 	leaderConfigMapName = "clusterlifecycle-state-metrics-lock"
 	metricsPath         = "/metrics"
 	healthzPath         = "/healthz"
+	readyzPath          = "/readyz"
 
-	hubTypeMCE              = "mce"
-	hubTypeACM              = "acm"
-	hubTypeStolostronEngine = "stolostron-engine"
-	hubTypeStolostron       = "stolostron"
+	// the query parameters of the tenant views of the metrics
+	clusterSetParam = "clusterset"
+	namespaceParam  = "namespace"
+
+	hubTypeMCE              = collectors.HubTypeMCE
+	hubTypeACM              = collectors.HubTypeACM
+	hubTypeStolostronEngine = collectors.HubTypeStolostronEngine
+	hubTypeStolostron       = collectors.HubTypeStolostron
 
 	configMapName = "clusterlifecycle-state-metrics-config"
 )
@@ -88,6 +103,19 @@ func (pl promLogger) Println(v ...interface{}) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == renderCommand {
+		if err := runRender(os.Args[2:], os.Stdout); err != nil {
+			klog.Fatalf("cannot render the metrics: %v", err)
+		}
+		os.Exit(0)
+	}
+	if len(os.Args) > 1 && os.Args[1] == catalogCommand {
+		if err := runCatalog(os.Args[2:], os.Stdout); err != nil {
+			klog.Fatalf("cannot write the metrics catalog: %v", err)
+		}
+		os.Exit(0)
+	}
+
 	opts.Parse()
 
 	if opts.Version {
@@ -116,13 +144,38 @@ func start(opts *options.Options) {
 		klog.Fatalf("cannot create kubeClient: %v", err)
 	}
 
-	timestampMetricsEnabled, err := isTimestampMetricsEnabled(ctx, kubeClient)
+	cfg, err := loadConfig(ctx, kubeClient, opts.Config)
+	if err != nil {
+		klog.Fatalf("cannot load the configuration: %v", err)
+	}
+	opts.ApplyConfig(cfg)
+
+	timestampMetricsEnabled, err := isTimestampMetricsEnabled(ctx, kubeClient, opts.TimestampMetricsEnabled)
 	if err != nil {
 		klog.Fatalf("cannot determine if timestamp metrics should be enabled: %v", err)
 	}
 
+	if opts.Once {
+		if err := runOnce(ctx, config, kubeClient, timestampMetricsEnabled, opts); err != nil {
+			klog.Fatalf("cannot take a snapshot of the metrics: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	hubKubeconfigs, err := listHubKubeconfigs(opts.HubKubeconfigs, opts.HubKubeconfigDir)
+	if err != nil {
+		klog.Fatalf("cannot list the kubeconfigs of the hubs: %v", err)
+	}
+	timestampMetricsEnabled, err = hubTimestampMetricsEnabled(hubKubeconfigs, timestampMetricsEnabled, opts.TimestampMetricsEnabled)
+	if err != nil {
+		klog.Fatalf("cannot enable the timestamp metrics: %v", err)
+	}
+
+	controllerRunner := controllers.NewRunner(func(ctx context.Context, setup func(mgr ctrl.Manager) error) {
+		startControllers(ctx, opts, setup)
+	})
 	if timestampMetricsEnabled {
-		go startControllers(opts)
+		controllerRunner.Start(ctx)
 	}
 
 	// Start TLS profile watcher to detect changes and trigger graceful restart via context cancellation
@@ -130,10 +183,151 @@ func start(opts *options.Options) {
 		klog.Fatalf("Failed to start TLS profile watcher: %v", err)
 	}
 
-	collectorBuilder := collectors.NewBuilder(ctx)
-	collectorBuilder.WithRestConfig(config).
-		WithKubeclient(kubeClient).
-		WithTimestampMetricsEnabled(timestampMetricsEnabled)
+	// In the multi-hub mode, the union of the metrics of the hubs is served. The
+	// tenant views are not served since the managed clusters of the hubs may
+	// have the same names.
+	var builders []*collectors.Builder
+	var hubs []collectors.Hub
+	var hasSynced func() bool
+	var clusterFilter func(clusterSet, namespace string) collectors.ClusterFilter
+	if len(hubKubeconfigs) > 0 {
+		builders, hubs = newHubBuilders(ctx, hubKubeconfigs, opts)
+		hasSynced = collectors.AnyHubSynced(hubs)
+	} else {
+		collectorBuilder := collectors.NewBuilder(ctx)
+		collectorBuilder.WithRestConfig(config).
+			WithKubeclient(kubeClient).
+			WithTimestampMetricsEnabled(timestampMetricsEnabled).
+			WithRefreshWorkers(opts.RefreshWorkers)
+		configureBuilder(collectorBuilder, opts)
+		builders = []*collectors.Builder{collectorBuilder}
+		hasSynced = collectorBuilder.HasSynced
+		clusterFilter = collectorBuilder.ClusterFilter
+	}
+
+	ocmMetricsRegistry := prometheus.NewRegistry()
+	for _, metric := range collectors.TelemetryMetrics() {
+		if err := ocmMetricsRegistry.Register(metric); err != nil {
+			panic(err)
+		}
+	}
+	for _, metric := range collectors.RefreshQueueMetrics() {
+		if err := ocmMetricsRegistry.Register(metric); err != nil {
+			panic(err)
+		}
+	}
+	if err := ocmMetricsRegistry.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})); err != nil {
+		panic(err)
+	}
+	if err := ocmMetricsRegistry.Register(prometheus.NewGoCollector()); err != nil {
+		panic(err)
+	}
+	if hubs != nil {
+		if err := ocmMetricsRegistry.Register(collectors.NewHubHealthCollector(hubs)); err != nil {
+			panic(err)
+		}
+	}
+	// The health probes are served without authentication, so the kubelet can probe them
+	var authFilter *auth.Filter
+	if opts.EnableAuth {
+		klog.Info("Authenticating and authorizing the requests to the https ports")
+		authFilter = auth.NewFilter(
+			auth.NewTokenReviewAuthenticator(kubeClient, nil, opts.AuthCacheTTL),
+			auth.NewSubjectAccessReviewAuthorizer(kubeClient, opts.AuthCacheTTL, opts.AuthDenyCacheTTL),
+			healthzPath, readyzPath,
+		).WithAttributesFunc(tenantAttributes).
+			WithClientCertificateUser(opts.ClientCertificateUser)
+	}
+
+	// Watch the serving certificate, so a rotated certificate is served without a restart
+	var certWatcher *certwatcher.CertWatcher
+	if opts.TLSCrtFile != "" && opts.TLSKeyFile != "" {
+		certWatcher, err = certwatcher.New(opts.TLSCrtFile, opts.TLSKeyFile)
+		if err != nil {
+			klog.Fatalf("cannot load the serving certificate: %v", err)
+		}
+		go func() {
+			if err := certWatcher.Start(ctx); err != nil {
+				klog.Errorf("cannot watch the serving certificate: %v", err)
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		telemetryServer(ctx, ocmMetricsRegistry, config, opts.TelemetryHost, opts.HTTPTelemetryPort, opts.HTTPSTelemetryPort, certWatcher,
+			opts.ClientCAFile, opts.AllowedClientNames, hasSynced, authFilter)
+	}()
+
+	var metricsCollectors []collectors.MetricsCollector
+	if hubs != nil {
+		for _, collectorBuilder := range builders {
+			go buildHub(ctx, collectorBuilder)
+		}
+		metricsCollectors = []collectors.MetricsCollector{collectors.NewUnionCollector(hubs)}
+	} else {
+		metricsCollectors, err = builders[0].Build()
+		if err != nil {
+			klog.Fatalf("cannot build the collectors: %v", err)
+		}
+	}
+
+	// enable or disable the timestamp metrics at runtime once the ConfigMap is changed,
+	// unless the timestamp metrics are set by the configuration file or the
+	// metrics of several hubs are served
+	namespace, err := GetComponentNamespace()
+	if err == nil && len(hubKubeconfigs) == 0 && (opts.Config == "" || opts.TimestampMetricsEnabled == nil) {
+		watchConfigMap(ctx, kubeClient, namespace, func(cm *corev1.ConfigMap) {
+			enabled, err := isTimestampMetricsEnabledByConfigMap(cm)
+			if err != nil {
+				klog.Errorf("cannot determine if timestamp metrics should be enabled: %v", err)
+				return
+			}
+			for _, collectorBuilder := range builders {
+				if err := collectorBuilder.SetTimestampMetricsEnabled(enabled); err != nil {
+					klog.Errorf("cannot set timestamp metrics enabled: %v", err)
+				}
+			}
+			if enabled {
+				controllerRunner.Start(ctx)
+			} else {
+				controllerRunner.Stop()
+			}
+		})
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		serveMetrics(ctx, metricsCollectors, config, opts.Host, opts.HTTPPort, opts.HTTPSPort, certWatcher,
+			opts.ClientCAFile, opts.AllowedClientNames, opts.EnableGZIPEncoding,
+			hasSynced, opts.WaitForCacheSync, clusterFilter, authFilter)
+	}()
+
+	// Wait for both servers to complete graceful shutdown
+	// (they handle ctx.Done() internally and shutdown when context is cancelled)
+	wg.Wait()
+
+	klog.Info("Servers shut down, exiting...")
+	// Exit cleanly so Kubernetes can restart the pod
+	os.Exit(0)
+}
+
+// configureBuilder configures the collectors built by the builder with the
+// options shared by the server and the render command.
+func configureBuilder(collectorBuilder *collectors.Builder, opts *options.Options) {
+	collectorBuilder.WithSelectors(opts.Selectors).
+		WithManagedClusterLabelOptions(managedClusterLabelOptions(opts.ManagedClusterLabels)).
+		WithManagedClusterAnnotationAllowlist(opts.ManagedClusterAnnotationAllowlist).
+		WithManagedClusterInfoOptions(cluster.InfoOptions{
+			CreatedVia:  cluster.ValueMapping(opts.ManagedClusterInfo.CreatedVia),
+			ServiceName: cluster.ValueMapping(opts.ManagedClusterInfo.ServiceName),
+		}).
+		WithCustomResources(opts.CustomResources).
+		WithSeriesLimits(opts.SeriesLimits)
 	if len(opts.Collectors) == 0 {
 		klog.Info("Using default collectors")
 		collectorBuilder.WithEnabledCollectors(options.DefaultCollectors.AsSlice())
@@ -157,7 +351,8 @@ func start(opts *options.Options) {
 	case hubTypeMCE, hubTypeACM, hubTypeStolostronEngine, hubTypeStolostron:
 		collectorBuilder.WithHubType(opts.HubType)
 	case "":
-		// do nothing if not specified
+		// detect the hub type from the hub if not specified
+		collectorBuilder.WithHubTypeDetection()
 	default:
 		klog.Fatal(fmt.Errorf("invalid hub type %q", opts.HubType))
 	}
@@ -173,46 +368,11 @@ func start(opts *options.Options) {
 	klog.Infof("metric white-black listing: %v", whiteBlackList.Status())
 
 	collectorBuilder.WithWhiteBlackList(whiteBlackList)
-
-	ocmMetricsRegistry := prometheus.NewRegistry()
-	if err := ocmMetricsRegistry.Register(collectors.ResourcesPerScrapeMetric); err != nil {
-		panic(err)
-	}
-	if err := ocmMetricsRegistry.Register(collectors.ScrapeErrorTotalMetric); err != nil {
-		panic(err)
-	}
-	if err := ocmMetricsRegistry.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})); err != nil {
-		panic(err)
-	}
-	if err := ocmMetricsRegistry.Register(prometheus.NewGoCollector()); err != nil {
-		panic(err)
-	}
-	var wg sync.WaitGroup
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		telemetryServer(ctx, ocmMetricsRegistry, config, opts.TelemetryHost, opts.HTTPTelemetryPort, opts.HTTPSTelemetryPort, opts.TLSCrtFile, opts.TLSKeyFile)
-	}()
-
-	collectors := collectorBuilder.Build()
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		serveMetrics(ctx, collectors, config, opts.Host, opts.HTTPPort, opts.HTTPSPort, opts.TLSCrtFile, opts.TLSKeyFile, opts.EnableGZIPEncoding)
-	}()
-
-	// Wait for both servers to complete graceful shutdown
-	// (they handle ctx.Done() internally and shutdown when context is cancelled)
-	wg.Wait()
-
-	klog.Info("Servers shut down, exiting...")
-	// Exit cleanly so Kubernetes can restart the pod
-	os.Exit(0)
 }
 
-func startControllers(opts *options.Options) {
+// startControllers runs the controller manager with the controllers set up by
+// setup until the context is done.
+func startControllers(ctx context.Context, opts *options.Options, setup func(mgr ctrl.Manager) error) {
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -232,7 +392,7 @@ func startControllers(opts *options.Options) {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		LeaderElectionReleaseOnCancel: true,
-		Cache: cache.Options{
+		Cache: ctrlcache.Options{
 			DefaultTransform: func(obj interface{}) (interface{}, error) {
 				mw, ok := obj.(*workv1.ManifestWork)
 				if !ok {
@@ -261,16 +421,14 @@ func startControllers(opts *options.Options) {
 		os.Exit(1)
 	} // speeds up voluntary leader transitions as the new leader don't have to wait
 
-	now := time.Now()
-	if err = controllers.NewManifestworkReconciler(mgr.GetClient(), now).SetupWithManager(mgr); err != nil {
+	if err = setup(mgr); err != nil {
 		logf.Log.Error(err, "unable to create controller", "controller", "ManifestWork")
 		os.Exit(1)
 	}
-	logf.Log.Info("The manifestwork controller start time has been set", "startTime", now)
 
 	// +kubebuilder:scaffold:builder
 
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		logf.Log.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -283,14 +441,17 @@ func telemetryServer(
 	host string,
 	httpPort int,
 	httpsPort int,
-	tlsCrtFile string,
-	tlsKeyFile string,
+	certWatcher *certwatcher.CertWatcher,
+	clientCAFile string,
+	allowedClientNames []string,
+	hasSynced func() bool,
+	authFilter *auth.Filter,
 ) {
 
 	mux := http.NewServeMux()
 
 	// Add metricsPath
-	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: promLogger{}}))
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: promLogger{}, EnableOpenMetrics: true}))
 	// Add healthzPath
 	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -298,6 +459,8 @@ func telemetryServer(
 			panic(err)
 		}
 	})
+	// Add readyzPath
+	mux.HandleFunc(readyzPath, readyzHandler(hasSynced))
 	// Add index
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if _, err := w.Write([]byte(`<html>
@@ -314,18 +477,23 @@ func telemetryServer(
 	})
 	var servers []*http.Server
 
-	if tlsCrtFile != "" && tlsKeyFile != "" {
-		// Get TLS config from OpenShift cluster profile
-		tlsConfig, err := tlsprofile.GetTLSConfig(kubeConfig)
+	if certWatcher != nil {
+		tlsConfig, err := newTLSConfig(kubeConfig, clientCAFile, allowedClientNames)
 		if err != nil {
 			klog.Fatalf("Failed to get TLS config: %v", err)
 		}
+		// Serve the certificate reloaded by the watcher once it is rotated
+		tlsConfig.GetCertificate = certWatcher.GetCertificate
 
 		// Address to listen on for web interface and telemetry
 		listenAddress := net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		var httpsHandler http.Handler = mux
+		if authFilter != nil {
+			httpsHandler = authFilter.Wrap(mux)
+		}
 		httpsServer := &http.Server{
 			Addr:      listenAddress,
-			Handler:   mux,
+			Handler:   httpsHandler,
 			TLSConfig: tlsConfig,
 		}
 		servers = append(servers, httpsServer)
@@ -333,7 +501,7 @@ func telemetryServer(
 		klog.Infof("Starting clusterlifecycle-state-metrics self metrics server: %s", listenAddress)
 		klog.Infof("Listening https: %s", listenAddress)
 		go func() {
-			if err := httpsServer.ListenAndServeTLS(tlsCrtFile, tlsKeyFile); err != nil && err != http.ErrServerClosed {
+			if err := httpsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 				klog.Errorf("HTTPS telemetry server error: %v", err)
 			}
 		}()
@@ -378,9 +546,14 @@ func serveMetrics(
 	host string,
 	httpPort int,
 	httpsPort int,
-	tlsCrtFile string,
-	tlsKeyFile string,
-	enableGZIPEncoding bool) {
+	certWatcher *certwatcher.CertWatcher,
+	clientCAFile string,
+	allowedClientNames []string,
+	enableGZIPEncoding bool,
+	hasSynced func() bool,
+	waitForCacheSync bool,
+	clusterFilter func(clusterSet, namespace string) collectors.ClusterFilter,
+	authFilter *auth.Filter) {
 
 	mux := http.NewServeMux()
 
@@ -391,7 +564,11 @@ func serveMetrics(
 	mux.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 
 	// Add metricsPath
-	mux.Handle(metricsPath, &metricHandler{collectors, enableGZIPEncoding})
+	handler := &metricHandler{collectors: collectors, enableGZIPEncoding: enableGZIPEncoding, clusterFilter: clusterFilter}
+	if waitForCacheSync {
+		handler.hasSynced = hasSynced
+	}
+	mux.Handle(metricsPath, handler)
 	// Add healthzPath
 	mux.HandleFunc(healthzPath, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(200)
@@ -399,6 +576,8 @@ func serveMetrics(
 			panic(err)
 		}
 	})
+	// Add readyzPath
+	mux.HandleFunc(readyzPath, readyzHandler(hasSynced))
 	// Add index
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if _, err := w.Write([]byte(`<html>
@@ -408,6 +587,7 @@ func serveMetrics(
 			 <ul>
              <li><a href='` + metricsPath + `'>metrics</a></li>
              <li><a href='` + healthzPath + `'>healthz</a></li>
+             <li><a href='` + readyzPath + `'>readyz</a></li>
 			 </ul>
              </body>
              </html>`)); err != nil {
@@ -417,18 +597,23 @@ func serveMetrics(
 
 	var servers []*http.Server
 
-	if tlsCrtFile != "" && tlsKeyFile != "" {
-		// Get TLS config from OpenShift cluster profile
-		tlsConfig, err := tlsprofile.GetTLSConfig(kubeConfig)
+	if certWatcher != nil {
+		tlsConfig, err := newTLSConfig(kubeConfig, clientCAFile, allowedClientNames)
 		if err != nil {
 			klog.Fatalf("Failed to get TLS config: %v", err)
 		}
+		// Serve the certificate reloaded by the watcher once it is rotated
+		tlsConfig.GetCertificate = certWatcher.GetCertificate
 
 		// Address to listen on for web interface and telemetry
 		listenAddress := net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		var httpsHandler http.Handler = mux
+		if authFilter != nil {
+			httpsHandler = authFilter.Wrap(mux)
+		}
 		httpsServer := &http.Server{
 			Addr:      listenAddress,
-			Handler:   mux,
+			Handler:   httpsHandler,
 			TLSConfig: tlsConfig,
 		}
 		servers = append(servers, httpsServer)
@@ -436,7 +621,7 @@ func serveMetrics(
 		klog.Infof("Starting metrics server: %s", listenAddress)
 		klog.Infof("Listening https: %s", listenAddress)
 		go func() {
-			if err := httpsServer.ListenAndServeTLS(tlsCrtFile, tlsKeyFile); err != nil && err != http.ErrServerClosed {
+			if err := httpsServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 				klog.Errorf("HTTPS metrics server error: %v", err)
 			}
 		}()
@@ -474,16 +659,80 @@ func serveMetrics(
 	klog.Info("Metrics servers stopped")
 }
 
+// newTLSConfig returns the TLS config of the https servers. The ciphers and
+// versions are from the OpenShift cluster profile. If the clientCAFile is set,
+// the servers require a client certificate signed by the CA.
+func newTLSConfig(kubeConfig *rest.Config, clientCAFile string, allowedClientNames []string) (*tls.Config, error) {
+	tlsConfig, err := tlsprofile.GetTLSConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	if clientCAFile != "" {
+		if err := auth.ConfigureClientCertificates(tlsConfig, clientCAFile, allowedClientNames); err != nil {
+			return nil, err
+		}
+	}
+	return tlsConfig, nil
+}
+
+// readyzHandler reports not ready until every watched resource has completed
+// its initial list.
+func readyzHandler(hasSynced func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasSynced() {
+			http.Error(w, "caches are not synced", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(200)
+		if _, err := w.Write([]byte("ok")); err != nil {
+			panic(err)
+		}
+	}
+}
+
 type metricHandler struct {
 	collectors         []collectors.MetricsCollector
 	enableGZIPEncoding bool
+	// hasSynced is set to respond 503 until every watched resource has completed
+	// its initial list, so partial metrics are never served.
+	hasSynced func() bool
+	// clusterFilter returns the filter of a tenant view of the metrics. The
+	// tenant views are not served if it is nil.
+	clusterFilter func(clusterSet, namespace string) collectors.ClusterFilter
 }
 
 func (m *metricHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.hasSynced != nil && !m.hasSynced() {
+		http.Error(w, "caches are not synced", http.StatusServiceUnavailable)
+		return
+	}
+
+	clusterSet, namespace, err := tenantView(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The tenant views are only served to the users authorized to see them, so
+	// they are never served on the http ports or without --enable-auth
+	if clusterSet != "" || namespace != "" {
+		if _, ok := auth.UserFrom(r.Context()); !ok {
+			http.Error(w, "the tenant views of the metrics require an authorized user", http.StatusForbidden)
+			return
+		}
+	}
+	var filter collectors.ClusterFilter
+	if m.clusterFilter != nil {
+		filter = m.clusterFilter(clusterSet, namespace)
+	} else if clusterSet != "" || namespace != "" {
+		http.Error(w, "the tenant views of the metrics are not served", http.StatusBadRequest)
+		return
+	}
+
 	resHeader := w.Header()
 	var writer io.Writer = w
 
-	resHeader.Set("Content-Type", `text/plain; version=`+"0.0.4")
+	format := collectors.NegotiateFormat(r.Header)
+	resHeader.Set("Content-Type", string(format))
 
 	if m.enableGZIPEncoding {
 		// Gzip response if requested. Taken from
@@ -499,8 +748,8 @@ func (m *metricHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	for _, c := range m.collectors {
-		c.WriteAll(w)
+	if err := collectors.WriteMetrics(writer, format, m.collectors, filter); err != nil {
+		klog.Errorf("cannot write metrics: %v", err)
 	}
 
 	// In case we gziped the response, we have to close the writer.
@@ -511,10 +760,124 @@ func (m *metricHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Check the ConfigMap to see if the timestamp-metrics should be enabled
-func isTimestampMetricsEnabled(ctx context.Context, clientset *kubernetes.Clientset) (bool, error) {
+// tenantView returns the ManagedClusterSet and the namespace of the managed
+// clusters a tenant view of the metrics is restricted to.
+func tenantView(r *http.Request) (clusterSet, namespace string, err error) {
+	query := r.URL.Query()
+	for _, param := range []string{clusterSetParam, namespaceParam} {
+		if len(query[param]) > 1 {
+			return "", "", fmt.Errorf("query parameter %q is specified more than once", param)
+		}
+	}
+	return query.Get(clusterSetParam), query.Get(namespaceParam), nil
+}
+
+// tenantAttributes returns the attributes to authorize a tenant view of the
+// metrics: the caller must be able to get the requested ManagedClusterSet and
+// namespace. The other requests are authorized as non-resource URLs.
+func tenantAttributes(r *http.Request) ([]auth.Attributes, error) {
+	if r.URL.Path != metricsPath {
+		return nil, nil
+	}
+	clusterSet, namespace, err := tenantView(r)
+	if err != nil {
+		return nil, err
+	}
+
+	attrsList := []auth.Attributes{}
+	if clusterSet != "" {
+		attrsList = append(attrsList, auth.Attributes{
+			Verb: "get",
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:    clusterv1beta2.GroupName,
+				Resource: "managedclustersets",
+				Name:     clusterSet,
+			},
+		})
+	}
+	if namespace != "" {
+		attrsList = append(attrsList, auth.Attributes{
+			Verb: "get",
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Resource: "namespaces",
+				Name:     namespace,
+			},
+		})
+	}
+	if len(attrsList) == 0 {
+		return nil, nil
+	}
+	return attrsList, nil
+}
+
+// managedClusterLabelOptions returns the options of the managed cluster label
+// metrics from the configuration.
+func managedClusterLabelOptions(c config.LabelsConfig) cluster.LabelOptions {
+	options := cluster.LabelOptions{
+		Allow: c.Allow,
+		Deny:  c.Deny,
+	}
+	for _, rename := range c.Renames {
+		options.Renames = append(options.Renames, cluster.LabelRename{From: rename.From, To: rename.To})
+	}
+	for _, group := range c.Groups {
+		options.Groups = append(options.Groups, cluster.LabelGroup{Name: group.Name, Keys: group.Keys})
+	}
+	return options
+}
+
+// loadConfig loads the configuration from the file if set, otherwise from the
+// config.yaml key of the clusterlifecycle-state-metrics-config ConfigMap. The
+// default configuration is returned if neither exists.
+func loadConfig(ctx context.Context, kubeClient kubernetes.Interface, file string) (*config.Configuration, error) {
+	if file != "" {
+		klog.Infof("Loading the configuration from %s", file)
+		return config.LoadFile(file)
+	}
+
+	cm, err := getConfigMap(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if cm == nil {
+		c := &config.Configuration{APIVersion: config.APIVersion, Kind: config.Kind}
+		config.SetDefaults(c)
+		return c, nil
+	}
+	if _, ok := cm.Data[config.ConfigMapKey]; ok {
+		klog.Infof("Loading the configuration from ConfigMap %s/%s", cm.Namespace, cm.Name)
+	}
+	return config.LoadConfigMap(cm)
+}
+
+// getConfigMap returns the clusterlifecycle-state-metrics-config ConfigMap, or
+// nil if it does not exist or the component namespace is unknown.
+func getConfigMap(ctx context.Context, kubeClient kubernetes.Interface) (*corev1.ConfigMap, error) {
 	namespace, err := GetComponentNamespace()
 	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to get namespace: %v", err)
+		}
+		return nil, nil
+	}
+
+	cm, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get ConfigMap: %v", err)
+	}
+	return cm, nil
+}
+
+// Check the configuration and the ConfigMap to see if the timestamp-metrics
+// should be enabled. The configuration takes precedence over the ConfigMap.
+func isTimestampMetricsEnabled(ctx context.Context, kubeClient kubernetes.Interface, configured *bool) (bool, error) {
+	if configured != nil {
+		return *configured, nil
+	}
+
+	if _, err := GetComponentNamespace(); err != nil {
 		if !os.IsNotExist(err) {
 			return false, fmt.Errorf("failed to get namespace: %v", err)
 		}
@@ -523,21 +886,57 @@ func isTimestampMetricsEnabled(ctx context.Context, clientset *kubernetes.Client
 		return true, nil
 	}
 
-	// Get the ConfigMap
-	cm, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), configMapName, metav1.GetOptions{})
-	if errors.IsNotFound(err) {
-		return false, nil
-	} else if err != nil {
-		return false, fmt.Errorf("failed to get ConfigMap: %v", err)
+	cm, err := getConfigMap(ctx, kubeClient)
+	if err != nil {
+		return false, err
 	}
+	return isTimestampMetricsEnabledByConfigMap(cm)
+}
 
-	// Check if collect-timestamp-metrics is "enable"
-	value, exists := cm.Data["collect-timestamp-metrics"]
-	if !exists {
+// isTimestampMetricsEnabledByConfigMap returns true if the timestamp metrics
+// are enabled by the config.yaml or the collect-timestamp-metrics key of the
+// ConfigMap. A nil ConfigMap disables the timestamp metrics.
+func isTimestampMetricsEnabledByConfigMap(cm *corev1.ConfigMap) (bool, error) {
+	if cm == nil {
 		return false, nil
 	}
+	c, err := config.LoadConfigMap(cm)
+	if err != nil {
+		return false, err
+	}
+	return c.TimestampMetrics.Enabled != nil && *c.TimestampMetrics.Enabled, nil
+}
+
+// watchConfigMap watches the clusterlifecycle-state-metrics-config ConfigMap in
+// the namespace, and calls onChange with the ConfigMap once it is added or
+// updated, or with nil once it is deleted.
+func watchConfigMap(ctx context.Context, kubeClient kubernetes.Interface, namespace string, onChange func(cm *corev1.ConfigMap)) {
+	lw := cache.NewFilteredListWatchFromClient(kubeClient.CoreV1().RESTClient(), "configmaps", namespace,
+		func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", configMapName).String()
+		})
+	_, controller := cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: lw,
+		ObjectType:    &corev1.ConfigMap{},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if cm, ok := obj.(*corev1.ConfigMap); ok {
+					onChange(cm)
+				}
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				if cm, ok := obj.(*corev1.ConfigMap); ok {
+					onChange(cm)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				onChange(nil)
+			},
+		},
+	})
 
-	return value == "Enable", nil
+	klog.Infof("Start watching ConfigMap %s/%s", namespace, configMapName)
+	go controller.RunWithContext(ctx)
 }
 
 func GetComponentNamespace() (string, error) {
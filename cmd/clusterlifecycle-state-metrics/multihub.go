@@ -0,0 +1,175 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/collectors"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/options"
+)
+
+// hubKubeconfigKey is the key of the kubeconfig of a hub kubeconfig secret
+// mounted in the hub kubeconfig directory.
+const hubKubeconfigKey = "kubeconfig"
+
+// hubKubeconfig is the kubeconfig file of a hub whose metrics are served with
+// the metrics of other hubs.
+type hubKubeconfig struct {
+	name string
+	path string
+}
+
+// listHubKubeconfigs returns the kubeconfigs of the hubs sorted by name. A
+// kubeconfig file is named after its base name without extension. An entry
+// of the directory is either a kubeconfig file or a mounted secret with a
+// kubeconfig key named after the directory. The hidden entries of the
+// directory, such as the ..data link of the mounted secrets, are skipped.
+func listHubKubeconfigs(files []string, dir string) ([]hubKubeconfig, error) {
+	kubeconfigs := []hubKubeconfig{}
+	for _, file := range files {
+		base := filepath.Base(file)
+		kubeconfigs = append(kubeconfigs, hubKubeconfig{name: strings.TrimSuffix(base, filepath.Ext(base)), path: file})
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the hub kubeconfig directory: %v", err)
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			// follow the links of the mounted secrets
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				kubeconfigs = append(kubeconfigs, hubKubeconfig{
+					name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+					path: path,
+				})
+				continue
+			}
+			path = filepath.Join(path, hubKubeconfigKey)
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("cannot find the kubeconfig of hub %s: %v", entry.Name(), err)
+			}
+			kubeconfigs = append(kubeconfigs, hubKubeconfig{name: entry.Name(), path: path})
+		}
+	}
+
+	sort.Slice(kubeconfigs, func(i, j int) bool {
+		return kubeconfigs[i].name < kubeconfigs[j].name
+	})
+	for i := 1; i < len(kubeconfigs); i++ {
+		if kubeconfigs[i].name == kubeconfigs[i-1].name {
+			return nil, fmt.Errorf("hub %s is specified by both %s and %s",
+				kubeconfigs[i].name, kubeconfigs[i-1].path, kubeconfigs[i].path)
+		}
+	}
+	return kubeconfigs, nil
+}
+
+// hubTimestampMetricsEnabled returns whether the timestamp metrics are enabled
+// given the hubs whose metrics are served together. The timestamps are
+// recorded by the controller annotating the ManifestWorks of the cluster the
+// exporter runs on, so they are never recorded on the hubs and the timestamp
+// metrics are disabled with multiple hubs. An error is returned if they are
+// enabled by the configuration file, and they are disabled otherwise.
+func hubTimestampMetricsEnabled(kubeconfigs []hubKubeconfig, enabled bool, configured *bool) (bool, error) {
+	if len(kubeconfigs) == 0 || !enabled {
+		return enabled, nil
+	}
+	if configured != nil && *configured {
+		return false, fmt.Errorf("the timestamp metrics cannot be enabled with --hub-kubeconfigs or --hub-kubeconfig-dir " +
+			"since the ManifestWork timestamps are only recorded on the cluster the exporter runs on")
+	}
+	klog.Warning("The timestamp metrics are disabled since the ManifestWork timestamps are not recorded on the hubs")
+	return false, nil
+}
+
+// hubRetryPeriod is the period the collectors of a hub are built again after
+// the hub cannot be read.
+const hubRetryPeriod = 30 * time.Second
+
+// newHubBuilders returns a builder for each hub, and the hubs served by a
+// union collector once the collectors of the builders are built. A hub whose
+// kubeconfig cannot be loaded is served without collectors, and reported as
+// not synced.
+func newHubBuilders(ctx context.Context, kubeconfigs []hubKubeconfig,
+	opts *options.Options) ([]*collectors.Builder, []collectors.Hub) {
+	builders := []*collectors.Builder{}
+	hubs := []collectors.Hub{}
+	for _, kubeconfig := range kubeconfigs {
+		collectorBuilder, err := newHubBuilder(ctx, kubeconfig, opts)
+		if err != nil {
+			klog.Errorf("cannot serve the metrics of hub %s: %v", kubeconfig.name, err)
+			hubs = append(hubs, collectors.Hub{
+				Name:      kubeconfig.name,
+				HasSynced: func() bool { return false },
+			})
+			continue
+		}
+
+		klog.Infof("Serving the metrics of hub %s from %s", kubeconfig.name, kubeconfig.path)
+		builders = append(builders, collectorBuilder)
+		hubs = append(hubs, collectorBuilder.Hub())
+	}
+	return builders, hubs
+}
+
+// newHubBuilder returns the builder of the collectors of a hub, whose
+// timestamp metrics are disabled.
+func newHubBuilder(ctx context.Context, kubeconfig hubKubeconfig,
+	opts *options.Options) (*collectors.Builder, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the config: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the kubeClient: %v", err)
+	}
+
+	collectorBuilder := collectors.NewBuilder(ctx)
+	collectorBuilder.WithHubName(kubeconfig.name).
+		WithRestConfig(config).
+		WithKubeclient(kubeClient).
+		WithRefreshWorkers(opts.RefreshWorkers)
+	configureBuilder(collectorBuilder, opts)
+	return collectorBuilder, nil
+}
+
+// buildHub builds the collectors of a hub, and builds them again until the
+// hub can be read, so an unreachable or forbidden hub does not prevent the
+// other hubs from being served. The hub is reported as not synced until its
+// collectors are built.
+func buildHub(ctx context.Context, collectorBuilder *collectors.Builder) {
+	hub := collectorBuilder.Hub()
+	err := wait.PollUntilContextCancel(ctx, hubRetryPeriod, true, func(context.Context) (bool, error) {
+		if _, err := collectorBuilder.Build(); err != nil {
+			klog.Errorf("cannot build the collectors of hub %s, retrying in %v: %v", hub.Name, hubRetryPeriod, err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return
+	}
+	klog.Infof("Serving the metrics of hub %s with ID %s", hub.Name, hub.ClusterID())
+}
@@ -0,0 +1,54 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"testing"
+)
+
+func Test_hubTimestampMetricsEnabled(t *testing.T) {
+	enabled, disabled := true, false
+	hubs := []hubKubeconfig{{name: "hub1", path: "hub1.kubeconfig"}}
+	tests := []struct {
+		name        string
+		kubeconfigs []hubKubeconfig
+		enabled     bool
+		configured  *bool
+		want        bool
+		wantErr     bool
+	}{
+		{
+			name:    "single hub",
+			enabled: true,
+			want:    true,
+		},
+		{
+			name:        "disabled with hubs",
+			kubeconfigs: hubs,
+			configured:  &disabled,
+		},
+		{
+			name:        "enabled by the configmap with hubs",
+			kubeconfigs: hubs,
+			enabled:     true,
+		},
+		{
+			name:        "enabled by the configuration file with hubs",
+			kubeconfigs: hubs,
+			enabled:     true,
+			configured:  &enabled,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hubTimestampMetricsEnabled(tt.kubeconfigs, tt.enabled, tt.configured)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected timestamp metrics enabled %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/collectors"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/options"
+)
+
+// runOnce lists every watched resource once and writes a snapshot of the
+// metrics to the once output. The metrics of the resources listed
+// successfully are written even if a list fails, and the errors of the failed
+// lists are returned.
+func runOnce(ctx context.Context, config *rest.Config, kubeClient kubernetes.Interface,
+	timestampMetricsEnabled bool, opts *options.Options) error {
+	format, err := collectors.ParseFormat(opts.OnceFormat)
+	if err != nil {
+		return err
+	}
+
+	collectorBuilder := collectors.NewBuilder(ctx)
+	collectorBuilder.WithRestConfig(config).
+		WithKubeclient(kubeClient).
+		WithTimestampMetricsEnabled(timestampMetricsEnabled)
+	configureBuilder(collectorBuilder, opts)
+
+	metricsCollectors, listErr := collectorBuilder.BuildOnce()
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if opts.OnceOutput != "-" {
+		if file, err = os.Create(opts.OnceOutput); err != nil {
+			return fmt.Errorf("cannot create the once output: %v", err)
+		}
+		out = file
+	}
+
+	errs := []error{listErr, collectors.WriteMetrics(out, format, metricsCollectors, nil)}
+	if file != nil {
+		errs = append(errs, file.Close())
+	}
+	klog.Infof("Wrote the metrics of %d collectors to %s", len(metricsCollectors), opts.OnceOutput)
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,95 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/collectors"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/manifests"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/options"
+)
+
+const renderCommand = "render"
+
+// runRender renders the metrics of the ManagedClusters, ManagedClusterAddOns,
+// ManifestWorks and ClusterDeployments in the manifests of a path, such as a
+// must-gather, in the Prometheus text format without a hub.
+func runRender(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet(renderCommand, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s %s: [flags] <file or directory>\n", os.Args[0], renderCommand)
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", "", "Path of the configuration file.")
+	hubClusterID := flags.String("hub-cluster-id", "",
+		"The ID of the hub cluster. Defaults to the cluster ID of the ClusterVersion in the manifests.")
+	hubType := flags.String("hub-type", "",
+		`The type of the hub (mce|acm|stolostron-engine|stolostron). Defaults to the type of the MultiClusterHub or MultiClusterEngine in the manifests.`)
+	timestampMetrics := flags.Bool("timestamp-metrics", false,
+		"Render the timestamp metrics. Defaults to the timestamp metrics of the configuration.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a file or directory, got %d arguments", flags.NArg())
+	}
+
+	cfg := &config.Configuration{APIVersion: config.APIVersion, Kind: config.Kind}
+	config.SetDefaults(cfg)
+	if *configFile != "" {
+		var err error
+		if cfg, err = config.LoadFile(*configFile); err != nil {
+			return err
+		}
+	}
+	renderOpts := options.NewOptions()
+	renderOpts.ApplyConfig(cfg)
+	renderOpts.HubType = *hubType
+
+	timestampMetricsEnabled := renderOpts.TimestampMetricsEnabled != nil && *renderOpts.TimestampMetricsEnabled
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "timestamp-metrics" {
+			timestampMetricsEnabled = *timestampMetrics
+		}
+	})
+
+	objects, err := manifests.Load(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("cannot load the manifests: %v", err)
+	}
+	if *hubClusterID == "" {
+		*hubClusterID = manifests.HubClusterID(objects)
+	}
+	if *hubClusterID == "" {
+		return fmt.Errorf("no ClusterVersion in the manifests, the hub cluster ID must be set with --hub-cluster-id")
+	}
+	// the hub type cannot be detected from a hub, so it is detected from the manifests
+	if renderOpts.HubType == "" {
+		renderOpts.HubType = collectors.HubTypeFromObjects(objects)
+	}
+	if renderOpts.HubType == "" {
+		return fmt.Errorf("no MultiClusterHub or MultiClusterEngine in the manifests, the hub type must be set with --hub-type")
+	}
+	klog.Infof("Rendering the metrics of %d objects of hub %q", len(objects), *hubClusterID)
+
+	collectorBuilder := collectors.NewBuilder(context.Background())
+	collectorBuilder.WithHubClusterID(*hubClusterID).
+		WithTimestampMetricsEnabled(timestampMetricsEnabled)
+	configureBuilder(collectorBuilder, renderOpts)
+
+	metricsCollectors, err := collectorBuilder.BuildFromObjects(objects)
+	if err != nil {
+		return fmt.Errorf("cannot render the metrics: %v", err)
+	}
+
+	return collectors.WriteMetrics(out, collectors.TextContentType, metricsCollectors, nil)
+}
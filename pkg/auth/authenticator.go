@@ -0,0 +1,98 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxCacheSize is the maximum number of the cached authentication results
+// and authorization decisions.
+const maxCacheSize = 4096
+
+// TokenAuthenticator authenticates a bearer token.
+type TokenAuthenticator interface {
+	// AuthenticateToken returns the user the token belongs to. It returns false
+	// if the token is not authenticated.
+	AuthenticateToken(ctx context.Context, token string) (*authenticationv1.UserInfo, bool, error)
+}
+
+type tokenReviewResult struct {
+	user          *authenticationv1.UserInfo
+	authenticated bool
+}
+
+// tokenReviewAuthenticator authenticates a bearer token with a TokenReview. The
+// results are cached for a ttl, so a scraper does not create a TokenReview on
+// every scrape.
+type tokenReviewAuthenticator struct {
+	kubeClient kubernetes.Interface
+	audiences  []string
+	ttl        time.Duration
+	cache      *utilcache.LRUExpireCache
+}
+
+// NewTokenReviewAuthenticator returns a TokenAuthenticator which creates a
+// TokenReview to authenticate a bearer token. The results are cached for the
+// given ttl, a zero ttl disables the cache.
+func NewTokenReviewAuthenticator(kubeClient kubernetes.Interface, audiences []string, ttl time.Duration) TokenAuthenticator {
+	return &tokenReviewAuthenticator{
+		kubeClient: kubeClient,
+		audiences:  audiences,
+		ttl:        ttl,
+		cache:      utilcache.NewLRUExpireCache(maxCacheSize),
+	}
+}
+
+func (a *tokenReviewAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticationv1.UserInfo, bool, error) {
+	// the hash of the token is cached instead of the token itself
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if a.ttl > 0 {
+		if cached, ok := a.cache.Get(key); ok {
+			result := cached.(tokenReviewResult)
+			return result.user, result.authenticated, nil
+		}
+	}
+
+	review, err := a.kubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: a.audiences,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot create token review: %v", err)
+	}
+
+	result := tokenReviewResult{authenticated: review.Status.Authenticated}
+	if result.authenticated {
+		user := review.Status.User
+		result.user = &user
+	}
+	if a.ttl > 0 {
+		a.cache.Add(key, result, a.ttl)
+	}
+	return result.user, result.authenticated, nil
+}
+
+// bearerToken returns the bearer token in the Authorization header of the request.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !found || !strings.EqualFold(scheme, "bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, len(token) > 0
+}
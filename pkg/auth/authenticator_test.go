@@ -0,0 +1,157 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// newTokenReviewClient returns a fake client which authenticates the given
+// tokens and counts the created token reviews.
+func newTokenReviewClient(tokens map[string]string, reviews *int) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		*reviews++
+		review := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview).DeepCopy()
+		if review.Spec.Token == "error" {
+			return true, nil, fmt.Errorf("internal error")
+		}
+		if username, ok := tokens[review.Spec.Token]; ok {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: username}
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func Test_TokenReviewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name              string
+		ttl               time.Duration
+		tokens            []string
+		wantUser          string
+		wantAuthenticated bool
+		wantErr           bool
+		wantReviews       int
+	}{
+		{
+			name:              "authenticated",
+			ttl:               time.Minute,
+			tokens:            []string{"valid"},
+			wantUser:          "system:serviceaccount:openshift-monitoring:prometheus-k8s",
+			wantAuthenticated: true,
+			wantReviews:       1,
+		},
+		{
+			name:        "not authenticated",
+			ttl:         time.Minute,
+			tokens:      []string{"invalid"},
+			wantReviews: 1,
+		},
+		{
+			name:              "cached",
+			ttl:               time.Minute,
+			tokens:            []string{"valid", "valid", "valid"},
+			wantUser:          "system:serviceaccount:openshift-monitoring:prometheus-k8s",
+			wantAuthenticated: true,
+			wantReviews:       1,
+		},
+		{
+			name:              "cache disabled",
+			tokens:            []string{"valid", "valid"},
+			wantUser:          "system:serviceaccount:openshift-monitoring:prometheus-k8s",
+			wantAuthenticated: true,
+			wantReviews:       2,
+		},
+		{
+			name:        "errors are not cached",
+			ttl:         time.Minute,
+			tokens:      []string{"error", "error"},
+			wantErr:     true,
+			wantReviews: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews := 0
+			client := newTokenReviewClient(map[string]string{"valid": "system:serviceaccount:openshift-monitoring:prometheus-k8s"}, &reviews)
+			authenticator := NewTokenReviewAuthenticator(client, nil, tt.ttl)
+
+			var (
+				user          *authenticationv1.UserInfo
+				authenticated bool
+				err           error
+			)
+			for _, token := range tt.tokens {
+				user, authenticated, err = authenticator.AuthenticateToken(context.TODO(), token)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, but got %v", tt.wantErr, err)
+			}
+			if authenticated != tt.wantAuthenticated {
+				t.Errorf("expected authenticated %v, but got %v", tt.wantAuthenticated, authenticated)
+			}
+			if authenticated && user.Username != tt.wantUser {
+				t.Errorf("expected user %q, but got %q", tt.wantUser, user.Username)
+			}
+			if reviews != tt.wantReviews {
+				t.Errorf("expected %d token reviews, but got %d", tt.wantReviews, reviews)
+			}
+		})
+	}
+}
+
+func Test_BearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		wantToken     string
+		wantOK        bool
+	}{
+		{
+			name: "no header",
+		},
+		{
+			name:          "bearer",
+			authorization: "Bearer abc",
+			wantToken:     "abc",
+			wantOK:        true,
+		},
+		{
+			name:          "lowercase scheme",
+			authorization: "bearer abc",
+			wantToken:     "abc",
+			wantOK:        true,
+		},
+		{
+			name:          "basic",
+			authorization: "Basic abc",
+		},
+		{
+			name:          "empty token",
+			authorization: "Bearer ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			token, ok := bearerToken(r)
+			if token != tt.wantToken || ok != tt.wantOK {
+				t.Errorf("expected %q %v, but got %q %v", tt.wantToken, tt.wantOK, token, ok)
+			}
+		})
+	}
+}
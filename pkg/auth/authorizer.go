@@ -0,0 +1,124 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Attributes describes the access of a user to authorize. Either Path or
+// ResourceAttributes is set.
+type Attributes struct {
+	User *authenticationv1.UserInfo
+	Verb string
+	// Path is the non-resource URL accessed
+	Path string
+	// ResourceAttributes is the resource accessed
+	ResourceAttributes *authorizationv1.ResourceAttributes
+}
+
+// Authorizer authorizes the access of a user.
+type Authorizer interface {
+	// Authorize returns true if the access is allowed, and the reason of the decision.
+	Authorize(ctx context.Context, attrs Attributes) (bool, string, error)
+}
+
+type subjectAccessReviewDecision struct {
+	allowed bool
+	reason  string
+}
+
+// subjectAccessReviewAuthorizer authorizes an access with a SubjectAccessReview.
+// The decisions are cached, the denials for a shorter time than the approvals
+// by default, so a newly granted access is honored soon.
+type subjectAccessReviewAuthorizer struct {
+	kubeClient kubernetes.Interface
+	allowTTL   time.Duration
+	denyTTL    time.Duration
+	cache      *utilcache.LRUExpireCache
+}
+
+// NewSubjectAccessReviewAuthorizer returns an Authorizer which creates a
+// SubjectAccessReview to authorize an access. The approvals are cached for the
+// allowTTL and the denials for the denyTTL, a zero ttl disables the cache.
+func NewSubjectAccessReviewAuthorizer(kubeClient kubernetes.Interface, allowTTL, denyTTL time.Duration) Authorizer {
+	return &subjectAccessReviewAuthorizer{
+		kubeClient: kubeClient,
+		allowTTL:   allowTTL,
+		denyTTL:    denyTTL,
+		cache:      utilcache.NewLRUExpireCache(maxCacheSize),
+	}
+}
+
+func (a *subjectAccessReviewAuthorizer) Authorize(ctx context.Context, attrs Attributes) (bool, string, error) {
+	spec := subjectAccessReviewSpec(attrs)
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return false, "", err
+	}
+	key := string(data)
+	if cached, ok := a.cache.Get(key); ok {
+		decision := cached.(subjectAccessReviewDecision)
+		return decision.allowed, decision.reason, nil
+	}
+
+	review, err := a.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: spec,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("cannot create subject access review: %v", err)
+	}
+
+	decision := subjectAccessReviewDecision{
+		allowed: review.Status.Allowed && !review.Status.Denied,
+		reason:  review.Status.Reason,
+	}
+	ttl := a.denyTTL
+	if decision.allowed {
+		ttl = a.allowTTL
+	}
+	if ttl > 0 {
+		a.cache.Add(key, decision, ttl)
+	}
+	return decision.allowed, decision.reason, nil
+}
+
+// subjectAccessReviewSpec returns the spec of the SubjectAccessReview for the
+// given attributes. The groups are sorted, so the spec is a stable cache key.
+func subjectAccessReviewSpec(attrs Attributes) authorizationv1.SubjectAccessReviewSpec {
+	spec := authorizationv1.SubjectAccessReviewSpec{}
+	if attrs.User != nil {
+		spec.User = attrs.User.Username
+		spec.UID = attrs.User.UID
+		spec.Groups = append([]string{}, attrs.User.Groups...)
+		sort.Strings(spec.Groups)
+		if len(attrs.User.Extra) > 0 {
+			spec.Extra = map[string]authorizationv1.ExtraValue{}
+			for k, v := range attrs.User.Extra {
+				spec.Extra[k] = authorizationv1.ExtraValue(v)
+			}
+		}
+	}
+
+	if attrs.ResourceAttributes != nil {
+		resourceAttributes := *attrs.ResourceAttributes
+		resourceAttributes.Verb = attrs.Verb
+		spec.ResourceAttributes = &resourceAttributes
+	} else {
+		spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: attrs.Path,
+			Verb: attrs.Verb,
+		}
+	}
+	return spec
+}
@@ -0,0 +1,124 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// newSubjectAccessReviewClient returns a fake client which allows the given
+// users to get /metrics and counts the created subject access reviews.
+func newSubjectAccessReviewClient(users map[string]bool, reviews *int) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		*reviews++
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+		attrs := review.Spec.NonResourceAttributes
+		if users[review.Spec.User] && attrs != nil && attrs.Verb == "get" && attrs.Path == "/metrics" {
+			review.Status.Allowed = true
+		} else {
+			review.Status.Reason = "no RBAC policy matched"
+		}
+		return true, review, nil
+	})
+	return client
+}
+
+func Test_SubjectAccessReviewAuthorizer(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowTTL    time.Duration
+		denyTTL     time.Duration
+		attrs       []Attributes
+		wantAllowed bool
+		wantReviews int
+	}{
+		{
+			name:     "allowed",
+			allowTTL: time.Minute,
+			attrs: []Attributes{
+				{User: &authenticationv1.UserInfo{Username: "prometheus"}, Verb: "get", Path: "/metrics"},
+			},
+			wantAllowed: true,
+			wantReviews: 1,
+		},
+		{
+			name:     "denied",
+			allowTTL: time.Minute,
+			attrs: []Attributes{
+				{User: &authenticationv1.UserInfo{Username: "someone"}, Verb: "get", Path: "/metrics"},
+			},
+			wantReviews: 1,
+		},
+		{
+			name:     "approval cached",
+			allowTTL: time.Minute,
+			attrs: []Attributes{
+				{User: &authenticationv1.UserInfo{Username: "prometheus", Groups: []string{"b", "a"}}, Verb: "get", Path: "/metrics"},
+				{User: &authenticationv1.UserInfo{Username: "prometheus", Groups: []string{"a", "b"}}, Verb: "get", Path: "/metrics"},
+			},
+			wantAllowed: true,
+			wantReviews: 1,
+		},
+		{
+			name:     "denial not cached",
+			allowTTL: time.Minute,
+			attrs: []Attributes{
+				{User: &authenticationv1.UserInfo{Username: "someone"}, Verb: "get", Path: "/metrics"},
+				{User: &authenticationv1.UserInfo{Username: "someone"}, Verb: "get", Path: "/metrics"},
+			},
+			wantReviews: 2,
+		},
+		{
+			name:     "denial cached",
+			allowTTL: time.Minute,
+			denyTTL:  time.Minute,
+			attrs: []Attributes{
+				{User: &authenticationv1.UserInfo{Username: "someone"}, Verb: "get", Path: "/metrics"},
+				{User: &authenticationv1.UserInfo{Username: "someone"}, Verb: "get", Path: "/metrics"},
+			},
+			wantReviews: 1,
+		},
+		{
+			name:     "different verb",
+			allowTTL: time.Minute,
+			attrs: []Attributes{
+				{User: &authenticationv1.UserInfo{Username: "prometheus"}, Verb: "get", Path: "/metrics"},
+				{User: &authenticationv1.UserInfo{Username: "prometheus"}, Verb: "post", Path: "/metrics"},
+			},
+			wantReviews: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews := 0
+			client := newSubjectAccessReviewClient(map[string]bool{"prometheus": true}, &reviews)
+			authorizer := NewSubjectAccessReviewAuthorizer(client, tt.allowTTL, tt.denyTTL)
+
+			var (
+				allowed bool
+				err     error
+			)
+			for _, attrs := range tt.attrs {
+				allowed, _, err = authorizer.Authorize(context.TODO(), attrs)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("expected allowed %v, but got %v", tt.wantAllowed, allowed)
+			}
+			if reviews != tt.wantReviews {
+				t.Errorf("expected %d subject access reviews, but got %d", tt.wantReviews, reviews)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ConfigureClientCertificates makes the server require a client certificate
+// signed by a CA in the clientCAFile. If allowedNames is not empty, the common
+// name or one of the subject alternative names of the client certificate must
+// be in it as well.
+func ConfigureClientCertificates(tlsConfig *tls.Config, clientCAFile string, allowedNames []string) error {
+	data, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return fmt.Errorf("cannot read client CA file %s: %v", clientCAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificate found in client CA file %s", clientCAFile)
+	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(allowedNames) > 0 {
+		allowed := map[string]struct{}{}
+		for _, name := range allowedNames {
+			allowed[name] = struct{}{}
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("no client certificate")
+			}
+			cert := cs.PeerCertificates[0]
+			for _, name := range certificateNames(cert) {
+				if _, ok := allowed[name]; ok {
+					return nil
+				}
+			}
+			return fmt.Errorf("client certificate %q is not allowed", cert.Subject.CommonName)
+		}
+	}
+	return nil
+}
+
+// certificateNames returns the common name and the subject alternative names
+// of a certificate.
+func certificateNames(cert *x509.Certificate) []string {
+	names := []string{}
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// verifiedClientCertificate returns the verified client certificate of the
+// request.
+func verifiedClientCertificate(r *http.Request) (*x509.Certificate, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return r.TLS.VerifiedChains[0][0], true
+}
@@ -0,0 +1,172 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (c *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func Test_ConfigureClientCertificates(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	otherCA := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "other-ca"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	newClient := func(parent *testCertificate, cn string, dnsNames ...string) *testCertificate {
+		return newTestCertificate(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: cn, Organization: []string{"thanos"}},
+			DNSNames:    dnsNames,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+		}, parent)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		allowedNames []string
+		client       *testCertificate
+		wantErr      bool
+		wantUser     string
+	}{
+		{
+			name:    "no client certificate",
+			wantErr: true,
+		},
+		{
+			name:    "client certificate of another CA",
+			client:  newClient(otherCA, "thanos-receive"),
+			wantErr: true,
+		},
+		{
+			name:     "client certificate",
+			client:   newClient(ca, "thanos-receive"),
+			wantUser: "thanos-receive",
+		},
+		{
+			name:         "allowed common name",
+			allowedNames: []string{"thanos-receive"},
+			client:       newClient(ca, "thanos-receive"),
+			wantUser:     "thanos-receive",
+		},
+		{
+			name:         "allowed DNS name",
+			allowedNames: []string{"thanos-receive.observability.svc"},
+			client:       newClient(ca, "thanos", "thanos-receive.observability.svc"),
+			wantUser:     "thanos",
+		},
+		{
+			name:         "not allowed",
+			allowedNames: []string{"thanos-receive"},
+			client:       newClient(ca, "someone"),
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := ""
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if cert, ok := verifiedClientCertificate(r); ok {
+					user = cert.Subject.CommonName
+				}
+			}))
+			server.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+			if err := ConfigureClientCertificates(server.TLS, caFile, tt.allowedNames); err != nil {
+				t.Fatal(err)
+			}
+			server.StartTLS()
+			defer server.Close()
+
+			client := server.Client()
+			transport := client.Transport.(*http.Transport)
+			if tt.client != nil {
+				transport.TLSClientConfig.Certificates = []tls.Certificate{tt.client.tlsCertificate()}
+			}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, but got %v", tt.wantErr, err)
+			}
+			if user != tt.wantUser {
+				t.Errorf("expected user %q, but got %q", tt.wantUser, user)
+			}
+		})
+	}
+}
+
+func Test_ConfigureClientCertificates_InvalidCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := ConfigureClientCertificates(&tls.Config{}, caFile, nil); err == nil {
+		t.Errorf("expected error for a missing file, but got nil")
+	}
+	if err := os.WriteFile(caFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ConfigureClientCertificates(&tls.Config{}, caFile, nil); err == nil {
+		t.Errorf("expected error for an invalid file, but got nil")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/klog/v2"
+)
+
+type userKey struct{}
+
+// WithUser returns a copy of the context carrying the authenticated user.
+func WithUser(ctx context.Context, user *authenticationv1.UserInfo) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFrom returns the authenticated user carried by the context.
+func UserFrom(ctx context.Context) (*authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey{}).(*authenticationv1.UserInfo)
+	return user, ok && user != nil
+}
+
+// AttributesFunc returns the attributes to authorize for a request. The user of
+// the attributes is set by the Filter. It returns nil to authorize the request
+// as a non-resource URL.
+type AttributesFunc func(r *http.Request) ([]Attributes, error)
+
+// Filter authenticates every request with its bearer token, or else with its
+// verified client certificate if a client certificate user is set, and
+// authorizes it with the authorizer before passing it on, the way
+// kube-rbac-proxy does. By default the request is
+// authorized as a non-resource URL, the verb is the lowercased HTTP method.
+// Requests on the excluded paths, like the health probes, are passed on as
+// they are.
+type Filter struct {
+	authenticator  TokenAuthenticator
+	authorizer     Authorizer
+	attributesFunc AttributesFunc
+	excludedPaths  map[string]struct{}
+	// clientCertificateUser is the user the requests with a verified client
+	// certificate and without bearer token are authorized as
+	clientCertificateUser string
+}
+
+// NewFilter returns a new Filter.
+func NewFilter(authenticator TokenAuthenticator, authorizer Authorizer, excludedPaths ...string) *Filter {
+	f := &Filter{
+		authenticator: authenticator,
+		authorizer:    authorizer,
+		excludedPaths: map[string]struct{}{},
+	}
+	for _, path := range excludedPaths {
+		f.excludedPaths[path] = struct{}{}
+	}
+	return f
+}
+
+// WithAttributesFunc sets the func returning the attributes to authorize for a
+// request. The request is allowed only if all the attributes are allowed.
+func (f *Filter) WithAttributesFunc(attributesFunc AttributesFunc) *Filter {
+	f.attributesFunc = attributesFunc
+	return f
+}
+
+// WithClientCertificateUser authenticates the requests without bearer token
+// but with a verified client certificate as the given user. The subject of the
+// certificate is not the identity of the request, since the client CA is not
+// trusted by the hub to assert its users and groups. By default these requests
+// are not authenticated.
+func (f *Filter) WithClientCertificateUser(username string) *Filter {
+	f.clientCertificateUser = username
+	return f
+}
+
+// Wrap returns a handler which filters the requests passed to the handler.
+func (f *Filter) Wrap(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := f.excludedPaths[r.URL.Path]; ok {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		user, authenticated := f.authenticate(r)
+		if !authenticated {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		attrsList, err := f.requestAttributes(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, attrs := range attrsList {
+			attrs.User = user
+			allowed, reason, err := f.authorizer.Authorize(r.Context(), attrs)
+			if err != nil {
+				klog.Errorf("cannot authorize user %q to %s: %v", user.Username, describe(attrs), err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				klog.V(2).Infof("user %q is forbidden to %s: %s", user.Username, describe(attrs), reason)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// authenticate returns the user of the request. The bearer token is preferred,
+// a request without it is authenticated as the client certificate user if it
+// has a verified client certificate.
+func (f *Filter) authenticate(r *http.Request) (*authenticationv1.UserInfo, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		if _, verified := verifiedClientCertificate(r); !verified || f.clientCertificateUser == "" {
+			return nil, false
+		}
+		return &authenticationv1.UserInfo{Username: f.clientCertificateUser}, true
+	}
+	user, authenticated, err := f.authenticator.AuthenticateToken(r.Context(), token)
+	if err != nil {
+		klog.Errorf("cannot authenticate the request to %s: %v", r.URL.Path, err)
+		return nil, false
+	}
+	return user, authenticated
+}
+
+// requestAttributes returns the attributes to authorize for the request.
+func (f *Filter) requestAttributes(r *http.Request) ([]Attributes, error) {
+	if f.attributesFunc != nil {
+		attrsList, err := f.attributesFunc(r)
+		if err != nil || attrsList != nil {
+			return attrsList, err
+		}
+	}
+	return []Attributes{{Verb: requestVerb(r), Path: r.URL.Path}}, nil
+}
+
+// describe returns a description of the access in the attributes for logging.
+func describe(attrs Attributes) string {
+	if attrs.ResourceAttributes == nil {
+		return attrs.Verb + " " + attrs.Path
+	}
+	ra := attrs.ResourceAttributes
+	resource := ra.Resource
+	if ra.Group != "" {
+		resource += "." + ra.Group
+	}
+	if ra.Name != "" {
+		resource += "/" + ra.Name
+	}
+	if ra.Namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %s", attrs.Verb, resource, ra.Namespace)
+	}
+	return attrs.Verb + " " + resource
+}
+
+// requestVerb returns the verb of a non-resource request.
+func requestVerb(r *http.Request) string {
+	if r.Method == http.MethodHead {
+		return "get"
+	}
+	return strings.ToLower(r.Method)
+}
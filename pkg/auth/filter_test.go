@@ -0,0 +1,244 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func Test_Filter(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		certUser string
+		// certOrgs are the organizations of the client certificate
+		certOrgs              []string
+		clientCertificateUser string
+		wantStatus            int
+		wantUser              string
+	}{
+		{
+			name:       "excluded path",
+			method:     http.MethodGet,
+			path:       "/healthz",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no token",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			token:      "invalid",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "token review error",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			token:      "error",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "forbidden",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			token:      "someone",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "forbidden path",
+			method:     http.MethodGet,
+			path:       "/debug/pprof/",
+			token:      "prometheus",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "allowed",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			token:      "prometheus",
+			wantStatus: http.StatusOK,
+			wantUser:   "prometheus",
+		},
+		{
+			name:       "head is get",
+			method:     http.MethodHead,
+			path:       "/metrics",
+			token:      "prometheus",
+			wantStatus: http.StatusOK,
+			wantUser:   "prometheus",
+		},
+		{
+			name:       "client certificate without client certificate user",
+			method:     http.MethodGet,
+			path:       "/metrics",
+			certUser:   "prometheus",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:                  "client certificate",
+			method:                http.MethodGet,
+			path:                  "/metrics",
+			certUser:              "thanos",
+			clientCertificateUser: "prometheus",
+			wantStatus:            http.StatusOK,
+			wantUser:              "prometheus",
+		},
+		{
+			name:                  "subject of client certificate ignored",
+			method:                http.MethodGet,
+			path:                  "/metrics",
+			certUser:              "prometheus",
+			certOrgs:              []string{"system:masters"},
+			clientCertificateUser: "someone",
+			wantStatus:            http.StatusForbidden,
+		},
+		{
+			name:                  "bearer token preferred to client certificate",
+			method:                http.MethodGet,
+			path:                  "/metrics",
+			token:                 "someone",
+			certUser:              "thanos",
+			clientCertificateUser: "prometheus",
+			wantStatus:            http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenReviews, subjectAccessReviews := 0, 0
+			filter := NewFilter(
+				NewTokenReviewAuthenticator(
+					newTokenReviewClient(map[string]string{"prometheus": "prometheus", "someone": "someone"}, &tokenReviews), nil, time.Minute),
+				NewSubjectAccessReviewAuthorizer(
+					newSubjectAccessReviewClient(map[string]bool{"prometheus": true}, &subjectAccessReviews), time.Minute, 0),
+				"/healthz", "/readyz",
+			).WithClientCertificateUser(tt.clientCertificateUser)
+
+			user := ""
+			handler := filter.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if u, ok := UserFrom(r.Context()); ok {
+					user = u.Username
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.certUser != "" {
+				r.TLS = &tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: tt.certUser, Organization: tt.certOrgs}}}},
+				}
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, w.Code)
+			}
+			if user != tt.wantUser {
+				t.Errorf("expected user %q, but got %q", tt.wantUser, user)
+			}
+		})
+	}
+}
+
+func Test_Filter_WithAttributesFunc(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		token      string
+		wantStatus int
+	}{
+		{
+			name:       "non-resource url",
+			token:      "prometheus",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "allowed namespace",
+			query:      "namespace=cluster1",
+			token:      "tenant",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "forbidden namespace",
+			query:      "namespace=cluster2",
+			token:      "tenant",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "invalid query",
+			query:      "namespace=cluster1&namespace=cluster2",
+			token:      "tenant",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subjectAccessReviews := 0
+			client := newSubjectAccessReviewClient(map[string]bool{"prometheus": true}, &subjectAccessReviews)
+			client.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview).DeepCopy()
+				attrs := review.Spec.ResourceAttributes
+				if attrs == nil {
+					return false, nil, nil
+				}
+				review.Status.Allowed = review.Spec.User == "tenant" && attrs.Verb == "get" &&
+					attrs.Resource == "namespaces" && attrs.Name == "cluster1"
+				return true, review, nil
+			})
+
+			tokenReviews := 0
+			filter := NewFilter(
+				NewTokenReviewAuthenticator(
+					newTokenReviewClient(map[string]string{"prometheus": "prometheus", "tenant": "tenant"}, &tokenReviews), nil, time.Minute),
+				NewSubjectAccessReviewAuthorizer(client, time.Minute, 0),
+			).WithAttributesFunc(func(r *http.Request) ([]Attributes, error) {
+				namespaces := r.URL.Query()["namespace"]
+				switch len(namespaces) {
+				case 0:
+					return nil, nil
+				case 1:
+					return []Attributes{{
+						Verb:               "get",
+						ResourceAttributes: &authorizationv1.ResourceAttributes{Resource: "namespaces", Name: namespaces[0]},
+					}}, nil
+				default:
+					return nil, fmt.Errorf("namespace is specified more than once")
+				}
+			})
+
+			handler := filter.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/metrics?"+tt.query, nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, but got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
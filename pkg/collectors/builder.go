@@ -4,8 +4,11 @@
 package collectors
 
 import (
+	"fmt"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
@@ -19,7 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -28,20 +31,31 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/kube-state-metrics/pkg/metric"
-	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
 	"k8s.io/kube-state-metrics/pkg/options"
 
 	"golang.org/x/net/context"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/addon"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/customresource"
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/work"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/version"
 )
 
 var ResyncPeriod = 60 * time.Minute
 
+var (
+	clusterDeploymentGroupKind = schema.GroupKind{Group: "hive.openshift.io", Kind: "ClusterDeployment"}
+	clusterDeploymentGVR       = schema.GroupVersionResource{
+		Group:    clusterDeploymentGroupKind.Group,
+		Version:  "v1",
+		Resource: "clusterdeployments",
+	}
+)
+
 type whiteBlackLister interface {
 	IsIncluded(string) bool
 	IsExcluded(string) bool
@@ -50,35 +64,110 @@ type whiteBlackLister interface {
 // Builder helps to build collectors. It follows the builder pattern
 // (https://en.wikipedia.org/wiki/Builder_pattern).
 type Builder struct {
-	hubType           string
+	// hubName identifies the hub when the metrics of several hubs are served
+	hubName           string
+	hubClusterID      string
 	namespaces        options.NamespaceList
 	ctx               context.Context
 	enabledCollectors []string
 	whiteBlackList    whiteBlackLister
 	restConfig        *rest.Config
 	kubeclient        kubernetes.Interface
+	// selectors are the label selectors of the watched resources indexed by collector
+	selectors    map[string]string
+	labelOptions cluster.LabelOptions
+	infoOptions  cluster.InfoOptions
+	// unmappedValues are the unmapped annotation values counted for the
+	// managed clusters of the hub, created once the managed cluster collector
+	// is built
+	unmappedValues *cluster.UnmappedValues
+	// annotationAllowlist are the key patterns of the exposed annotations of the managed clusters
+	annotationAllowlist []string
+	// customResources configures the state metrics of custom resources
+	customResources []config.CustomResourceConfig
+	// seriesLimits limits the series of the metric families
+	seriesLimits config.LimitsConfig
 
 	clusterIdCache               *clusterIdCache
+	clusterSetCache              *clusterSetCache
 	clusterHibernatingStateCache *clusterHibernatingStateCache
 	clusterTimestampCache        *clusterTimestampCache
 	composedClusterStore         *composedStore
 	composedAddOnStore           *composedStore
 	composedManifestWorkStore    *composedStore
-
+	// refreshQueue is created on first use, once the name of the hub labeling its self metrics is set
+	refreshQueue     *refreshQueue
+	refreshQueueOnce sync.Once
+	refreshWorkers   int
+
+	// customResourceStores is a map indexed by collector with the stores of the custom resources
+	customResourceStores map[string]customResourceStore
+
+	// seriesLimiters is a map indexed by collector with the limiters of the series of its metric families
+	seriesLimiters map[string]*seriesLimiter
+
+	// instrumentedStores is a map indexed by resource with the stores the reflectors write into
+	instrumentedStores map[string]*instrumentedStore
+
+	// Protects syncedFuncs, since the ManifestWorks can be watched once the collectors are built
+	syncedMutex sync.RWMutex
+	// syncedFuncs reports whether each reflector has completed its initial list
+	syncedFuncs []func() bool
+	built       atomic.Bool
+	// watching is set once Build watches the hub
+	watching atomic.Bool
+	// manifestWorksWatched is set once the ManifestWorks are watched
+	manifestWorksWatched atomic.Bool
+
+	// Protects timestampMetricsEnabled, timestampCacheAdded and timestampStores
+	timestampMutex          sync.Mutex
 	timestampMetricsEnabled bool
+	// timestampCacheStore is the timestamp cache, added to the ManifestWork
+	// stores once the timestamp metrics are enabled, so the ManifestWorks are
+	// only watched for their timestamps if the timestamp metrics are enabled
+	timestampCacheStore *switchableStore
+	timestampCacheAdded bool
+	// timestampStores are the timestamp cache and the stores of the timestamp
+	// metric families, which are enabled and disabled at runtime
+	timestampStores []*switchableStore
+
+	// Protects hubClusterID and hubClusterIDSource
+	hubClusterIDMutex sync.RWMutex
+
+	// collectors are the collectors built by Build
+	collectors []MetricsCollector
+
+	// Protects hubType
+	hubTypeMutex sync.RWMutex
+	hubType      string
+	// detectHubType is set to detect the hub type instead of setting it
+	detectHubType bool
+	// hubClusterIDSource is where the ID of the hub cluster is read from
+	hubClusterIDSource string
+	// hubInfoStore keeps the info of the hub once the managed cluster collector is built
+	hubInfoStore *hubInfoStore
 }
 
 // NewBuilder returns a new builder.
 func NewBuilder(ctx context.Context) *Builder {
 	clusterIdCache := newClusterIdCache()
+	clusterSetCache := newClusterSetCache()
 	clusterHibernatingStateCache := newClusterHibernatingStateCache()
+	clusterTimestampCache := newClusterTimestampCache()
+	// the timestamp cache is disabled until the timestamp metrics are enabled
+	timestampCacheStore := newSwitchableStore(clusterTimestampCache, false)
 	return &Builder{
 		ctx:                          ctx,
 		clusterIdCache:               clusterIdCache,
+		clusterSetCache:              clusterSetCache,
 		clusterHibernatingStateCache: clusterHibernatingStateCache,
-		composedClusterStore:         newComposedStore(clusterIdCache),
+		composedClusterStore:         newComposedStore(clusterIdCache, clusterSetCache),
 		composedAddOnStore:           newComposedStore(),
+		clusterTimestampCache:        clusterTimestampCache,
 		composedManifestWorkStore:    newComposedStore(),
+		refreshWorkers:               1,
+		timestampCacheStore:          timestampCacheStore,
+		timestampStores:              []*switchableStore{timestampCacheStore},
 	}
 }
 
@@ -92,19 +181,133 @@ func (b *Builder) WithRestConfig(restConfig *rest.Config) *Builder {
 	return b
 }
 
+// WithTimestampMetricsEnabled sets whether the timestamp metrics are enabled
+// once the collectors are built.
 func (b *Builder) WithTimestampMetricsEnabled(timestampMetricsEnabled bool) *Builder {
-	b.timestampMetricsEnabled = timestampMetricsEnabled
 	klog.InfoS("set timetamp metrics enabled", "enabled", timestampMetricsEnabled)
-	if timestampMetricsEnabled && b.clusterTimestampCache == nil {
-		clusterTimestampCache := newClusterTimestampCache()
-		b.composedManifestWorkStore.AddStore(clusterTimestampCache)
-		b.clusterTimestampCache = clusterTimestampCache
+	if err := b.SetTimestampMetricsEnabled(timestampMetricsEnabled); err != nil {
+		klog.Errorf("cannot set timestamp metrics enabled: %v", err)
+	}
+	return b
+}
+
+// SetTimestampMetricsEnabled enables or disables the timestamp cache and the
+// timestamp metric families at runtime. Once disabled, the timestamp metrics
+// are removed; once enabled, the ManifestWorks are watched if they are not
+// yet, and the ManifestWorks and ManagedClusters of all clusters are refreshed
+// to rebuild them.
+func (b *Builder) SetTimestampMetricsEnabled(enabled bool) error {
+	changed, err := b.switchTimestampStores(enabled)
+	if enabled && b.watching.Load() {
+		b.startWatchingManifestWorks()
+	}
+
+	if enabled && changed && b.built.Load() {
+		klog.Info("Refresh the manifestwork and managed cluster metrics since the timestamp metrics are enabled")
+		for _, clusterName := range b.clusterIdCache.ClusterNames() {
+			b.getRefreshQueue().Enqueue(refreshManifestWorks, clusterName)
+			b.getRefreshQueue().Enqueue(refreshManagedCluster, clusterName)
+		}
+	}
+	return err
+}
+
+// switchTimestampStores enables or disables the timestamp stores, and adds the
+// timestamp cache to the ManifestWork stores the first time they are enabled.
+// It returns true if the state of a store is changed.
+func (b *Builder) switchTimestampStores(enabled bool) (bool, error) {
+	b.timestampMutex.Lock()
+	defer b.timestampMutex.Unlock()
+
+	b.timestampMetricsEnabled = enabled
+	if enabled && !b.timestampCacheAdded {
+		b.composedManifestWorkStore.AddStore(b.timestampCacheStore)
+		b.timestampCacheAdded = true
+	}
+
+	changed := false
+	errs := []error{}
+	for _, store := range b.timestampStores {
+		storeChanged, err := store.SetEnabled(enabled)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		changed = changed || storeChanged
 	}
+	return changed, utilerrors.NewAggregate(errs)
+}
+
+// buildTimestampMetricsStore returns a metrics store of the timestamp metric
+// families, which is enabled and disabled with the timestamp metrics.
+func (b *Builder) buildTimestampMetricsStore(collector string, families []metric.FamilyGenerator) *switchableStore {
+	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList, families)
+	metricsStore := newClusterMetricsStore(
+		metric.ExtractMetricFamilyHeaders(filteredMetricFamilies),
+		metric.ComposeMetricGenFuncs(filteredMetricFamilies),
+	).withSeriesLimiter(b.seriesLimiter(collector))
+
+	b.timestampMutex.Lock()
+	defer b.timestampMutex.Unlock()
+	store := newSwitchableStore(metricsStore, b.timestampMetricsEnabled)
+	b.timestampStores = append(b.timestampStores, store)
+	return store
+}
+
+// WithRefreshWorkers sets the number of workers refreshing the metrics which
+// depend on the state of other resources.
+func (b *Builder) WithRefreshWorkers(workers int) *Builder {
+	b.refreshWorkers = workers
+	return b
+}
+
+// WithHubName sets the name identifying the hub when the metrics of several
+// hubs are served, such as the name of its kubeconfig. The self metrics of the
+// builder are labeled with it.
+func (b *Builder) WithHubName(hubName string) *Builder {
+	b.hubName = hubName
+	return b
+}
+
+// WithHubClusterID sets the ID of the hub cluster instead of reading it from
+// the ClusterVersion or the kube-system namespace of the hub.
+func (b *Builder) WithHubClusterID(hubClusterID string) *Builder {
+	b.hubClusterIDMutex.Lock()
+	defer b.hubClusterIDMutex.Unlock()
+	b.hubClusterID = hubClusterID
+	b.hubClusterIDSource = HubClusterIDSourceConfigured
 	return b
 }
 
+// HubClusterID returns the ID of the hub cluster. It is read from the
+// ClusterVersion or the kube-system namespace of the hub unless set by
+// WithHubClusterID.
+func (b *Builder) HubClusterID() (string, error) {
+	b.hubClusterIDMutex.Lock()
+	defer b.hubClusterIDMutex.Unlock()
+	if b.hubClusterID == "" {
+		ocpClient, err := ocpclient.NewForConfig(b.restConfig)
+		if err != nil {
+			return "", fmt.Errorf("cannot create ocpclient: %v", err)
+		}
+		hubClusterID, hubClusterIDSource, err := getHubClusterID(ocpClient, b.kubeclient)
+		if err != nil {
+			return "", err
+		}
+		b.hubClusterID, b.hubClusterIDSource = hubClusterID, hubClusterIDSource
+	}
+	return b.hubClusterID, nil
+}
+
+// knownHubClusterID returns the ID of the hub cluster and where it is read
+// from, which are empty until the ID is read by HubClusterID.
+func (b *Builder) knownHubClusterID() (string, string) {
+	b.hubClusterIDMutex.RLock()
+	defer b.hubClusterIDMutex.RUnlock()
+	return b.hubClusterID, b.hubClusterIDSource
+}
+
 func (b *Builder) WithHubType(hubType string) *Builder {
-	b.hubType = hubType
+	b.SetHubType(hubType)
 	return b
 }
 
@@ -124,6 +327,47 @@ func (b *Builder) WithNamespaces(n options.NamespaceList) *Builder {
 	return b
 }
 
+// WithSelectors sets the label selectors of the resources watched by the
+// collectors, indexed by collector name.
+func (b *Builder) WithSelectors(selectors map[string]string) *Builder {
+	b.selectors = selectors
+	return b
+}
+
+// WithManagedClusterLabelOptions configures the labels of the managed clusters
+// exposed by the label metrics.
+func (b *Builder) WithManagedClusterLabelOptions(labelOptions cluster.LabelOptions) *Builder {
+	b.labelOptions = labelOptions
+	return b
+}
+
+// WithManagedClusterInfoOptions configures the label values of the info metrics
+// of the managed clusters.
+func (b *Builder) WithManagedClusterInfoOptions(infoOptions cluster.InfoOptions) *Builder {
+	b.infoOptions = infoOptions
+	return b
+}
+
+// WithManagedClusterAnnotationAllowlist sets the key patterns of the
+// annotations of the managed clusters exposed by the annotation metrics.
+func (b *Builder) WithManagedClusterAnnotationAllowlist(allowlist []string) *Builder {
+	b.annotationAllowlist = allowlist
+	return b
+}
+
+// WithCustomResources configures the state metrics of custom resources. Each
+// custom resource is watched by its own collector.
+func (b *Builder) WithCustomResources(customResources []config.CustomResourceConfig) *Builder {
+	b.customResources = customResources
+	return b
+}
+
+// WithSeriesLimits sets the limits of the series of the metric families.
+func (b *Builder) WithSeriesLimits(limits config.LimitsConfig) *Builder {
+	b.seriesLimits = limits
+	return b
+}
+
 // WithWhiteBlackList configures the white or blacklisted metrics to be exposed
 // by the collectors build by the Builder
 func (b *Builder) WithWhiteBlackList(l whiteBlackLister) *Builder {
@@ -131,8 +375,272 @@ func (b *Builder) WithWhiteBlackList(l whiteBlackLister) *Builder {
 	return b
 }
 
-// Build initializes and registers all enabled collectors.
-func (b *Builder) Build() []MetricsCollector {
+// Build initializes and registers all enabled collectors, and starts watching
+// the hub. An error is returned if the hub cannot be read, before the
+// collectors are built, so Build can be called again once the hub is
+// reachable.
+func (b *Builder) Build() ([]MetricsCollector, error) {
+	if _, err := b.HubClusterID(); err != nil {
+		return nil, err
+	}
+	clusterClient, err := b.initClusterIdCache()
+	if err != nil {
+		return nil, err
+	}
+
+	collectors, activeCollectorNames := b.buildCollectors()
+	b.startRefreshingHub()
+
+	// start watching resources
+	b.startWatchingManagedClusters(clusterClient)
+	b.startWatchingClusterDeployments()
+	b.startWatchingManagedClusterAddOns()
+	b.startWatchingManifestWorks()
+	b.startWatchingCustomResources()
+
+	// report the self metrics of the collectors once the watched resources are known
+	for index, name := range activeCollectorNames {
+		var objects func() int
+		if store, ok := b.instrumentedStores[name]; ok {
+			objects = store.Len
+		}
+		collectors[index] = newInstrumentedCollector(b.hubName, name, collectors[index], objects)
+	}
+
+	// start refreshing metrics once the state of other resources is changed
+	go b.getRefreshQueue().Run(b.ctx, b.refreshWorkers)
+
+	// from now on, the ManifestWorks are watched once the timestamp metrics
+	// are enabled, including if they were enabled while building
+	b.watching.Store(true)
+	b.startWatchingManifestWorks()
+
+	b.collectors = collectors
+	b.built.Store(true)
+	return collectors, nil
+}
+
+// Collectors returns the built collectors, or nil until they are built.
+func (b *Builder) Collectors() []MetricsCollector {
+	if !b.built.Load() {
+		return nil
+	}
+	return b.collectors
+}
+
+// Hub returns the hub of the builder, whose metrics are served with the
+// metrics of other hubs once its collectors are built by Build.
+func (b *Builder) Hub() Hub {
+	return Hub{
+		Name: b.hubName,
+		ClusterID: func() string {
+			hubClusterID, _ := b.knownHubClusterID()
+			return hubClusterID
+		},
+		Type:       b.HubType,
+		Collectors: b.Collectors,
+		HasSynced:  b.HasSynced,
+	}
+}
+
+// BuildFromObjects initializes the enabled collectors with the given objects
+// instead of watching the hub, so the metrics of a hub can be rendered
+// offline. The ManagedClusters, ManagedClusterAddOns, ManifestWorks and
+// ClusterDeployments are used, and the other objects are ignored. The
+// metrics of the custom resources are not rendered.
+func (b *Builder) BuildFromObjects(objects []runtime.Object) ([]MetricsCollector, error) {
+	if _, err := b.HubClusterID(); err != nil {
+		return nil, err
+	}
+	collectors, _ := b.buildCollectors()
+	err := b.replaceObjects(objects)
+
+	b.collectors = collectors
+	b.built.Store(true)
+	return collectors, err
+}
+
+// BuildOnce initializes the enabled collectors with a single list of each
+// watched resource instead of watching them, so a snapshot of the metrics can
+// be rendered. The collectors are built with the resources listed
+// successfully, and the errors of the failed lists are returned.
+func (b *Builder) BuildOnce() ([]MetricsCollector, error) {
+	if _, err := b.HubClusterID(); err != nil {
+		return nil, err
+	}
+	collectors, _ := b.buildCollectors()
+	if b.hubInfoStore != nil {
+		dynamicClient, ocpClient, err := b.newHubClients()
+		if err != nil {
+			return nil, fmt.Errorf("cannot create the hub clients: %v", err)
+		}
+		b.refreshHub(dynamicClient, ocpClient)
+	}
+
+	objects, errs := b.listOnce()
+	if err := b.replaceObjects(objects); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, b.listCustomResourcesOnce()...)
+
+	b.collectors = collectors
+	b.built.Store(true)
+	return collectors, utilerrors.NewAggregate(errs)
+}
+
+// replaceObjects replaces the content of the stores with the
+// ManagedClusters, ManagedClusterAddOns, ManifestWorks and ClusterDeployments
+// of the given objects, and ignores the other objects.
+func (b *Builder) replaceObjects(objects []runtime.Object) error {
+	clusters, addons, works, clusterDeployments := []interface{}{}, []interface{}{}, []interface{}{}, []interface{}{}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *mcv1.ManagedCluster:
+			clusters = append(clusters, o)
+		case *addonv1alpha1.ManagedClusterAddOn:
+			addons = append(addons, o)
+		case *workv1.ManifestWork:
+			works = append(works, o)
+		case *unstructured.Unstructured:
+			if o.GroupVersionKind().GroupKind() == clusterDeploymentGroupKind {
+				clusterDeployments = append(clusterDeployments, o)
+			}
+		}
+	}
+
+	// the cluster metrics depend on the hibernating state, and are rendered
+	// again once the timestamps are known from the ManifestWorks
+	errs := []error{}
+	for _, replace := range []struct {
+		store   cache.Store
+		objects []interface{}
+	}{
+		{b.clusterHibernatingStateCache, clusterDeployments},
+		{b.composedClusterStore, clusters},
+		{b.composedManifestWorkStore, works},
+		{b.composedAddOnStore, addons},
+		{b.composedClusterStore, clusters},
+	} {
+		if err := replace.store.Replace(replace.objects, ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// listOnce lists the ManagedClusters, ClusterDeployments, and the
+// ManagedClusterAddOns and ManifestWorks if their collectors are enabled. It
+// returns the listed objects and an error for each failed list. The optional
+// resources the hub does not serve are skipped.
+func (b *Builder) listOnce() ([]runtime.Object, []error) {
+	objects := []runtime.Object{}
+	errs := []error{}
+
+	clusterClient, err := clusterclient.NewForConfig(b.restConfig)
+	if err != nil {
+		return nil, []error{fmt.Errorf("cannot create clusterclient: %v", err)}
+	}
+	clusterList, err := clusterClient.ClusterV1().ManagedClusters().List(b.ctx, b.listOptions("managedclusters"))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cannot list managedclusters: %v", err))
+	} else {
+		for index := range clusterList.Items {
+			objects = append(objects, &clusterList.Items[index])
+		}
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(b.restConfig)
+	if err != nil {
+		return nil, []error{fmt.Errorf("cannot create dynamic client: %v", err)}
+	}
+	clusterDeploymentList, err := dynamicClient.Resource(clusterDeploymentGVR).Namespace(metav1.NamespaceAll).List(b.ctx, metav1.ListOptions{})
+	if errors.IsNotFound(err) {
+		klog.Infof("Skip clusterdeployments since the hub does not serve them")
+	} else if err != nil {
+		errs = append(errs, fmt.Errorf("cannot list clusterdeployments: %v", err))
+	} else {
+		for index := range clusterDeploymentList.Items {
+			objects = append(objects, &clusterDeploymentList.Items[index])
+		}
+	}
+
+	if b.composedAddOnStore.Size() > 0 {
+		addOnClient, err := addonclient.NewForConfig(b.restConfig)
+		if err != nil {
+			return nil, []error{fmt.Errorf("cannot create addonclient: %v", err)}
+		}
+		addons, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns(metav1.NamespaceAll).List(b.ctx, b.listOptions("managedclusteraddons"))
+		if errors.IsNotFound(err) {
+			klog.Infof("Skip managedclusteraddons since the hub does not serve them")
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("cannot list managedclusteraddons: %v", err))
+		} else {
+			for index := range addons.Items {
+				objects = append(objects, &addons.Items[index])
+			}
+		}
+	}
+
+	if b.composedManifestWorkStore.Size() > 0 {
+		workClient, err := workclient.NewForConfig(b.restConfig)
+		if err != nil {
+			return nil, []error{fmt.Errorf("cannot create workclient: %v", err)}
+		}
+		works, err := workClient.WorkV1().ManifestWorks(metav1.NamespaceAll).List(b.ctx, b.listOptions("manifestworks"))
+		if errors.IsNotFound(err) {
+			klog.Infof("Skip manifestworks since the hub does not serve them")
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("cannot list manifestworks: %v", err))
+		} else {
+			for index := range works.Items {
+				objects = append(objects, &works.Items[index])
+			}
+		}
+	}
+
+	return objects, errs
+}
+
+// listCustomResourcesOnce lists the custom resources into their stores once,
+// and returns an error for each failed list. The custom resources the hub does
+// not serve are skipped.
+func (b *Builder) listCustomResourcesOnce() []error {
+	if len(b.customResources) == 0 {
+		return nil
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(b.restConfig)
+	if err != nil {
+		return []error{fmt.Errorf("cannot create dynamic client: %v", err)}
+	}
+
+	errs := []error{}
+	for _, c := range b.customResources {
+		name := customResourceCollectorName(c)
+		gvr := schema.GroupVersionResource{Group: c.Group, Version: c.Version, Resource: c.Resource}
+		list, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).List(b.ctx, metav1.ListOptions{LabelSelector: c.Selector})
+		if errors.IsNotFound(err) {
+			klog.Infof("Skip %s since the hub does not serve them", name)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cannot list %s: %v", name, err))
+			continue
+		}
+
+		objects := []interface{}{}
+		for index := range list.Items {
+			objects = append(objects, &list.Items[index])
+		}
+		if err := b.customResourceStores[name].store.Replace(objects, ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// buildCollectors builds the enabled collectors and returns them with their names.
+func (b *Builder) buildCollectors() ([]MetricsCollector, []string) {
 	if b.whiteBlackList == nil {
 		panic("whiteBlackList should not be nil")
 	}
@@ -152,15 +660,118 @@ func (b *Builder) Build() []MetricsCollector {
 
 	}
 
+	for _, c := range b.customResources {
+		activeCollectorNames = append(activeCollectorNames, customResourceCollectorName(c))
+		collectors = append(collectors, b.buildCustomResourceCollector(c))
+	}
+
 	klog.Infof("Active collectors: %s", strings.Join(activeCollectorNames, ","))
+	if b.hubInfoStore != nil {
+		b.hubInfoStore.Update(func(info *cluster.HubInfo) {
+			info.Collectors = activeCollectorNames
+		})
+	}
+	return collectors, activeCollectorNames
+}
 
-	// start watching resources
-	b.startWatchingManagedClusters()
-	b.startWatchingClusterDeployments()
-	b.startWatchingManagedClusterAddOns()
-	b.startWatchingManifestWorks()
+// HasSynced returns true once the collectors are built and every reflector
+// started by Build has completed its initial list.
+func (b *Builder) HasSynced() bool {
+	if !b.built.Load() {
+		return false
+	}
 
-	return collectors
+	b.syncedMutex.RLock()
+	defer b.syncedMutex.RUnlock()
+	for _, synced := range b.syncedFuncs {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// ClusterFilter returns a filter selecting the managed clusters in the given
+// ManagedClusterSet and namespace. An empty clusterSet or namespace does not
+// restrict the managed clusters, and a nil filter is returned if neither is set.
+// The namespace of a managed cluster is named after it.
+func (b *Builder) ClusterFilter(clusterSet, namespace string) ClusterFilter {
+	if clusterSet == "" && namespace == "" {
+		return nil
+	}
+	return func(clusterName string) bool {
+		if namespace != "" && clusterName != namespace {
+			return false
+		}
+		if clusterSet != "" && b.clusterSetCache.GetClusterSet(clusterName) != clusterSet {
+			return false
+		}
+		return true
+	}
+}
+
+// seriesLimiter returns the limiter of the series of the metric families of a
+// collector, shared by all stores of the collector.
+func (b *Builder) seriesLimiter(collector string) *seriesLimiter {
+	if b.seriesLimiters == nil {
+		b.seriesLimiters = map[string]*seriesLimiter{}
+	}
+	limiter, ok := b.seriesLimiters[collector]
+	if !ok {
+		limiter = newSeriesLimiter(b.hubName, collector, b.seriesLimits)
+		b.seriesLimiters[collector] = limiter
+	}
+	return limiter
+}
+
+// getRefreshQueue returns the queue refreshing the metrics which depend on the
+// state of other resources.
+func (b *Builder) getRefreshQueue() *refreshQueue {
+	b.refreshQueueOnce.Do(func() {
+		b.refreshQueue = newRefreshQueue(b.hubName)
+	})
+	return b.refreshQueue
+}
+
+// instrumentStore wraps the store of a resource to report the self metrics of
+// the resource.
+func (b *Builder) instrumentStore(resource string, store cache.Store) cache.Store {
+	if b.instrumentedStores == nil {
+		b.instrumentedStores = map[string]*instrumentedStore{}
+	}
+	instrumented := newInstrumentedStore(b.hubName, resource, store)
+	b.instrumentedStores[resource] = instrumented
+	return instrumented
+}
+
+// listOptions returns the list options selecting the resources watched by a
+// collector.
+func (b *Builder) listOptions(collector string) metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: b.selectors[collector]}
+}
+
+// newListWatch returns a ListWatch of the resources watched by a collector.
+func (b *Builder) newListWatch(c cache.Getter, collector string) cache.ListerWatcher {
+	return instrumentListWatch(b.hubName, collector,
+		cache.NewFilteredListWatchFromClient(c, collector, metav1.NamespaceAll, func(options *metav1.ListOptions) {
+			options.LabelSelector = b.selectors[collector]
+		}))
+}
+
+// runReflector starts the reflector and tracks its initial sync.
+func (b *Builder) runReflector(reflector *cache.Reflector) {
+	b.addSyncedFunc(func() bool {
+		return reflector.LastSyncResourceVersion() != ""
+	})
+	go reflector.Run(b.ctx.Done())
+}
+
+// addSyncedFunc adds a func reporting whether a reflector has completed its
+// initial list.
+func (b *Builder) addSyncedFunc(synced func() bool) {
+	b.syncedMutex.Lock()
+	defer b.syncedMutex.Unlock()
+	b.syncedFuncs = append(b.syncedFuncs, synced)
 }
 
 var availableCollectors = map[string]func(f *Builder) MetricsCollector{
@@ -171,33 +782,38 @@ var availableCollectors = map[string]func(f *Builder) MetricsCollector{
 
 func (b *Builder) buildManagedClusterCollector() MetricsCollector {
 	// build metrics store
-	ocpClient, err := ocpclient.NewForConfig(b.restConfig)
-	if err != nil {
-		klog.Fatalf("cannot create ocpclient: %v", err)
-	}
-
-	hubClusterID := getHubClusterID(ocpClient, b.kubeclient)
+	hubClusterID, hubClusterIDSource := b.knownHubClusterID()
+	b.unmappedValues = cluster.NewUnmappedValues(b.hubName)
 
 	clusterFamilies := []metric.FamilyGenerator{
-		cluster.GetManagedClusterInfoMetricFamilies(hubClusterID, b.hubType),
-		cluster.GetManagedClusterLabelMetricFamilies(hubClusterID),
+		cluster.GetManagedClusterInfoMetricFamilies(hubClusterID, b.HubType, b.infoOptions, b.unmappedValues),
+		cluster.GetManagedClusterLabelMetricFamilies(hubClusterID, b.labelOptions),
+		cluster.GetManagedClusterLabelGroupMetricFamilies(hubClusterID, b.labelOptions),
+		cluster.GetManagedClusterLabelDroppedMetricFamilies(hubClusterID, b.labelOptions),
+		cluster.GetManagedClusterAnnotationMetricFamilies(hubClusterID, b.annotationAllowlist),
 		cluster.GetManagedClusterStatusMetricFamilies(),
 		cluster.GetManagedClusterWorkerCoresMetricFamilies(hubClusterID, b.clusterHibernatingStateCache.IsHibernating),
 	}
-	if b.timestampMetricsEnabled && b.clusterTimestampCache != nil {
-		clusterFamilies = append(clusterFamilies,
-			cluster.GetManagedClusterTimestampMetricFamilies(hubClusterID, b.clusterTimestampCache.GetClusterTimestamps))
-	}
 	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList, clusterFamilies)
 	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
 	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
-	metricsStore := metricsstore.NewMetricsStore(
+	metricsStore := newClusterMetricsStore(
 		familyHeaders,
 		composedMetricGenFuncs,
-	)
+	).withSeriesLimiter(b.seriesLimiter("managedclusters"))
 
 	// register to the composed cluster store
 	b.composedClusterStore.AddStore(metricsStore)
+	// forget the unmapped values of the deleted managed clusters
+	b.composedClusterStore.AddStore(newUnmappedValuesStore(b.unmappedValues))
+
+	// build timestamp metrics store
+	timestampMetricsStore := b.buildTimestampMetricsStore("managedclusters", []metric.FamilyGenerator{
+		cluster.GetManagedClusterTimestampMetricFamilies(hubClusterID, b.clusterTimestampCache.GetClusterTimestamps),
+	})
+
+	// register to the composed cluster store
+	b.composedClusterStore.AddStore(timestampMetricsStore)
 
 	// build counter metrics store
 	filteredMetricFamilies = metric.FilterMetricFamilies(b.whiteBlackList,
@@ -211,8 +827,23 @@ func (b *Builder) buildManagedClusterCollector() MetricsCollector {
 	// register to the composed cluster store
 	b.composedClusterStore.AddStore(counterMetricsStore)
 
+	// build hub info store
+	filteredMetricFamilies = metric.FilterMetricFamilies(b.whiteBlackList,
+		[]metric.FamilyGenerator{
+			cluster.GetHubInfoMetricFamilies(),
+		})
+	b.hubInfoStore = newHubInfoStore(
+		metric.ExtractMetricFamilyHeaders(filteredMetricFamilies),
+		metric.ComposeMetricGenFuncs(filteredMetricFamilies),
+		cluster.HubInfo{
+			ClusterID:       hubClusterID,
+			ClusterIDSource: hubClusterIDSource,
+			Type:            b.HubType(),
+			ExporterVersion: version.Release,
+		})
+
 	// return a composed collector
-	return newComposedMetricsCollector(metricsStore, counterMetricsStore)
+	return newComposedMetricsCollector(metricsStore, timestampMetricsStore, counterMetricsStore, b.hubInfoStore)
 }
 
 func (b *Builder) buildManagedClusterAddOnCollector() MetricsCollector {
@@ -222,10 +853,10 @@ func (b *Builder) buildManagedClusterAddOnCollector() MetricsCollector {
 		})
 	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
 	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
-	metricsStore := metricsstore.NewMetricsStore(
+	metricsStore := newClusterMetricsStore(
 		familyHeaders,
 		composedMetricGenFuncs,
-	)
+	).withSeriesLimiter(b.seriesLimiter("managedclusteraddons"))
 
 	// register to the composed addon store
 	b.composedAddOnStore.AddStore(metricsStore)
@@ -238,20 +869,25 @@ func (b *Builder) buildManifestWorkCollector() MetricsCollector {
 	workFamilies := []metric.FamilyGenerator{
 		work.GetManifestWorkStatusMetricFamilies(b.clusterIdCache.GetClusterId),
 	}
-	if b.timestampMetricsEnabled {
-		workFamilies = append(workFamilies, work.GetManifestWorkTimestampMetricFamilies(b.clusterIdCache.GetClusterId))
-	}
 	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList, workFamilies)
 	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
 	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
-	metricsStore := metricsstore.NewMetricsStore(
+	metricsStore := newClusterMetricsStore(
 		familyHeaders,
 		composedMetricGenFuncs,
-	)
+	).withSeriesLimiter(b.seriesLimiter("manifestworks"))
 
 	// register to the composed manifestwork store
 	b.composedManifestWorkStore.AddStore(metricsStore)
 
+	// build timestamp metrics store
+	timestampMetricsStore := b.buildTimestampMetricsStore("manifestworks", []metric.FamilyGenerator{
+		work.GetManifestWorkTimestampMetricFamilies(b.clusterIdCache.GetClusterId),
+	})
+
+	// register to the composed manifestwork store
+	b.composedManifestWorkStore.AddStore(timestampMetricsStore)
+
 	// build counter metrics store
 	filteredMetricFamilies = metric.FilterMetricFamilies(b.whiteBlackList,
 		[]metric.FamilyGenerator{
@@ -265,21 +901,118 @@ func (b *Builder) buildManifestWorkCollector() MetricsCollector {
 	b.composedManifestWorkStore.AddStore(counterMetricsStore)
 
 	// return a composed collector
-	return newComposedMetricsCollector(metricsStore, counterMetricsStore)
+	return newComposedMetricsCollector(metricsStore, timestampMetricsStore, counterMetricsStore)
+}
+
+// customResourceStore is the store of a custom resource, which keeps the
+// objects besides generating their metrics.
+type customResourceStore struct {
+	objects cache.Store
+	store   *composedStore
+}
+
+// customResourceCollectorName returns the name of the collector of a custom
+// resource, which is the resource qualified by its group.
+func customResourceCollectorName(c config.CustomResourceConfig) string {
+	return schema.GroupResource{Group: c.Group, Resource: c.Resource}.String()
+}
+
+func (b *Builder) buildCustomResourceCollector(c config.CustomResourceConfig) MetricsCollector {
+	filteredMetricFamilies := metric.FilterMetricFamilies(b.whiteBlackList,
+		customresource.GetCustomResourceMetricFamilies(c, b.clusterIdCache.LookupClusterId))
+	composedMetricGenFuncs := metric.ComposeMetricGenFuncs(filteredMetricFamilies)
+	familyHeaders := metric.ExtractMetricFamilyHeaders(filteredMetricFamilies)
+	metricsStore := newClusterMetricsStore(
+		familyHeaders,
+		composedMetricGenFuncs,
+	).withSeriesLimiter(b.seriesLimiter(customResourceCollectorName(c)))
+
+	// the objects are kept to refresh their metrics once the cluster ID is changed
+	if b.customResourceStores == nil {
+		b.customResourceStores = map[string]customResourceStore{}
+	}
+	objects := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	b.customResourceStores[customResourceCollectorName(c)] = customResourceStore{
+		objects: objects,
+		store:   newComposedStore(objects, metricsStore),
+	}
+
+	return metricsStore
+}
+
+func (b *Builder) startWatchingCustomResources() {
+	if len(b.customResources) == 0 {
+		return
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(b.restConfig)
+	if err != nil {
+		klog.Fatalf("cannot create dynamic client: %v", err)
+	}
+
+	for _, c := range b.customResources {
+		name := customResourceCollectorName(c)
+		objects, store := b.customResourceStores[name].objects, b.customResourceStores[name].store
+		gvr := schema.GroupVersionResource{Group: c.Group, Version: c.Version, Resource: c.Resource}
+		selector := c.Selector
+		resource := newOptionalResource(name, gvr)
+
+		target := refreshTarget("customresource/" + name)
+		b.getRefreshQueue().AddHandler(target, func(clusterName string) error {
+			if !resource.Available() {
+				return nil
+			}
+			errs := []error{}
+			for _, obj := range objects.List() {
+				o, ok := obj.(metav1.Object)
+				if !ok || objectClusterName(o) != clusterName {
+					continue
+				}
+				if err := store.Update(obj); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return utilerrors.NewAggregate(errs)
+		})
+
+		// refresh the custom resource store once the cluster ID of a certian cluster is changed
+		b.clusterIdCache.AddOnClusterIdChangeFunc(func(clusterName string) error {
+			b.getRefreshQueue().Enqueue(target, clusterName)
+			return nil
+		})
+
+		lw := instrumentListWatch(b.hubName, name, &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = selector
+				return dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).List(b.ctx, options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = selector
+				return dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).Watch(b.ctx, options)
+			},
+		})
+		instrumentedStore := b.instrumentStore(name, store)
+
+		b.watchOptionalResource(resource, func() (*cache.Reflector, error) {
+			klog.Infof("Start watching %s", gvr.String())
+			return cache.NewReflector(lw, &unstructured.Unstructured{}, instrumentedStore, ResyncPeriod), nil
+		})
+	}
 }
 
-func (b *Builder) startWatchingManagedClusters() {
+// initClusterIdCache initializes the clusterID cache with the managed
+// clusters of the hub, and returns the client of the managed clusters.
+func (b *Builder) initClusterIdCache() (clusterclient.Interface, error) {
 	clusterClient, err := clusterclient.NewForConfig(b.restConfig)
 	if err != nil {
-		klog.Fatalf("cannot create clusterclient: %v", err)
+		return nil, fmt.Errorf("cannot create clusterclient: %v", err)
 	}
 
-	// initialize clusterID cache
-	clusterList, err := clusterClient.ClusterV1().ManagedClusters().List(b.ctx, metav1.ListOptions{})
+	clusterList, err := clusterClient.ClusterV1().ManagedClusters().List(b.ctx, b.listOptions("managedclusters"))
 	if errors.IsNotFound(err) {
 		klog.Errorf("cannot list managed clusters: %v", err)
 	} else if err != nil {
-		klog.Fatalf("cannot list managed clusters: %v", err)
+		return nil, fmt.Errorf("cannot list managed clusters: %v", err)
 	}
 
 	clusters := []interface{}{}
@@ -287,29 +1020,42 @@ func (b *Builder) startWatchingManagedClusters() {
 		clusters = append(clusters, &clusterList.Items[index])
 	}
 	if err := b.clusterIdCache.Replace(clusters, ""); err != nil {
-		klog.Fatalf("cannot initialize clusterID cache: %v", err)
+		return nil, fmt.Errorf("cannot initialize clusterID cache: %v", err)
 	}
 	klog.Infof("Cluster ID cached for %d managed clusters", len(clusters))
+	return clusterClient, nil
+}
 
-	if b.timestampMetricsEnabled && b.clusterTimestampCache != nil {
-		// refresh the managed cluster store once the timestamp of a certian cluster is changed
-		b.clusterTimestampCache.AddOnTimestampChangeFunc(func(clusterName string) error {
-			klog.Infof("Refresh the managed cluster metrics since the timestamp of cluster %q is changed", clusterName)
-			cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(b.ctx, clusterName, metav1.GetOptions{})
-			if err != nil {
-				return err
-			}
+func (b *Builder) startWatchingManagedClusters(clusterClient clusterclient.Interface) {
+	b.getRefreshQueue().AddHandler(refreshManagedCluster, func(clusterName string) error {
+		cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(b.ctx, clusterName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// ignore the managed clusters out of the selector
+		if selector, err := labels.Parse(b.selectors["managedclusters"]); err != nil || !selector.Matches(labels.Set(cluster.Labels)) {
+			return err
+		}
 
-			return b.composedClusterStore.Update(cluster)
-		})
-	}
+		return b.composedClusterStore.Update(cluster)
+	})
+
+	// refresh the managed cluster store once the timestamp of a certian cluster is changed
+	b.clusterTimestampCache.AddOnTimestampChangeFunc(func(clusterName string) error {
+		klog.Infof("Refresh the managed cluster metrics since the timestamp of cluster %q is changed", clusterName)
+		b.getRefreshQueue().Enqueue(refreshManagedCluster, clusterName)
+		return nil
+	})
 
 	// start watching managed clusters
-	lw := cache.NewListWatchFromClient(clusterClient.ClusterV1().RESTClient(), "managedclusters", metav1.NamespaceAll, fields.Everything())
-	reflector := cache.NewReflector(lw, &mcv1.ManagedCluster{}, b.composedClusterStore, ResyncPeriod)
+	lw := b.newListWatch(clusterClient.ClusterV1().RESTClient(), "managedclusters")
+	reflector := cache.NewReflector(lw, &mcv1.ManagedCluster{}, b.instrumentStore("managedclusters", b.composedClusterStore), ResyncPeriod)
 
 	klog.Infof("Start watching ManagedClusters")
-	go reflector.Run(b.ctx.Done())
+	b.runReflector(reflector)
 }
 
 func (b *Builder) startWatchingManagedClusterAddOns() {
@@ -322,10 +1068,12 @@ func (b *Builder) startWatchingManagedClusterAddOns() {
 		klog.Fatalf("cannot create addonclient: %v", err)
 	}
 
-	// refresh the addon store once the cluster ID of a certian cluster is changed
-	b.clusterIdCache.AddOnClusterIdChangeFunc(func(clusterName string) error {
-		klog.Infof("Refresh the addon metrics since the cluster ID of cluster %q is changed", clusterName)
-		addons, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns(clusterName).List(b.ctx, metav1.ListOptions{})
+	resource := newOptionalResource("managedclusteraddons", addonv1alpha1.SchemeGroupVersion.WithResource("managedclusteraddons"))
+	b.getRefreshQueue().AddHandler(refreshManagedClusterAddOns, func(clusterName string) error {
+		if !resource.Available() {
+			return nil
+		}
+		addons, err := addOnClient.AddonV1alpha1().ManagedClusterAddOns(clusterName).List(b.ctx, b.listOptions("managedclusteraddons"))
 		if err != nil {
 			return err
 		}
@@ -340,16 +1088,26 @@ func (b *Builder) startWatchingManagedClusterAddOns() {
 		return utilerrors.NewAggregate(errs)
 	})
 
-	lw := cache.NewListWatchFromClient(addOnClient.AddonV1alpha1().RESTClient(), "managedclusteraddons",
-		metav1.NamespaceAll, fields.Everything())
-	reflector := cache.NewReflector(lw, &addonv1alpha1.ManagedClusterAddOn{}, b.composedAddOnStore, ResyncPeriod)
+	// refresh the addon store once the cluster ID of a certian cluster is changed
+	b.clusterIdCache.AddOnClusterIdChangeFunc(func(clusterName string) error {
+		klog.Infof("Refresh the addon metrics since the cluster ID of cluster %q is changed", clusterName)
+		b.getRefreshQueue().Enqueue(refreshManagedClusterAddOns, clusterName)
+		return nil
+	})
 
-	klog.Infof("Start watching ManagedClusterAddOns")
-	go reflector.Run(b.ctx.Done())
+	lw := b.newListWatch(addOnClient.AddonV1alpha1().RESTClient(), "managedclusteraddons")
+	store := b.instrumentStore("managedclusteraddons", b.composedAddOnStore)
+	b.watchOptionalResource(resource, func() (*cache.Reflector, error) {
+		klog.Infof("Start watching ManagedClusterAddOns")
+		return cache.NewReflector(lw, &addonv1alpha1.ManagedClusterAddOn{}, store, ResyncPeriod), nil
+	})
 }
 
+// startWatchingManifestWorks watches the ManifestWorks if their collector or
+// the timestamp metrics are enabled. They are watched once, even if it is
+// called again once the timestamp metrics are enabled.
 func (b *Builder) startWatchingManifestWorks() {
-	if b.composedManifestWorkStore.Size() == 0 {
+	if b.composedManifestWorkStore.Size() == 0 || !b.manifestWorksWatched.CompareAndSwap(false, true) {
 		return
 	}
 
@@ -358,10 +1116,12 @@ func (b *Builder) startWatchingManifestWorks() {
 		klog.Fatalf("cannot create workclient: %v", err)
 	}
 
-	// refresh the manifestwork store once the cluster ID of a certian cluster is changed
-	b.clusterIdCache.AddOnClusterIdChangeFunc(func(clusterName string) error {
-		klog.Infof("Refresh the manifestwork metrics since the cluster ID of cluster %q is changed", clusterName)
-		works, err := workClient.WorkV1().ManifestWorks(clusterName).List(b.ctx, metav1.ListOptions{})
+	resource := newOptionalResource("manifestworks", workv1.SchemeGroupVersion.WithResource("manifestworks"))
+	b.getRefreshQueue().AddHandler(refreshManifestWorks, func(clusterName string) error {
+		if !resource.Available() {
+			return nil
+		}
+		works, err := workClient.WorkV1().ManifestWorks(clusterName).List(b.ctx, b.listOptions("manifestworks"))
 		if err != nil {
 			return err
 		}
@@ -376,11 +1136,19 @@ func (b *Builder) startWatchingManifestWorks() {
 		return utilerrors.NewAggregate(errs)
 	})
 
-	lw := cache.NewListWatchFromClient(workClient.WorkV1().RESTClient(), "manifestworks", metav1.NamespaceAll, fields.Everything())
-	reflector := cache.NewReflector(lw, &workv1.ManifestWork{}, b.composedManifestWorkStore, ResyncPeriod)
+	// refresh the manifestwork store once the cluster ID of a certian cluster is changed
+	b.clusterIdCache.AddOnClusterIdChangeFunc(func(clusterName string) error {
+		klog.Infof("Refresh the manifestwork metrics since the cluster ID of cluster %q is changed", clusterName)
+		b.getRefreshQueue().Enqueue(refreshManifestWorks, clusterName)
+		return nil
+	})
 
-	klog.Infof("Start watching ManifestWorks")
-	go reflector.Run(b.ctx.Done())
+	lw := b.newListWatch(workClient.WorkV1().RESTClient(), "manifestworks")
+	store := b.instrumentStore("manifestworks", b.composedManifestWorkStore)
+	b.watchOptionalResource(resource, func() (*cache.Reflector, error) {
+		klog.Infof("Start watching ManifestWorks")
+		return cache.NewReflector(lw, &workv1.ManifestWork{}, store, ResyncPeriod), nil
+	})
 }
 
 func (b *Builder) startWatchingClusterDeployments() {
@@ -389,70 +1157,68 @@ func (b *Builder) startWatchingClusterDeployments() {
 		klog.Fatalf("cannot create dynamic client: %v", err)
 	}
 
-	gvr := schema.GroupVersionResource{
-		Group:    "hive.openshift.io",
-		Version:  "v1",
-		Resource: "clusterdeployments",
-	}
-
-	// initialize hibernating state cache
-	clusterDeploymentList, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).List(b.ctx, metav1.ListOptions{})
-	if err != nil {
-		klog.Fatalf("cannot list clusterdeployments: %v", err)
-	}
-
-	clusterDeployments := []interface{}{}
-	for index := range clusterDeploymentList.Items {
-		clusterDeployments = append(clusterDeployments, &clusterDeploymentList.Items[index])
-	}
-	if err := b.clusterHibernatingStateCache.Replace(clusterDeployments, ""); err != nil {
-		klog.Fatalf("cannot initialize hibernating state cache: %v", err)
-	}
-	klog.Infof("Hibernating state cached for %d clusterdeployments", len(clusterDeployments))
-
-	clusterClient, err := clusterclient.NewForConfig(b.restConfig)
-	if err != nil {
-		klog.Fatalf("cannot create clusterclient: %v", err)
-	}
-	b.clusterHibernatingStateCache.AddOnHibernatingStateChangeFunc(func(clusterName string) error {
-		klog.Infof("Refresh the managed cluster metrics since the hibernating state of cluster %q is changed", clusterName)
-		cluster, err := clusterClient.ClusterV1().ManagedClusters().Get(b.ctx, clusterName, metav1.GetOptions{})
-		if err != nil {
-			return err
-		}
+	gvr := clusterDeploymentGVR
 
-		return b.composedClusterStore.Update(cluster)
-	})
-
-	// start watching clusterdeployments
-	lw := &cache.ListWatch{
+	// the clusterdeployments are only served by the hubs with Hive
+	lw := instrumentListWatch(b.hubName, "clusterdeployments", &cache.ListWatch{
 		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
 			return dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).List(b.ctx, options)
 		},
 		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
 			return dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).Watch(b.ctx, options)
 		},
-	}
-	reflector := cache.NewReflector(lw, &unstructured.Unstructured{}, b.clusterHibernatingStateCache, ResyncPeriod)
+	})
+	store := b.instrumentStore("clusterdeployments", b.clusterHibernatingStateCache)
+	b.watchOptionalResource(newOptionalResource("clusterdeployments", gvr), func() (*cache.Reflector, error) {
+		// initialize hibernating state cache
+		clusterDeploymentList, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).List(b.ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("cannot list clusterdeployments: %v", err)
+		}
 
-	klog.Infof("Start watching ClusterDeployments")
-	go reflector.Run(b.ctx.Done())
+		clusterDeployments := []interface{}{}
+		for index := range clusterDeploymentList.Items {
+			clusterDeployments = append(clusterDeployments, &clusterDeploymentList.Items[index])
+		}
+		if err := b.clusterHibernatingStateCache.Replace(clusterDeployments, ""); err != nil {
+			return nil, fmt.Errorf("cannot initialize hibernating state cache: %v", err)
+		}
+		klog.Infof("Hibernating state cached for %d clusterdeployments", len(clusterDeployments))
+
+		b.clusterHibernatingStateCache.AddOnHibernatingStateChangeFunc(func(clusterName string) error {
+			klog.Infof("Refresh the managed cluster metrics since the hibernating state of cluster %q is changed", clusterName)
+			b.getRefreshQueue().Enqueue(refreshManagedCluster, clusterName)
+			return nil
+		})
+
+		// refresh the managed cluster metrics once the clusterdeployments are
+		// watched after the collectors are built
+		if b.built.Load() {
+			for _, clusterName := range b.clusterIdCache.ClusterNames() {
+				b.getRefreshQueue().Enqueue(refreshManagedCluster, clusterName)
+			}
+		}
+
+		klog.Infof("Start watching ClusterDeployments")
+		return cache.NewReflector(lw, &unstructured.Unstructured{}, store, ResyncPeriod), nil
+	})
 }
 
-func getHubClusterID(ocpClient ocpclient.Interface, kubeClient kubernetes.Interface) string {
+// getHubClusterID returns the ID of the hub cluster and where it is read from:
+// the cluster ID of the ClusterVersion, or the UID of the kube-system namespace
+// if the hub has no ClusterVersion.
+func getHubClusterID(ocpClient ocpclient.Interface, kubeClient kubernetes.Interface) (string, string, error) {
 	cv, err := ocpClient.ConfigV1().ClusterVersions().Get(context.TODO(), "version", metav1.GetOptions{})
 	if err == nil {
-		return string(cv.Spec.ClusterID)
+		return string(cv.Spec.ClusterID), HubClusterIDSourceClusterVersion, nil
 	}
-
-	if errors.IsNotFound(err) {
-		ns, err := kubeClient.CoreV1().Namespaces().Get(context.TODO(), "kube-system", metav1.GetOptions{})
-		if err != nil {
-			klog.Fatalf("Error getting namespace %v \n", err)
-		}
-		return string(ns.GetUID())
+	if !errors.IsNotFound(err) {
+		return "", "", fmt.Errorf("cannot get the cluster version: %v", err)
 	}
 
-	klog.Fatalf("Error getting cluster version %v \n,", err)
-	return ""
+	ns, err := kubeClient.CoreV1().Namespaces().Get(context.TODO(), "kube-system", metav1.GetOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("cannot get the kube-system namespace: %v", err)
+	}
+	return string(ns.GetUID()), HubClusterIDSourceKubeSystem, nil
 }
@@ -0,0 +1,140 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"k8s.io/kube-state-metrics/pkg/whiteblacklist"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/simulator"
+)
+
+// benchmarkHubSizes are the numbers of managed clusters of the simulated hubs.
+var benchmarkHubSizes = []int{100, 1000}
+
+func newBenchmarkHub(clusters int) *simulator.Hub {
+	return simulator.NewHub(simulator.Options{
+		Clusters:         clusters,
+		AddOnsPerCluster: 8,
+		WorksPerCluster:  4,
+		Seed:             1,
+	})
+}
+
+func newBenchmarkBuilder(b *testing.B) *Builder {
+	w, err := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return NewBuilder(context.TODO()).
+		WithHubClusterID("hub-id").
+		WithHubType("mce").
+		WithEnabledCollectors([]string{"managedclusters", "managedclusteraddons", "manifestworks"}).
+		WithTimestampMetricsEnabled(true).
+		WithWhiteBlackList(w)
+}
+
+// storeTarget returns a simulator target writing into the stores of the
+// builder, as the reflectors would.
+func storeTarget(b *Builder) *simulator.StoreTarget {
+	return &simulator.StoreTarget{
+		ManagedClusters:      b.composedClusterStore,
+		ManagedClusterAddOns: b.composedAddOnStore,
+		ManifestWorks:        b.composedManifestWorkStore,
+		ClusterDeployments:   b.clusterHibernatingStateCache,
+	}
+}
+
+// BenchmarkBuilder_Build reports the time, the allocations and the heap
+// retained to build the collectors of a hub.
+func BenchmarkBuilder_Build(b *testing.B) {
+	for _, size := range benchmarkHubSizes {
+		b.Run(fmt.Sprintf("clusters=%d", size), func(b *testing.B) {
+			objects := newBenchmarkHub(size).Objects()
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			var heap uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				collectors, err := newBenchmarkBuilder(b).BuildFromObjects(objects)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if after.HeapAlloc > before.HeapAlloc {
+					heap += after.HeapAlloc - before.HeapAlloc
+				}
+				runtime.KeepAlive(collectors)
+			}
+			b.ReportMetric(float64(heap)/float64(b.N), "heap-bytes/op")
+		})
+	}
+}
+
+// BenchmarkBuilder_Event reports the latency of the simulated changes of a
+// hub, from the event to the updated metrics.
+func BenchmarkBuilder_Event(b *testing.B) {
+	for _, size := range benchmarkHubSizes {
+		b.Run(fmt.Sprintf("clusters=%d", size), func(b *testing.B) {
+			hub := newBenchmarkHub(size)
+			builder := newBenchmarkBuilder(b)
+			if _, err := builder.BuildFromObjects(hub.Objects()); err != nil {
+				b.Fatal(err)
+			}
+			target := storeTarget(builder)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			events := 0
+			var elapsed time.Duration
+			for i := 0; i < b.N; i++ {
+				for _, event := range hub.Next() {
+					start := time.Now()
+					if err := target.Apply(context.TODO(), event); err != nil {
+						b.Fatal(err)
+					}
+					elapsed += time.Since(start)
+					events++
+				}
+			}
+			if events > 0 {
+				b.ReportMetric(float64(elapsed.Nanoseconds())/float64(events), "ns/event")
+			}
+		})
+	}
+}
+
+// BenchmarkBuilder_Scrape reports the time and the size of a scrape of the
+// metrics of a hub.
+func BenchmarkBuilder_Scrape(b *testing.B) {
+	for _, size := range benchmarkHubSizes {
+		b.Run(fmt.Sprintf("clusters=%d", size), func(b *testing.B) {
+			collectors, err := newBenchmarkBuilder(b).BuildFromObjects(newBenchmarkHub(size).Objects())
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			w := &countingWriter{writer: io.Discard}
+			for i := 0; i < b.N; i++ {
+				for _, c := range collectors {
+					c.WriteAll(w)
+				}
+			}
+			b.ReportMetric(float64(w.count)/float64(b.N), "bytes/scrape")
+		})
+	}
+}
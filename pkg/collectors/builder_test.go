@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	ocinfrav1 "github.com/openshift/api/config/v1"
@@ -15,14 +16,22 @@ import (
 	"golang.org/x/net/context"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/kube-state-metrics/pkg/metric"
 	koptions "k8s.io/kube-state-metrics/pkg/options"
 	"k8s.io/kube-state-metrics/pkg/whiteblacklist"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/version"
+
 	ocpclientfake "github.com/openshift/client-go/config/clientset/versioned/fake"
 	"k8s.io/client-go/kubernetes/fake"
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
@@ -189,6 +198,27 @@ func TestBuilder_WithNamespaces(t *testing.T) {
 	}
 }
 
+func TestBuilder_WithSelectors(t *testing.T) {
+	b := NewBuilder(ctx).WithSelectors(map[string]string{
+		"managedclusters": "env=prod",
+	})
+
+	tests := []struct {
+		collector string
+		want      string
+	}{
+		{collector: "managedclusters", want: "env=prod"},
+		{collector: "manifestworks", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.collector, func(t *testing.T) {
+			if got := b.listOptions(test.collector).LabelSelector; got != test.want {
+				t.Errorf("expected label selector %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
 func TestBuilder_WithWhiteBlackList(t *testing.T) {
 	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
 	type fields struct {
@@ -258,6 +288,8 @@ func TestBuilder_Build(t *testing.T) {
 # TYPE acm_managed_cluster_import_timestamp gauge
 # HELP acm_managed_cluster_count Managed cluster count
 # TYPE acm_managed_cluster_count gauge
+# HELP acm_hub_info Hub cluster information
+# TYPE acm_hub_info gauge
 `
 		addOnCollectorHeaders = `# HELP acm_managed_cluster_addon_status_condition Managed cluster add-on status condition
 # TYPE acm_managed_cluster_addon_status_condition gauge
@@ -296,7 +328,7 @@ func TestBuilder_Build(t *testing.T) {
 
 	ocpClient, _ := ocpclient.NewForConfig(envTest.Config)
 
-	version := &ocinfrav1.ClusterVersion{
+	clusterVersion := &ocinfrav1.ClusterVersion{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "version",
 		},
@@ -307,10 +339,27 @@ func TestBuilder_Build(t *testing.T) {
 
 	_, err = ocpClient.ConfigV1().
 		ClusterVersions().
-		Create(context.TODO(), version, metav1.CreateOptions{})
+		Create(context.TODO(), clusterVersion, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(envTest.Config)
 	if err != nil {
 		t.Fatal(err)
 	}
+	serverVersion, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hubInfo returns the info of the hub of the envtest with the given
+	// collectors, which has neither OpenShift nor product version.
+	hubInfo := func(collectors string) string {
+		return `acm_hub_info{hub_cluster_id="mycluster_id",hub_cluster_id_source="clusterversion",hub_type="mce",` +
+			`kube_version="` + serverVersion.GitVersion + `",ocp_version="",product_version="",` +
+			`exporter_version="` + version.Release + `",collectors="` + collectors + `"} 1
+`
+	}
 
 	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
 	type fields struct {
@@ -343,7 +392,7 @@ func TestBuilder_Build(t *testing.T) {
 				enabledCollectors: []string{"managedclusters"},
 				whiteBlackList:    w,
 			},
-			want: []string{clusterCollectorHeaders},
+			want: []string{clusterCollectorHeaders + hubInfo("managedclusters")},
 		},
 		{
 			name: "all collectors enabled",
@@ -354,18 +403,25 @@ func TestBuilder_Build(t *testing.T) {
 				enabledCollectors: []string{"managedclusters", "managedclusteraddons", "manifestworks"},
 				whiteBlackList:    w,
 			},
-			want: []string{clusterCollectorHeaders, addOnCollectorHeaders, workCollectorHeaders},
+			want: []string{
+				clusterCollectorHeaders + hubInfo("managedclusters,managedclusteraddons,manifestworks"),
+				addOnCollectorHeaders,
+				workCollectorHeaders,
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := NewBuilder(tt.fields.ctx).WithTimestampMetricsEnabled(true)
+			b := NewBuilder(tt.fields.ctx).WithTimestampMetricsEnabled(true).WithHubType(HubTypeMCE)
 			b.namespaces = tt.fields.namespaces
 			b.enabledCollectors = tt.fields.enabledCollectors
 			b.whiteBlackList = tt.fields.whiteBlackList
 			b.restConfig = envTest.Config
 
-			stores := b.Build()
+			stores, err := b.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
 			if len(stores) != len(tt.want) {
 				t.Errorf(
 					"number of MetricsStores = %v, want %v",
@@ -384,6 +440,98 @@ func TestBuilder_Build(t *testing.T) {
 	}
 }
 
+func TestBuilder_startWatchingManifestWorks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the hub does not serve the ManifestWorks, so the watch is started without reflector
+	b := NewBuilder(ctx).
+		WithRestConfig(&rest.Config{Host: "https://127.0.0.1:1"}).
+		WithKubeclient(&discoveryKubeclient{
+			Interface: kubeclientfake.NewSimpleClientset(),
+			discovery: &servingDiscovery{gvr: testOptionalGVR},
+		}).
+		WithTimestampMetricsEnabled(false)
+
+	// without the manifestworks collector nor the timestamp metrics
+	b.startWatchingManifestWorks()
+	if b.manifestWorksWatched.Load() {
+		t.Errorf("expected the manifestworks not watched")
+	}
+	if b.composedManifestWorkStore.Size() != 0 {
+		t.Errorf("expected no manifestwork store, got %d", b.composedManifestWorkStore.Size())
+	}
+
+	// the manifestworks are watched once the timestamp metrics are enabled on the built hub
+	b.watching.Store(true)
+	if err := b.SetTimestampMetricsEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	if !b.manifestWorksWatched.Load() {
+		t.Errorf("expected the manifestworks watched once the timestamp metrics are enabled")
+	}
+	if _, ok := b.instrumentedStores["manifestworks"]; !ok {
+		t.Errorf("expected the manifestwork store instrumented")
+	}
+
+	// the timestamp cache is added once
+	if err := b.SetTimestampMetricsEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetTimestampMetricsEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	if b.composedManifestWorkStore.Size() != 1 {
+		t.Errorf("expected the timestamp cache added once, got %d stores", b.composedManifestWorkStore.Size())
+	}
+}
+
+func TestBuilder_HasSynced(t *testing.T) {
+	tests := []struct {
+		name        string
+		built       bool
+		syncedFuncs []func() bool
+		want        bool
+	}{
+		{
+			name: "not built",
+			want: false,
+		},
+		{
+			name:  "built without reflectors",
+			built: true,
+			want:  true,
+		},
+		{
+			name:  "not all synced",
+			built: true,
+			syncedFuncs: []func() bool{
+				func() bool { return true },
+				func() bool { return false },
+			},
+			want: false,
+		},
+		{
+			name:  "all synced",
+			built: true,
+			syncedFuncs: []func() bool{
+				func() bool { return true },
+				func() bool { return true },
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Builder{syncedFuncs: tt.syncedFuncs}
+			b.built.Store(tt.built)
+			if got := b.HasSynced(); got != tt.want {
+				t.Errorf("Builder.HasSynced() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func writeKubeconfigFile(restConfig *rest.Config, kubeconfigFileName string) error {
 	kubeconfig := clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{"default-cluster": {
@@ -413,6 +561,8 @@ func Test_getHubClusterID(t *testing.T) {
 		clusterVersion *ocinfrav1.ClusterVersion
 		ns             *corev1.Namespace
 		want           string
+		wantSource     string
+		wantErr        bool
 	}{
 		{
 			name: "Get cluster id",
@@ -424,8 +574,9 @@ func Test_getHubClusterID(t *testing.T) {
 					ClusterID: "mycluster_id",
 				},
 			},
-			ns:   &corev1.Namespace{},
-			want: "mycluster_id",
+			ns:         &corev1.Namespace{},
+			want:       "mycluster_id",
+			wantSource: HubClusterIDSourceClusterVersion,
 		},
 		{
 			name:           "Get cluster id",
@@ -437,7 +588,14 @@ func Test_getHubClusterID(t *testing.T) {
 					UID:  "kube-system-uid",
 				},
 			},
-			want: "kube-system-uid",
+			want:       "kube-system-uid",
+			wantSource: HubClusterIDSourceKubeSystem,
+		},
+		{
+			name:           "no cluster id",
+			clusterVersion: &ocinfrav1.ClusterVersion{},
+			ns:             &corev1.Namespace{},
+			wantErr:        true,
 		},
 	}
 
@@ -445,9 +603,272 @@ func Test_getHubClusterID(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			fakeOcpClient := ocpclientfake.NewSimpleClientset(tt.clusterVersion)
 			fakeKubeClient := kubeclientfake.NewSimpleClientset(tt.ns)
-			if got := getHubClusterID(fakeOcpClient, fakeKubeClient); got != tt.want {
-				t.Errorf("getHubClusterID() = %v, want %v", got, tt.want)
+			got, source, err := getHubClusterID(fakeOcpClient, fakeKubeClient)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getHubClusterID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("getHubClusterID() = %v, %v, want %v, %v", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestBuilder_ClusterFilter(t *testing.T) {
+	b := NewBuilder(context.TODO())
+	if err := b.composedClusterStore.Replace([]interface{}{
+		newTestManagedClusterInSet("cluster1", "dev"),
+		newTestManagedClusterInSet("cluster2", "dev"),
+		newTestManagedClusterInSet("cluster3", "prod"),
+	}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		clusterSet string
+		namespace  string
+		want       []string
+	}{
+		{
+			name:       "cluster set",
+			clusterSet: "dev",
+			want:       []string{"cluster1", "cluster2"},
+		},
+		{
+			name:      "namespace",
+			namespace: "cluster3",
+			want:      []string{"cluster3"},
+		},
+		{
+			name:       "cluster set and namespace",
+			clusterSet: "dev",
+			namespace:  "cluster3",
+			want:       []string{},
+		},
+		{
+			name:       "unknown cluster set",
+			clusterSet: "test",
+			want:       []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := b.ClusterFilter(tt.clusterSet, tt.namespace)
+			got := []string{}
+			for _, clusterName := range []string{"cluster1", "cluster2", "cluster3"} {
+				if filter(clusterName) {
+					got = append(got, clusterName)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected clusters %v, but got %v", tt.want, got)
 			}
 		})
 	}
+
+	if b.ClusterFilter("", "") != nil {
+		t.Errorf("expected nil filter")
+	}
+}
+
+func TestBuilder_SetTimestampMetricsEnabled(t *testing.T) {
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO()).WithTimestampMetricsEnabled(false)
+	b.whiteBlackList = w
+	store := b.buildTimestampMetricsStore("managedclusters", []metric.FamilyGenerator{
+		{
+			Name: "acm_managed_cluster_import_timestamp",
+			Type: metric.Gauge,
+			Help: "Import timestamp",
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{Value: 1}}}
+			},
+		},
+	})
+
+	if store.Enabled() {
+		t.Errorf("expected the timestamp metrics store to be disabled")
+	}
+	if err := b.SetTimestampMetricsEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range b.timestampStores {
+		if !s.Enabled() {
+			t.Errorf("expected the timestamp stores to be enabled")
+		}
+	}
+	if err := b.SetTimestampMetricsEnabled(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range b.timestampStores {
+		if s.Enabled() {
+			t.Errorf("expected the timestamp stores to be disabled")
+		}
+	}
+}
+
+func TestBuilder_buildCustomResourceCollector(t *testing.T) {
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO())
+	b.whiteBlackList = w
+	c := config.CustomResourceConfig{
+		Group:    "policy.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "policies",
+		Metrics: []config.CustomResourceMetricConfig{
+			{
+				Name: "acm_policy_compliance",
+				Type: config.MetricTypeStateSet,
+				Path: []string{"status", "compliant"},
+				List: []string{"Compliant", "NonCompliant"},
+			},
+		},
+	}
+
+	if name := customResourceCollectorName(c); name != "policies.policy.open-cluster-management.io" {
+		t.Errorf("unexpected collector name %q", name)
+	}
+
+	// the managed cluster labels are only set for a known cluster
+	if err := b.clusterIdCache.Add(newTestManagedCluster("cluster1")); err != nil {
+		t.Fatal(err)
+	}
+	collector := b.buildCustomResourceCollector(c)
+	store := b.customResourceStores[customResourceCollectorName(c)]
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "policy1", "namespace": "cluster1", "uid": "uid1"},
+		"status":   map[string]interface{}{"compliant": "Compliant"},
+	}}
+	if err := store.store.Add(policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.objects.List()) != 1 {
+		t.Errorf("expected the object to be kept")
+	}
+
+	buf := &bytes.Buffer{}
+	collector.WriteAll(buf)
+	want := `acm_policy_compliance{name="policy1",namespace="cluster1",managed_cluster_id="cluster1",managed_cluster_name="cluster1",state="Compliant"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, buf.String())
+	}
+}
+
+func TestBuilder_Build_unreachableHub(t *testing.T) {
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO()).
+		WithHubName("unreachable").
+		WithRestConfig(&rest.Config{Host: "https://127.0.0.1:1"}).
+		WithKubeclient(kubeclientfake.NewSimpleClientset()).
+		WithEnabledCollectors([]string{"managedclusters"}).
+		WithWhiteBlackList(w)
+
+	if _, err := b.Build(); err == nil {
+		t.Fatal("expected an error building the collectors of an unreachable hub")
+	}
+	hub := b.Hub()
+	if hub.Name != "unreachable" || hub.ClusterID() != "" {
+		t.Errorf("unexpected hub %s with ID %q", hub.Name, hub.ClusterID())
+	}
+	if hub.HasSynced() || hub.Collectors() != nil {
+		t.Errorf("expected the hub not synced and without collectors")
+	}
+}
+
+func TestBuilder_BuildFromObjects(t *testing.T) {
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO()).
+		WithHubClusterID("hub-id").
+		WithEnabledCollectors([]string{"managedclusters", "managedclusteraddons"}).
+		WithWhiteBlackList(w)
+
+	cluster := newTestManagedCluster("cluster1")
+	cluster.Labels = map[string]string{"clusterID": "cluster1-id"}
+	addon := &addonv1alpha1.ManagedClusterAddOn{
+		ObjectMeta: metav1.ObjectMeta{Name: "work-manager", Namespace: "cluster1", UID: "uid-addon"},
+	}
+	ignored := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ignored"}}
+
+	collectors, err := b.BuildFromObjects([]runtime.Object{cluster, addon, ignored})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collectors) != 2 {
+		t.Fatalf("expected 2 collectors, got %d", len(collectors))
+	}
+
+	buf := &bytes.Buffer{}
+	for _, c := range collectors {
+		c.WriteAll(buf)
+	}
+	for _, want := range []string{
+		`acm_managed_cluster_labels{hub_cluster_id="hub-id",managed_cluster_id="cluster1-id"} 1`,
+		`acm_managed_cluster_count 1`,
+		`acm_managed_cluster_addon_status_condition{addon_name="work-manager",managed_cluster_id="cluster1-id",managed_cluster_name="cluster1",condition="Available",status="unknown"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, buf.String())
+		}
+	}
+	if !b.HasSynced() {
+		t.Errorf("expected the builder to be synced")
+	}
+}
+
+func TestBuilder_RegisteredFamilies(t *testing.T) {
+	// the collectors are built in the order of their names
+	enabledCollectors := []string{
+		generators.CollectorManagedClusterAddOns,
+		generators.CollectorManagedClusters,
+		generators.CollectorManifestWorks,
+	}
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO()).
+		WithHubClusterID("hub-id").
+		WithTimestampMetricsEnabled(true).
+		WithEnabledCollectors(enabledCollectors).
+		WithWhiteBlackList(w)
+
+	collectors, err := b.BuildFromObjects(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exposed := map[string]bool{}
+	for index, c := range collectors {
+		buf := &bytes.Buffer{}
+		c.WriteAll(buf)
+
+		helps := map[string]string{}
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if help, ok := strings.CutPrefix(line, "# HELP "); ok {
+				name, help, _ := strings.Cut(help, " ")
+				helps[name] = help
+				continue
+			}
+			metricType, ok := strings.CutPrefix(line, "# TYPE ")
+			if !ok {
+				continue
+			}
+			name, metricType, _ := strings.Cut(metricType, " ")
+			exposed[name] = true
+
+			desc, ok := generators.RegisteredFamily(name)
+			if !ok {
+				t.Errorf("metric family %q is not registered", name)
+				continue
+			}
+			if desc.Help != helps[name] || string(desc.Type) != metricType || desc.Collector != enabledCollectors[index] {
+				t.Errorf("metric family %q is registered as %+v, but exposed by collector %q with help %q and type %q",
+					name, desc, enabledCollectors[index], helps[name], metricType)
+			}
+		}
+	}
+
+	for _, desc := range generators.RegisteredFamilies() {
+		if !exposed[desc.Name] {
+			t.Errorf("registered metric family %q is not exposed", desc.Name)
+		}
+	}
 }
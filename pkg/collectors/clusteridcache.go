@@ -20,7 +20,7 @@ type onClusterIdChangeFunc func(clusterName string) error
 // interface. Instead of storing entire ManagedCluster objects, it
 // stores cluster IDs of ManagedCluster objects.
 type clusterIdCache struct {
-	// Protects metrics
+	// Protects data and onClusterIdChangeFuncs
 	mutex sync.RWMutex
 
 	// data is a map indexed by cluster name with cluster IDs
@@ -37,10 +37,34 @@ func newClusterIdCache() *clusterIdCache {
 }
 
 func (s *clusterIdCache) GetClusterId(clusterName string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	return s.data[clusterName]
 }
 
+// LookupClusterId returns the ID of a cluster, which may be empty, and
+// whether the cluster is known.
+func (s *clusterIdCache) LookupClusterId(clusterName string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	clusterId, ok := s.data[clusterName]
+	return clusterId, ok
+}
+
+// ClusterNames returns the names of the cached clusters.
+func (s *clusterIdCache) ClusterNames() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	names := make([]string, 0, len(s.data))
+	for name := range s.data {
+		names = append(names, name)
+	}
+	return names
+}
+
 func (s *clusterIdCache) AddOnClusterIdChangeFunc(callback onClusterIdChangeFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.onClusterIdChangeFuncs = append(s.onClusterIdChangeFuncs, callback)
 }
 
@@ -56,16 +80,20 @@ func (s *clusterIdCache) Add(obj interface{}) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	clusterId := s.data[clusterName]
+	clusterId, ok := s.data[clusterName]
 	newClusterId := getClusterID(o)
-	if clusterId == newClusterId {
+	if ok && clusterId == newClusterId {
 		return nil
 	}
 
 	s.data[clusterName] = newClusterId
 	klog.V(5).Infof("Cluster ID of cluster %q is changed from %q to %q", clusterName, clusterId, newClusterId)
+	return s.runCallbacks(clusterName)
+}
 
-	// run callback funcs once cluster ID is changed
+// runCallbacks runs the callback funcs once the cluster ID of a cluster is
+// changed, or once the cluster is added or deleted.
+func (s *clusterIdCache) runCallbacks(clusterName string) error {
 	errs := []error{}
 	for _, callback := range s.onClusterIdChangeFuncs {
 		if err := callback(clusterName); err != nil {
@@ -92,8 +120,11 @@ func (s *clusterIdCache) Delete(obj interface{}) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if _, ok := s.data[o.GetName()]; !ok {
+		return nil
+	}
 	delete(s.data, o.GetName())
-	return nil
+	return s.runCallbacks(o.GetName())
 }
 
 // List implements the List method of the store interface.
@@ -113,6 +144,8 @@ func (s *clusterIdCache) Get(obj interface{}) (item interface{}, exists bool, er
 
 // GetByKey implements the GetByKey method of the store interface.
 func (s *clusterIdCache) GetByKey(key string) (item interface{}, exists bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	cluserId, ok := s.data[key]
 	return cluserId, ok, nil
 }
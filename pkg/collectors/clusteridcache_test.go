@@ -37,6 +37,15 @@ func Test_ClusterIDCache_GetClusterId(t *testing.T) {
 			},
 		},
 	}
+	// an OpenShift cluster whose ID is not known yet
+	cluster4 := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster4",
+			Labels: map[string]string{
+				mciv1beta1.LabelKubeVendor: string(mciv1beta1.KubeVendorOpenShift),
+			},
+		},
+	}
 
 	tests := []struct {
 		name              string
@@ -46,6 +55,7 @@ func Test_ClusterIDCache_GetClusterId(t *testing.T) {
 		toUpdate          []interface{}
 		toDelete          []interface{}
 		want              string
+		wantKnown         bool
 		numberOfIdChanged int
 	}{
 		{
@@ -83,10 +93,19 @@ func Test_ClusterIDCache_GetClusterId(t *testing.T) {
 			numberOfIdChanged: 2,
 		},
 		{
-			name:        "delete",
-			clusterName: "cluster1",
-			existing:    []interface{}{cluster1},
-			toDelete:    []interface{}{cluster1},
+			name:              "add cluster without id",
+			clusterName:       "cluster4",
+			toAdd:             []interface{}{cluster4},
+			toUpdate:          []interface{}{cluster4},
+			wantKnown:         true,
+			numberOfIdChanged: 1,
+		},
+		{
+			name:              "delete",
+			clusterName:       "cluster1",
+			existing:          []interface{}{cluster1},
+			toDelete:          []interface{}{cluster1, cluster1},
+			numberOfIdChanged: 1,
 		},
 	}
 
@@ -119,6 +138,9 @@ func Test_ClusterIDCache_GetClusterId(t *testing.T) {
 			if actual := cache.GetClusterId(tt.clusterName); actual != tt.want {
 				t.Errorf("want\n%s\nbut got\n%v", tt.want, actual)
 			}
+			if _, known := cache.LookupClusterId(tt.clusterName); known != (tt.want != "" || tt.wantKnown) {
+				t.Errorf("unexpected cluster %s known %v", tt.clusterName, known)
+			}
 
 			if numberOfIdChanged != tt.numberOfIdChanged {
 				t.Errorf("want numberOfIdChanged %d\nbut got%d", tt.numberOfIdChanged, numberOfIdChanged)
@@ -0,0 +1,295 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"io"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+)
+
+// ClusterFilter returns true if the metrics of the given managed cluster
+// should be written.
+type ClusterFilter func(clusterName string) bool
+
+// clusterMetricsStore implements the k8s.io/client-go/tools/cache.Store
+// interface. Like the MetricsStore of kube-state-metrics, it stores the
+// metrics generated based on the Kubernetes objects instead of the objects.
+// It also keeps the managed cluster each object belongs to, so the metrics
+// of some of the managed clusters can be written without parsing them.
+type clusterMetricsStore struct {
+	// Protects metrics, families, clusters, series and dropped
+	mutex sync.RWMutex
+
+	// metrics is a map indexed by Kubernetes object id, containing a slice of
+	// metric families, containing a slice of metrics.
+	metrics map[types.UID][][]byte
+
+	// families is a map indexed by Kubernetes object id, containing the metric
+	// families generated from the object, which the formats other than the
+	// text formats are encoded from.
+	families map[types.UID][]*metric.Family
+
+	// clusters is a map indexed by Kubernetes object id with the name of the
+	// managed cluster the object belongs to.
+	clusters map[types.UID]string
+
+	// series is a map indexed by Kubernetes object id, containing the number
+	// of series of each metric family. It is only kept with a limiter.
+	series map[types.UID][]int
+
+	// dropped is a map indexed by Kubernetes object id, containing the number
+	// of series of each metric family dropped by the limiter, so they are
+	// counted once. It is only kept with a limiter.
+	dropped map[types.UID][]int
+
+	// limiter limits the series of the metric families, if set.
+	limiter *seriesLimiter
+
+	// headers contains the header (TYPE and HELP) of each metric family.
+	headers []string
+	// familyHeaders contains the name, help and type of each metric family.
+	familyHeaders []familyHeader
+
+	// generateMetricsFunc generates metrics based on a given Kubernetes object
+	// and returns them grouped by metric family.
+	generateMetricsFunc func(interface{}) []metricsstore.FamilyByteSlicer
+}
+
+// newClusterMetricsStore returns a new clusterMetricsStore
+func newClusterMetricsStore(headers []string, generateFunc func(interface{}) []metricsstore.FamilyByteSlicer) *clusterMetricsStore {
+	return &clusterMetricsStore{
+		generateMetricsFunc: generateFunc,
+		headers:             headers,
+		familyHeaders:       parseFamilyHeaders(headers),
+		metrics:             map[types.UID][][]byte{},
+		families:            map[types.UID][]*metric.Family{},
+		clusters:            map[types.UID]string{},
+		series:              map[types.UID][]int{},
+		dropped:             map[types.UID][]int{},
+	}
+}
+
+// withSeriesLimiter limits the series of the metric families of the store
+// with the given limiter.
+func (s *clusterMetricsStore) withSeriesLimiter(limiter *seriesLimiter) *clusterMetricsStore {
+	s.limiter = limiter
+	return s
+}
+
+// objectClusterName returns the name of the managed cluster an object belongs
+// to. A cluster scoped object is the managed cluster itself, a namespaced one
+// is in the namespace of the managed cluster.
+func objectClusterName(o metav1.Object) string {
+	if o.GetNamespace() != "" {
+		return o.GetNamespace()
+	}
+	return o.GetName()
+}
+
+// Add implements the Add method of the store interface.
+func (s *clusterMetricsStore) Add(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	families := metricFamilies(s.generateMetricsFunc(obj))
+	familyStrings := make([][]byte, len(families))
+	for i, f := range families {
+		if f != nil {
+			familyStrings[i] = f.ByteSlice()
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.limiter != nil {
+		s.limitSeries(o.GetUID(), families, familyStrings)
+	}
+	s.metrics[o.GetUID()] = familyStrings
+	s.families[o.GetUID()] = families
+	s.clusters[o.GetUID()] = objectClusterName(o)
+	return nil
+}
+
+// limitSeries admits the series of each metric family of an object within the
+// limits, and drops the others from the families and the family strings. It
+// must be called with the mutex held.
+func (s *clusterMetricsStore) limitSeries(uid types.UID, families []*metric.Family, familyStrings [][]byte) {
+	current, currentDropped := s.series[uid], s.dropped[uid]
+	series := make([]int, len(families))
+	dropped := make([]int, len(families))
+	for i, family := range families {
+		if family == nil {
+			continue
+		}
+		currentSeries, droppedSeries := 0, 0
+		if i < len(current) {
+			currentSeries = current[i]
+		}
+		if i < len(currentDropped) {
+			droppedSeries = currentDropped[i]
+		}
+
+		series[i] = s.limiter.admit(s.familyHeaders[i].name, currentSeries, droppedSeries, len(family.Metrics))
+		dropped[i] = len(family.Metrics) - series[i]
+		if series[i] < len(family.Metrics) {
+			admitted := *family
+			admitted.Metrics = family.Metrics[:series[i]]
+			families[i] = &admitted
+			familyStrings[i] = admitted.ByteSlice()
+		}
+	}
+	s.series[uid] = series
+	s.dropped[uid] = dropped
+}
+
+// releaseSeries releases the series of the metric families of an object. The
+// dropped series are kept until the object is deleted. It must be called with
+// the mutex held.
+func (s *clusterMetricsStore) releaseSeries(uid types.UID) {
+	if s.limiter == nil {
+		return
+	}
+	for i, series := range s.series[uid] {
+		s.limiter.admit(s.familyHeaders[i].name, series, 0, 0)
+	}
+	delete(s.series, uid)
+}
+
+// Update implements the Update method of the store interface.
+func (s *clusterMetricsStore) Update(obj interface{}) error {
+	return s.Add(obj)
+}
+
+// Delete implements the Delete method of the store interface.
+func (s *clusterMetricsStore) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.releaseSeries(o.GetUID())
+	delete(s.dropped, o.GetUID())
+	delete(s.metrics, o.GetUID())
+	delete(s.families, o.GetUID())
+	delete(s.clusters, o.GetUID())
+	return nil
+}
+
+// List implements the List method of the store interface.
+func (s *clusterMetricsStore) List() []interface{} {
+	return nil
+}
+
+// ListKeys implements the ListKeys method of the store interface.
+func (s *clusterMetricsStore) ListKeys() []string {
+	return nil
+}
+
+// Get implements the Get method of the store interface.
+func (s *clusterMetricsStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// GetByKey implements the GetByKey method of the store interface.
+func (s *clusterMetricsStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// Replace implements the Replace method of the store interface.
+func (s *clusterMetricsStore) Replace(list []interface{}, _ string) error {
+	s.mutex.Lock()
+	for uid := range s.series {
+		s.releaseSeries(uid)
+	}
+	s.metrics = map[types.UID][][]byte{}
+	s.families = map[types.UID][]*metric.Family{}
+	s.clusters = map[types.UID]string{}
+	s.mutex.Unlock()
+
+	for _, o := range list {
+		if err := s.Add(o); err != nil {
+			return err
+		}
+	}
+
+	// forget the dropped series of the objects not listed anymore
+	s.mutex.Lock()
+	for uid := range s.dropped {
+		if _, ok := s.metrics[uid]; !ok {
+			delete(s.dropped, uid)
+		}
+	}
+	s.mutex.Unlock()
+	return nil
+}
+
+// Resync implements the Resync method of the store interface.
+func (s *clusterMetricsStore) Resync() error {
+	return nil
+}
+
+// WriteAll writes all metrics of the store into the given writer, zipped with the
+// help text of each metric family.
+func (s *clusterMetricsStore) WriteAll(w io.Writer) {
+	s.WriteFiltered(w, nil)
+}
+
+// WriteFiltered writes the metrics of the objects belonging to the managed
+// clusters selected by the filter into the given writer, zipped with the help
+// text of each metric family. A nil filter selects all managed clusters.
+func (s *clusterMetricsStore) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	uids := make([]types.UID, 0, len(s.metrics))
+	for uid := range s.metrics {
+		if filter == nil || filter(s.clusters[uid]) {
+			uids = append(uids, uid)
+		}
+	}
+
+	for i, help := range s.headers {
+		write(w, []byte(help))
+		write(w, []byte{'\n'})
+		for _, uid := range uids {
+			write(w, s.metrics[uid][i])
+		}
+	}
+}
+
+// CollectFamilies calls collect with each metric family of the store and the
+// metrics generated from the objects belonging to the managed clusters
+// selected by the filter. A nil filter selects all managed clusters.
+func (s *clusterMetricsStore) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	uids := make([]types.UID, 0, len(s.families))
+	for uid := range s.families {
+		if filter == nil || filter(s.clusters[uid]) {
+			uids = append(uids, uid)
+		}
+	}
+
+	for i, header := range s.familyHeaders {
+		metrics := make([][]*metric.Metric, 0, len(uids))
+		for _, uid := range uids {
+			if m := familyMetrics(s.families[uid], i); len(m) > 0 {
+				metrics = append(metrics, m)
+			}
+		}
+		collect(newFamily(header, metrics))
+	}
+}
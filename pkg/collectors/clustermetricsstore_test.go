@@ -0,0 +1,112 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+)
+
+func Test_ClusterMetricsStore(t *testing.T) {
+	headers := []string{`# HELP acm_managed_cluster_addon_info Addon information
+# TYPE acm_managed_cluster_addon_info gauge`}
+
+	generateFunc := func(obj interface{}) []metricsstore.FamilyByteSlicer {
+		o := obj.(metav1.Object)
+		return []metricsstore.FamilyByteSlicer{
+			&metric.Family{
+				Name: "acm_managed_cluster_addon_info",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"managed_cluster_name", "addon_name"},
+						LabelValues: []string{o.GetNamespace(), o.GetName()},
+						Value:       1,
+					},
+				},
+			},
+		}
+	}
+	newAddOn := func(name, namespace string) *addonv1alpha1.ManagedClusterAddOn {
+		return &addonv1alpha1.ManagedClusterAddOn{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				UID:       ktypes.UID("uid-" + namespace + "-" + name),
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		toAdd    []*addonv1alpha1.ManagedClusterAddOn
+		toDelete []*addonv1alpha1.ManagedClusterAddOn
+		filter   ClusterFilter
+		want     string
+	}{
+		{
+			name: "empty",
+			want: `# HELP acm_managed_cluster_addon_info Addon information
+# TYPE acm_managed_cluster_addon_info gauge
+`,
+		},
+		{
+			name:  "no filter",
+			toAdd: []*addonv1alpha1.ManagedClusterAddOn{newAddOn("work-manager", "cluster1")},
+			want: `# HELP acm_managed_cluster_addon_info Addon information
+# TYPE acm_managed_cluster_addon_info gauge
+acm_managed_cluster_addon_info{managed_cluster_name="cluster1",addon_name="work-manager"} 1
+`,
+		},
+		{
+			name: "filtered",
+			toAdd: []*addonv1alpha1.ManagedClusterAddOn{
+				newAddOn("work-manager", "cluster1"),
+				newAddOn("work-manager", "cluster2"),
+			},
+			filter: func(clusterName string) bool { return clusterName == "cluster2" },
+			want: `# HELP acm_managed_cluster_addon_info Addon information
+# TYPE acm_managed_cluster_addon_info gauge
+acm_managed_cluster_addon_info{managed_cluster_name="cluster2",addon_name="work-manager"} 1
+`,
+		},
+		{
+			name: "deleted",
+			toAdd: []*addonv1alpha1.ManagedClusterAddOn{
+				newAddOn("work-manager", "cluster1"),
+				newAddOn("work-manager", "cluster2"),
+			},
+			toDelete: []*addonv1alpha1.ManagedClusterAddOn{newAddOn("work-manager", "cluster2")},
+			filter:   func(clusterName string) bool { return clusterName == "cluster2" },
+			want: `# HELP acm_managed_cluster_addon_info Addon information
+# TYPE acm_managed_cluster_addon_info gauge
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newClusterMetricsStore(headers, generateFunc)
+			for _, obj := range tt.toAdd {
+				if err := store.Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, obj := range tt.toDelete {
+				if err := store.Delete(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			buf := new(bytes.Buffer)
+			store.WriteFiltered(buf, tt.filter)
+			if buf.String() != tt.want {
+				t.Errorf("want\n%s\nbut got\n%v", tt.want, buf.String())
+			}
+		})
+	}
+}
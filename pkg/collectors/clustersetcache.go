@@ -0,0 +1,113 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+)
+
+// clusterSetCache implements the k8s.io/client-go/tools/cache.Store
+// interface. Instead of storing entire ManagedCluster objects, it
+// stores the ManagedClusterSet each ManagedCluster belongs to.
+type clusterSetCache struct {
+	// Protects data
+	mutex sync.RWMutex
+
+	// data is a map indexed by cluster name with cluster set names
+	data map[string]string
+}
+
+// newClusterSetCache returns a new clusterSetCache
+func newClusterSetCache() *clusterSetCache {
+	return &clusterSetCache{
+		data: map[string]string{},
+	}
+}
+
+// GetClusterSet returns the name of the ManagedClusterSet the cluster belongs to.
+func (s *clusterSetCache) GetClusterSet(clusterName string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.data[clusterName]
+}
+
+// Add implements the Add method of the store interface.
+func (s *clusterSetCache) Add(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data[o.GetName()] = o.GetLabels()[clusterv1beta2.ClusterSetLabel]
+	return nil
+}
+
+// Update implements the Update method of the store interface.
+func (s *clusterSetCache) Update(obj interface{}) error {
+	return s.Add(obj)
+}
+
+// Delete implements the Delete method of the store interface.
+func (s *clusterSetCache) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data, o.GetName())
+	return nil
+}
+
+// List implements the List method of the store interface.
+func (s *clusterSetCache) List() []interface{} {
+	return nil
+}
+
+// ListKeys implements the ListKeys method of the store interface.
+func (s *clusterSetCache) ListKeys() []string {
+	return nil
+}
+
+// Get implements the Get method of the store interface.
+func (s *clusterSetCache) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// GetByKey implements the GetByKey method of the store interface.
+func (s *clusterSetCache) GetByKey(key string) (item interface{}, exists bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	clusterSet, ok := s.data[key]
+	return clusterSet, ok, nil
+}
+
+// Replace implements the Replace method of the store interface.
+func (s *clusterSetCache) Replace(list []interface{}, _ string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.data = map[string]string{}
+	for _, o := range list {
+		obj, err := meta.Accessor(o)
+		if err != nil {
+			return err
+		}
+		s.data[obj.GetName()] = obj.GetLabels()[clusterv1beta2.ClusterSetLabel]
+	}
+
+	return nil
+}
+
+// Resync implements the Resync method of the store interface.
+func (s *clusterSetCache) Resync() error {
+	return nil
+}
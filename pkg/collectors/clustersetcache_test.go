@@ -0,0 +1,52 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+)
+
+func newTestManagedClusterInSet(name, clusterSet string) *mcv1.ManagedCluster {
+	cluster := newTestManagedCluster(name)
+	if clusterSet != "" {
+		cluster.Labels = map[string]string{clusterv1beta2.ClusterSetLabel: clusterSet}
+	}
+	return cluster
+}
+
+func Test_ClusterSetCache(t *testing.T) {
+	cache := newClusterSetCache()
+	if err := cache.Replace([]interface{}{
+		newTestManagedClusterInSet("cluster1", "dev"),
+		newTestManagedClusterInSet("cluster2", ""),
+	}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Add(newTestManagedClusterInSet("cluster3", "prod")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Update(newTestManagedClusterInSet("cluster1", "prod")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Delete(&mcv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster3"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"cluster1": "prod",
+		"cluster2": "",
+		"cluster3": "",
+	}
+	for clusterName, clusterSet := range want {
+		if got := cache.GetClusterSet(clusterName); got != clusterSet {
+			t.Errorf("expected cluster set %q of cluster %q, but got %q", clusterSet, clusterName, got)
+		}
+	}
+	if _, exists, _ := cache.GetByKey("cluster3"); exists {
+		t.Errorf("expected cluster3 to be deleted")
+	}
+}
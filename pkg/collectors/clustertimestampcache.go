@@ -45,6 +45,8 @@ func newClusterTimestampCache() *clusterTimestampCache {
 }
 
 func (s *clusterTimestampCache) GetClusterTimestamps(clusterName string) map[string]float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	return s.data[clusterName]
 }
 
@@ -184,6 +186,8 @@ func (s *clusterTimestampCache) Get(obj interface{}) (item interface{}, exists b
 
 // GetByKey implements the GetByKey method of the store interface.
 func (s *clusterTimestampCache) GetByKey(key string) (item interface{}, exists bool, err error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	timestamps, ok := s.data[key]
 	return timestamps, ok, nil
 }
@@ -5,8 +5,13 @@ package collectors
 
 import (
 	"io"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
 )
 
 var (
@@ -15,7 +20,7 @@ var (
 			Name: "ksm_scrape_error_total",
 			Help: "Total scrape errors encountered when scraping a resource",
 		},
-		[]string{"resource"},
+		[]string{"hub", "resource"},
 	)
 
 	ResourcesPerScrapeMetric = prometheus.NewSummaryVec(
@@ -23,12 +28,170 @@ var (
 			Name: "ksm_resources_per_scrape",
 			Help: "Number of resources returned per scrape",
 		},
-		[]string{"resource"},
+		[]string{"hub", "resource"},
+	)
+
+	ResourceObjectsMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ksm_resource_objects",
+			Help: "Number of objects of a resource held by the stores",
+		},
+		[]string{"hub", "resource"},
+	)
+
+	StoreErrorTotalMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ksm_store_error_total",
+			Help: "Total errors encountered when updating the stores of a resource",
+		},
+		[]string{"hub", "resource", "operation"},
+	)
+
+	WatchRestartTotalMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ksm_watch_restart_total",
+			Help: "Total restarts of the watch on a resource",
+		},
+		[]string{"hub", "resource"},
+	)
+
+	LastEventTimestampMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ksm_last_event_timestamp_seconds",
+			Help: "Unix timestamp of the last event of a resource received by the stores",
+		},
+		[]string{"hub", "resource"},
+	)
+
+	RenderDurationMetric = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ksm_render_duration_seconds",
+			Help:    "Time spent rendering the metrics of a resource per scrape",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		},
+		[]string{"hub", "resource"},
+	)
+
+	ResponseSizeMetric = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ksm_response_size_bytes",
+			Help:    "Uncompressed size of the metrics of a resource per scrape",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 10),
+		},
+		[]string{"hub", "resource"},
+	)
+
+	FamilySeriesMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ksm_family_series",
+			Help: "Number of series of a metric family held by the stores of a collector",
+		},
+		[]string{"hub", "collector", "family"},
+	)
+
+	DroppedSeriesTotalMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ksm_dropped_series_total",
+			Help: "Total series of a metric family dropped since the series limit of the family or collector is reached, counted once per object while they are dropped",
+		},
+		[]string{"hub", "collector", "family"},
 	)
 )
 
+// TelemetryMetrics returns the self metrics of the collectors, which are
+// expected to be registered on the telemetry registry.
+func TelemetryMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		ScrapeErrorTotalMetric,
+		ResourcesPerScrapeMetric,
+		ResourceObjectsMetric,
+		StoreErrorTotalMetric,
+		WatchRestartTotalMetric,
+		LastEventTimestampMetric,
+		RenderDurationMetric,
+		ResponseSizeMetric,
+		FamilySeriesMetric,
+		DroppedSeriesTotalMetric,
+		HubRenderDurationMetric,
+		ResourceAvailableMetric,
+		cluster.UnmappedValuesTotalMetric,
+	}
+}
+
 type MetricsCollector interface {
 	WriteAll(w io.Writer)
+	// WriteFiltered writes the metrics of the managed clusters selected by the filter.
+	WriteFiltered(w io.Writer, filter ClusterFilter)
+	// CollectFamilies calls collect with each metric family of the managed
+	// clusters selected by the filter, in the order the families are written.
+	// A nil filter selects all managed clusters.
+	CollectFamilies(filter ClusterFilter, collect func(family *Family))
+}
+
+// Family is a metric family of a collector with the metrics generated by its
+// stores, so it can be encoded without rendering the text format.
+type Family struct {
+	Name string
+	Help string
+	Type metric.Type
+	// Metrics are the metrics generated from each object. They are shared with
+	// the stores and must not be modified.
+	Metrics [][]*metric.Metric
+}
+
+// familyHeader is the name, help and type of a metric family.
+type familyHeader struct {
+	name       string
+	help       string
+	metricType metric.Type
+}
+
+// parseFamilyHeaders returns the name, help and type of each metric family
+// from its header (HELP and TYPE) in the text format.
+func parseFamilyHeaders(headers []string) []familyHeader {
+	familyHeaders := make([]familyHeader, len(headers))
+	for i, header := range headers {
+		for _, line := range strings.Split(header, "\n") {
+			switch {
+			case strings.HasPrefix(line, "# HELP "):
+				familyHeaders[i].name, familyHeaders[i].help, _ = strings.Cut(line[len("# HELP "):], " ")
+			case strings.HasPrefix(line, "# TYPE "):
+				_, metricType, _ := strings.Cut(line[len("# TYPE "):], " ")
+				familyHeaders[i].metricType = metric.Type(metricType)
+			}
+		}
+	}
+	return familyHeaders
+}
+
+// newFamily returns the family of the given header with the metrics generated
+// from each object.
+func newFamily(header familyHeader, metrics [][]*metric.Metric) *Family {
+	return &Family{
+		Name:    header.name,
+		Help:    header.help,
+		Type:    header.metricType,
+		Metrics: metrics,
+	}
+}
+
+// metricFamilies returns the generated families as kube-state-metrics
+// families. The families of other types are nil.
+func metricFamilies(families []metricsstore.FamilyByteSlicer) []*metric.Family {
+	metricFamilies := make([]*metric.Family, len(families))
+	for i, f := range families {
+		metricFamilies[i], _ = f.(*metric.Family)
+	}
+	return metricFamilies
+}
+
+// familyMetrics returns the metrics of a generated family, or nil if the
+// family is not generated.
+func familyMetrics(families []*metric.Family, i int) []*metric.Metric {
+	if i >= len(families) || families[i] == nil {
+		return nil
+	}
+	return families[i].Metrics
 }
 
 // composedMetricsCollector is a collector that composes multiple
@@ -48,3 +211,15 @@ func (c *composedMetricsCollector) WriteAll(w io.Writer) {
 		collector.WriteAll(w)
 	}
 }
+
+func (c *composedMetricsCollector) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	for _, collector := range c.collectors {
+		collector.WriteFiltered(w, filter)
+	}
+}
+
+func (c *composedMetricsCollector) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {
+	for _, collector := range c.collectors {
+		collector.CollectFamilies(filter, collect)
+	}
+}
@@ -4,6 +4,8 @@
 package collectors
 
 import (
+	"sync"
+
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/cache"
 )
@@ -11,6 +13,8 @@ import (
 // composedStore implements the k8s.io/client-go/tools/cache.Store
 // interface. It composes multiple Store into a single one.
 type composedStore struct {
+	// Protects stores, which can be added while the reflector writes into the composed store
+	mutex  sync.RWMutex
 	stores []cache.Store
 }
 
@@ -22,15 +26,21 @@ func newComposedStore(stores ...cache.Store) *composedStore {
 }
 
 func (s *composedStore) AddStore(store cache.Store) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	s.stores = append(s.stores, store)
 }
 
 func (s *composedStore) Size() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	return len(s.stores)
 }
 
 // Add implements the Add method of the store interface.
 func (s *composedStore) Add(obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	errs := []error{}
 	for _, store := range s.stores {
 		if err := store.Add(obj); err != nil {
@@ -43,6 +53,8 @@ func (s *composedStore) Add(obj interface{}) error {
 
 // Update implements the Update method of the store interface.
 func (s *composedStore) Update(obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	errs := []error{}
 	for _, store := range s.stores {
 		if err := store.Update(obj); err != nil {
@@ -55,6 +67,8 @@ func (s *composedStore) Update(obj interface{}) error {
 
 // Delete implements the Delete method of the store interface.
 func (s *composedStore) Delete(obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	errs := []error{}
 	for _, store := range s.stores {
 		if err := store.Delete(obj); err != nil {
@@ -88,6 +102,8 @@ func (s *composedStore) GetByKey(key string) (item interface{}, exists bool, err
 // Replace implements the Replace method of the store interface.
 // When the app restarts, the Replace func will be invoked by listwatcher
 func (s *composedStore) Replace(list []interface{}, str string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	errs := []error{}
 	for _, store := range s.stores {
 		if err := store.Replace(list, str); err != nil {
@@ -100,6 +116,8 @@ func (s *composedStore) Replace(list []interface{}, str string) error {
 
 // Resync implements the Resync method of the store interface.
 func (s *composedStore) Resync() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
 	errs := []error{}
 	for _, store := range s.stores {
 		if err := store.Resync(); err != nil {
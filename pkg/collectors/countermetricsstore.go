@@ -10,12 +10,15 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
 	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
 )
 
 // CounterMetricsStore implements the k8s.io/client-go/tools/cache.Store
 // interface. Instead of storing entire Kubernetes objects, it stores UID of
 // those objects, which can be used to caculate the number of the stored objects.
+// It also keeps the managed cluster each object belongs to, so the objects of
+// some of the managed clusters can be counted.
 type CounterMetricsStore struct {
 	// Protects metrics
 	mutex sync.RWMutex
@@ -23,11 +26,18 @@ type CounterMetricsStore struct {
 	// metricFamilies is a slice of metric families, containing a slice of metrics.
 	metricFamilies [][]byte
 
-	// data is a map indexed by Kubernetes object id with empty structs
-	data map[types.UID]struct{}
+	// families are the generated metric families, which the formats other than
+	// the text formats are encoded from.
+	families []*metric.Family
+
+	// data is a map indexed by Kubernetes object id with the name of the managed
+	// cluster the object belongs to
+	data map[types.UID]string
 
 	// headers contains the header (TYPE and HELP) of each metric family.
 	headers []string
+	// familyHeaders contains the name, help and type of each metric family.
+	familyHeaders []familyHeader
 
 	// generateMetricsFunc generates metrics based on a given int (the number of the
 	// stored objects) and returns them grouped by metric family.
@@ -39,7 +49,8 @@ func newCounterMetricsStore(headers []string, generateFunc func(interface{}) []m
 	return &CounterMetricsStore{
 		generateMetricsFunc: generateFunc,
 		headers:             headers,
-		data:                map[types.UID]struct{}{},
+		familyHeaders:       parseFamilyHeaders(headers),
+		data:                map[types.UID]string{},
 	}
 }
 
@@ -54,21 +65,30 @@ func (s *CounterMetricsStore) Add(obj interface{}) error {
 	defer s.mutex.Unlock()
 
 	uid := o.GetUID()
-	if _, ok := s.data[uid]; ok {
+	clusterName := objectClusterName(o)
+	if name, ok := s.data[uid]; ok && name == clusterName {
 		return nil
 	}
 
-	s.data[uid] = struct{}{}
-	families := s.generateMetricsFunc(len(s.data))
-	s.metricFamilies = make([][]byte, len(families))
-
-	for i, f := range families {
-		s.metricFamilies[i] = f.ByteSlice()
-	}
+	s.data[uid] = clusterName
+	s.generate()
 
 	return nil
 }
 
+// generate generates the metric families of the number of the stored objects.
+// It must be called with the mutex held.
+func (s *CounterMetricsStore) generate() {
+	s.families = metricFamilies(s.generateMetricsFunc(len(s.data)))
+	s.metricFamilies = make([][]byte, len(s.families))
+
+	for i, f := range s.families {
+		if f != nil {
+			s.metricFamilies[i] = f.ByteSlice()
+		}
+	}
+}
+
 // Update implements the Update method of the store interface.
 func (s *CounterMetricsStore) Update(obj interface{}) error {
 	// TODO: For now, just call Add, in the future one could check if the resource version changed?
@@ -92,12 +112,7 @@ func (s *CounterMetricsStore) Delete(obj interface{}) error {
 	}
 
 	delete(s.data, uid)
-	families := s.generateMetricsFunc(len(s.data))
-	s.metricFamilies = make([][]byte, len(families))
-
-	for i, f := range families {
-		s.metricFamilies[i] = f.ByteSlice()
-	}
+	s.generate()
 
 	return nil
 }
@@ -125,7 +140,7 @@ func (s *CounterMetricsStore) GetByKey(key string) (item interface{}, exists boo
 // Add implements the Add method of the store interface.
 func (s *CounterMetricsStore) Replace(list []interface{}, _ string) error {
 	s.mutex.Lock()
-	s.data = map[types.UID]struct{}{}
+	s.data = map[types.UID]string{}
 	s.mutex.Unlock()
 
 	for _, o := range list {
@@ -160,6 +175,61 @@ func (s *CounterMetricsStore) WriteAll(w io.Writer) {
 	}
 }
 
+// WriteFiltered writes the metrics generated based on the number of the objects
+// belonging to the managed clusters selected by the filter into the given writer.
+// A nil filter selects all managed clusters.
+func (s *CounterMetricsStore) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	if filter == nil {
+		s.WriteAll(w)
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	count := 0
+	for _, clusterName := range s.data {
+		if filter(clusterName) {
+			count++
+		}
+	}
+	families := s.generateMetricsFunc(count)
+	for i, help := range s.headers {
+		write(w, []byte(help))
+		write(w, []byte{'\n'})
+		if len(families) > i {
+			write(w, families[i].ByteSlice())
+		}
+	}
+}
+
+// CollectFamilies calls collect with each metric family generated based on the
+// number of the objects belonging to the managed clusters selected by the
+// filter. A nil filter selects all managed clusters.
+func (s *CounterMetricsStore) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	families := s.families
+	if filter != nil {
+		count := 0
+		for _, clusterName := range s.data {
+			if filter(clusterName) {
+				count++
+			}
+		}
+		families = metricFamilies(s.generateMetricsFunc(count))
+	}
+
+	for i, header := range s.familyHeaders {
+		metrics := [][]*metric.Metric{}
+		if m := familyMetrics(families, i); len(m) > 0 {
+			metrics = append(metrics, m)
+		}
+		collect(newFamily(header, metrics))
+	}
+}
+
 func write(w io.Writer, data []byte) {
 	if _, err := w.Write(data); err != nil {
 		klog.Errorf("cannot write data: %v", string(data))
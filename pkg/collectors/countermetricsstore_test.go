@@ -12,6 +12,7 @@ import (
 	"k8s.io/kube-state-metrics/pkg/metric"
 	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
 	mcv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
 )
 
 func Test_CounterMetricsStore_WriteAll(t *testing.T) {
@@ -102,3 +103,74 @@ func toObject(uid string) runtime.Object {
 	cluster.UID = types.UID(uid)
 	return cluster
 }
+
+func Test_CounterMetricsStore_WriteFiltered(t *testing.T) {
+	headers := []string{`# HELP acm_manifestwork_count ManifestWork count
+# TYPE acm_manifestwork_count gauge`}
+
+	generateFunc := func(obj interface{}) []metricsstore.FamilyByteSlicer {
+		return []metricsstore.FamilyByteSlicer{
+			&metric.Family{
+				Name:    "acm_manifestwork_count",
+				Metrics: []*metric.Metric{{Value: float64(obj.(int))}},
+			},
+		}
+	}
+
+	store := newCounterMetricsStore(headers, generateFunc)
+	for _, obj := range []runtime.Object{
+		toNamespacedObject("a", "cluster1"),
+		toNamespacedObject("b", "cluster1"),
+		toNamespacedObject("c", "cluster2"),
+	} {
+		if err := store.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter ClusterFilter
+		want   string
+	}{
+		{
+			name: "no filter",
+			want: `# HELP acm_manifestwork_count ManifestWork count
+# TYPE acm_manifestwork_count gauge
+acm_manifestwork_count 3
+`,
+		},
+		{
+			name:   "cluster1",
+			filter: func(clusterName string) bool { return clusterName == "cluster1" },
+			want: `# HELP acm_manifestwork_count ManifestWork count
+# TYPE acm_manifestwork_count gauge
+acm_manifestwork_count 2
+`,
+		},
+		{
+			name:   "none",
+			filter: func(clusterName string) bool { return false },
+			want: `# HELP acm_manifestwork_count ManifestWork count
+# TYPE acm_manifestwork_count gauge
+acm_manifestwork_count 0
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			store.WriteFiltered(buf, tt.filter)
+			if buf.String() != tt.want {
+				t.Errorf("want\n%s\nbut got\n%v", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func toNamespacedObject(uid, namespace string) runtime.Object {
+	work := &workv1.ManifestWork{}
+	work.UID = types.UID(uid)
+	work.Namespace = namespace
+	return work
+}
@@ -0,0 +1,357 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+// TextContentType is the content type of the Prometheus text format the stores render.
+const TextContentType = `text/plain; version=0.0.4`
+
+// JSONContentType is the content type of the metrics encoded in JSON, one
+// family per line.
+const JSONContentType = `application/json`
+
+// openMetricsUnits are the units declared in the UNIT metadata of a family
+// whose name ends with one of them.
+var openMetricsUnits = []string{
+	"seconds",
+	"bytes",
+	"ratio",
+	"celsius",
+	"meters",
+	"grams",
+	"volts",
+	"amperes",
+	"joules",
+}
+
+// NegotiateFormat returns the exposition format to respond with according to
+// the Accept header of the request. The Prometheus text, OpenMetrics and
+// delimited protobuf formats are supported, it falls back to the text format.
+func NegotiateFormat(h http.Header) expfmt.Format {
+	format := expfmt.NegotiateIncludingOpenMetrics(h)
+	switch format.FormatType() {
+	case expfmt.TypeProtoDelim:
+		return expfmt.NewFormat(expfmt.TypeProtoDelim)
+	case expfmt.TypeOpenMetrics:
+		return openMetricsFormat(format)
+	default:
+		return TextContentType
+	}
+}
+
+// openMetricsFormat returns the negotiated OpenMetrics format without the escaping
+// scheme. Escaping schemes other than the legacy one are not supported, the label
+// names of the collectors are always valid legacy names.
+func openMetricsFormat(negotiated expfmt.Format) expfmt.Format {
+	version := expfmt.OpenMetricsVersion_1_0_0
+	if strings.Contains(string(negotiated), "version="+expfmt.OpenMetricsVersion_0_0_1) {
+		version = expfmt.OpenMetricsVersion_0_0_1
+	}
+	format, _ := expfmt.NewOpenMetricsFormat(version)
+	return format
+}
+
+// ParseFormat returns the exposition format of the given name, which is one of
+// text, openmetrics and json.
+func ParseFormat(name string) (expfmt.Format, error) {
+	switch name {
+	case "text":
+		return TextContentType, nil
+	case "openmetrics":
+		return openMetricsFormat(expfmt.NewFormat(expfmt.TypeOpenMetrics)), nil
+	case "json":
+		return JSONContentType, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected text, openmetrics or json", name)
+	}
+}
+
+// WriteMetrics writes the metrics of the collectors of the managed clusters
+// selected by the filter into w in the given format. A nil filter selects all
+// managed clusters. The text and OpenMetrics formats are written from the
+// metrics rendered by the stores, the delimited protobuf and JSON formats are
+// encoded from the metric families generated by the stores.
+func WriteMetrics(w io.Writer, format expfmt.Format, metricsCollectors []MetricsCollector, filter ClusterFilter) error {
+	if encode := newFamilyEncoder(w, format); encode != nil {
+		errs := []error{}
+		for _, c := range metricsCollectors {
+			c.CollectFamilies(filter, func(family *Family) {
+				if len(family.Metrics) == 0 {
+					return
+				}
+				if err := encode(newMetricFamily(family)); err != nil {
+					errs = append(errs, err)
+				}
+			})
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+
+	formatWriter := newFormatWriter(w, format)
+	for _, c := range metricsCollectors {
+		if filter != nil {
+			c.WriteFiltered(formatWriter, filter)
+		} else {
+			c.WriteAll(formatWriter)
+		}
+	}
+	return formatWriter.Close()
+}
+
+// newFamilyEncoder returns a function encoding a metric family into w if the
+// format is encoded one family at a time, such as the delimited protobuf
+// format, otherwise nil.
+func newFamilyEncoder(w io.Writer, format expfmt.Format) func(*dto.MetricFamily) error {
+	if format == JSONContentType {
+		encoder := json.NewEncoder(w)
+		return func(family *dto.MetricFamily) error {
+			return encoder.Encode(newJSONFamily(family))
+		}
+	}
+	if format.FormatType() == expfmt.TypeProtoDelim {
+		return expfmt.NewEncoder(w, format).Encode
+	}
+	return nil
+}
+
+// newMetricFamily returns the metric family in the Prometheus data model.
+func newMetricFamily(family *Family) *dto.MetricFamily {
+	metricType := dto.MetricType_UNTYPED
+	switch family.Type {
+	case metric.Gauge:
+		metricType = dto.MetricType_GAUGE
+	case metric.Counter:
+		metricType = dto.MetricType_COUNTER
+	}
+
+	mf := &dto.MetricFamily{
+		Name: proto.String(family.Name),
+		Type: metricType.Enum(),
+	}
+	if family.Help != "" {
+		mf.Help = proto.String(family.Help)
+	}
+	for _, metrics := range family.Metrics {
+		for _, m := range metrics {
+			dm := &dto.Metric{Label: make([]*dto.LabelPair, len(m.LabelKeys))}
+			for i, key := range m.LabelKeys {
+				dm.Label[i] = &dto.LabelPair{Name: proto.String(key), Value: proto.String(m.LabelValues[i])}
+			}
+			switch metricType {
+			case dto.MetricType_GAUGE:
+				dm.Gauge = &dto.Gauge{Value: proto.Float64(m.Value)}
+			case dto.MetricType_COUNTER:
+				dm.Counter = &dto.Counter{Value: proto.Float64(m.Value)}
+			default:
+				dm.Untyped = &dto.Untyped{Value: proto.Float64(m.Value)}
+			}
+			mf.Metric = append(mf.Metric, dm)
+		}
+	}
+	return mf
+}
+
+// formatWriter converts the metrics written by the collectors in the
+// Prometheus text format to a text exposition format. Close must be called
+// once all collectors are written.
+type formatWriter interface {
+	io.Writer
+	Close() error
+}
+
+// newFormatWriter returns a formatWriter writing the given text format into w.
+func newFormatWriter(w io.Writer, format expfmt.Format) formatWriter {
+	if format.FormatType() == expfmt.TypeOpenMetrics {
+		return &openMetricsWriter{writer: w}
+	}
+	return &textWriter{writer: w}
+}
+
+// textWriter writes the metrics as they are.
+type textWriter struct {
+	writer io.Writer
+}
+
+func (w *textWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+func (w *textWriter) Close() error {
+	return nil
+}
+
+// openMetricsWriter converts the metrics in the Prometheus text format to the
+// OpenMetrics 1.0 format. The samples of the two formats are compatible, so only
+// the metadata of each family is rewritten:
+//   - a gauge family named with the "_info" suffix is an info family named without it;
+//   - a counter family named with the "_total" suffix is named without it;
+//   - a family named with a unit suffix gets the UNIT metadata;
+//   - the exposition is terminated with "# EOF".
+type openMetricsWriter struct {
+	writer io.Writer
+
+	// line holds the incomplete line of the last write
+	line []byte
+	// help holds the HELP line of the family until its TYPE line is written
+	help []byte
+	err  error
+}
+
+func (w *openMetricsWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line = append(w.line, p...)
+			break
+		}
+
+		if len(w.line) > 0 {
+			w.line = append(w.line, p[:i]...)
+			w.writeLine(w.line)
+			w.line = w.line[:0]
+		} else {
+			w.writeLine(p[:i])
+		}
+		p = p[i+1:]
+	}
+
+	if w.err != nil {
+		return 0, w.err
+	}
+	return n, nil
+}
+
+func (w *openMetricsWriter) Close() error {
+	if len(w.line) > 0 {
+		w.writeLine(w.line)
+		w.line = nil
+	}
+	w.flushHelp()
+	w.write([]byte("# EOF\n"))
+	return w.err
+}
+
+func (w *openMetricsWriter) writeLine(line []byte) {
+	switch {
+	case len(line) == 0:
+		// empty lines are not allowed
+	case bytes.HasPrefix(line, []byte("# HELP ")):
+		w.flushHelp()
+		w.help = append(w.help[:0], line...)
+	case bytes.HasPrefix(line, []byte("# TYPE ")):
+		w.writeMetadata(string(line[len("# TYPE "):]))
+	default:
+		w.flushHelp()
+		w.write(line)
+		w.write([]byte{'\n'})
+	}
+}
+
+// writeMetadata writes the HELP, TYPE and UNIT lines of a family.
+func (w *openMetricsWriter) writeMetadata(nameAndType string) {
+	name, metricType, _ := strings.Cut(nameAndType, " ")
+	familyName := name
+	switch {
+	case metricType == "gauge" && strings.HasSuffix(name, "_info"):
+		familyName, metricType = strings.TrimSuffix(name, "_info"), "info"
+	case metricType == "counter":
+		familyName = strings.TrimSuffix(name, "_total")
+	case metricType == "untyped":
+		metricType = "unknown"
+	}
+
+	if w.help != nil {
+		_, help, _ := strings.Cut(string(w.help[len("# HELP "):]), " ")
+		w.help = nil
+		w.write([]byte("# HELP " + familyName + " " + strings.ReplaceAll(help, `"`, `\"`) + "\n"))
+	}
+	w.write([]byte("# TYPE " + familyName + " " + metricType + "\n"))
+
+	if metricType == "info" {
+		return
+	}
+	for _, unit := range openMetricsUnits {
+		if strings.HasSuffix(familyName, "_"+unit) {
+			w.write([]byte("# UNIT " + familyName + " " + unit + "\n"))
+			break
+		}
+	}
+}
+
+// flushHelp writes a HELP line which is not followed by a TYPE line.
+func (w *openMetricsWriter) flushHelp() {
+	if w.help == nil {
+		return
+	}
+	w.write(w.help)
+	w.write([]byte{'\n'})
+	w.help = nil
+}
+
+func (w *openMetricsWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.writer.Write(p)
+}
+
+// jsonFamily is a metric family encoded in JSON.
+type jsonFamily struct {
+	Name    string       `json:"name"`
+	Help    string       `json:"help,omitempty"`
+	Type    string       `json:"type"`
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+// jsonMetric is a sample of a metric family encoded in JSON.
+type jsonMetric struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+func newJSONFamily(family *dto.MetricFamily) jsonFamily {
+	f := jsonFamily{
+		Name:    family.GetName(),
+		Help:    family.GetHelp(),
+		Type:    strings.ToLower(family.GetType().String()),
+		Metrics: []jsonMetric{},
+	}
+	for _, m := range family.GetMetric() {
+		metric := jsonMetric{}
+		for _, label := range m.GetLabel() {
+			if metric.Labels == nil {
+				metric.Labels = map[string]string{}
+			}
+			metric.Labels[label.GetName()] = label.GetValue()
+		}
+		switch {
+		case m.Gauge != nil:
+			metric.Value = m.GetGauge().GetValue()
+		case m.Counter != nil:
+			metric.Value = m.GetCounter().GetValue()
+		default:
+			metric.Value = m.GetUntyped().GetValue()
+		}
+		f.Metrics = append(f.Metrics, metric)
+	}
+	return f
+}
@@ -0,0 +1,288 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+func Test_NegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   expfmt.Format
+	}{
+		{
+			name: "no accept header",
+			want: TextContentType,
+		},
+		{
+			name:   "text",
+			accept: "text/plain;version=0.0.4;q=0.5,*/*;q=0.1",
+			want:   TextContentType,
+		},
+		{
+			name:   "openmetrics 1.0.0",
+			accept: "application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1",
+			want:   "application/openmetrics-text; version=1.0.0; charset=utf-8",
+		},
+		{
+			name:   "protobuf",
+			accept: "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3",
+			want:   "application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited",
+		},
+		{
+			name:   "openmetrics 0.0.1",
+			accept: "application/openmetrics-text;version=0.0.1",
+			want:   "application/openmetrics-text; version=0.0.1; charset=utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.accept != "" {
+				h.Set("Accept", tt.accept)
+			}
+			if got := NegotiateFormat(h); got != tt.want {
+				t.Errorf("NegotiateFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_FormatWriter(t *testing.T) {
+	input := []string{
+		"# HELP acm_managed_cluster_info Managed cluster information",
+		"\n",
+		"# TYPE acm_managed_cluster_info gauge",
+		"\n",
+		`acm_managed_cluster_info{hub_cluster_id="hub",managed_cluster_id="c1"} 1` + "\n" +
+			`acm_managed_cluster_info{hub_cluster_id="hub",managed_cluster_id="c2"} 1` + "\n",
+		"# HELP acm_managed_cluster_count Managed cluster count\n# TYPE acm_managed_cluster_count gauge\n",
+		"acm_managed_cluster_count 2\n",
+		"# HELP acm_managed_cluster_unmapped_values_total Unmapped \"values\"\n# TYPE acm_managed_cluster_unmapped_values_total counter\n",
+		`acm_managed_cluster_unmapped_values_total{label="created_via"} 3` + "\n",
+		"# HELP ksm_render_duration_seconds Render time\n# TYPE ksm_render_duration_seconds gauge\n",
+		// split a sample across writes
+		"ksm_render_duration_",
+		"seconds 0.5\n",
+	}
+
+	tests := []struct {
+		name   string
+		format expfmt.Format
+		want   string
+	}{
+		{
+			name:   "text",
+			format: TextContentType,
+			want: `# HELP acm_managed_cluster_info Managed cluster information
+# TYPE acm_managed_cluster_info gauge
+acm_managed_cluster_info{hub_cluster_id="hub",managed_cluster_id="c1"} 1
+acm_managed_cluster_info{hub_cluster_id="hub",managed_cluster_id="c2"} 1
+# HELP acm_managed_cluster_count Managed cluster count
+# TYPE acm_managed_cluster_count gauge
+acm_managed_cluster_count 2
+# HELP acm_managed_cluster_unmapped_values_total Unmapped "values"
+# TYPE acm_managed_cluster_unmapped_values_total counter
+acm_managed_cluster_unmapped_values_total{label="created_via"} 3
+# HELP ksm_render_duration_seconds Render time
+# TYPE ksm_render_duration_seconds gauge
+ksm_render_duration_seconds 0.5
+`,
+		},
+		{
+			name:   "openmetrics",
+			format: "application/openmetrics-text; version=1.0.0; charset=utf-8",
+			want: `# HELP acm_managed_cluster Managed cluster information
+# TYPE acm_managed_cluster info
+acm_managed_cluster_info{hub_cluster_id="hub",managed_cluster_id="c1"} 1
+acm_managed_cluster_info{hub_cluster_id="hub",managed_cluster_id="c2"} 1
+# HELP acm_managed_cluster_count Managed cluster count
+# TYPE acm_managed_cluster_count gauge
+acm_managed_cluster_count 2
+# HELP acm_managed_cluster_unmapped_values Unmapped \"values\"
+# TYPE acm_managed_cluster_unmapped_values counter
+acm_managed_cluster_unmapped_values_total{label="created_via"} 3
+# HELP ksm_render_duration_seconds Render time
+# TYPE ksm_render_duration_seconds gauge
+# UNIT ksm_render_duration_seconds seconds
+ksm_render_duration_seconds 0.5
+# EOF
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w := newFormatWriter(buf, tt.format)
+			for _, data := range input {
+				if _, err := w.Write([]byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("expected\n%s\ngot\n%s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func Test_FormatWriter_CounterMetricsStore(t *testing.T) {
+	store := newCounterMetricsStore(
+		[]string{"# HELP acm_managed_cluster_count Managed cluster count\n# TYPE acm_managed_cluster_count gauge"}, nil)
+
+	buf := new(bytes.Buffer)
+	w := newFormatWriter(buf, "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	store.WriteAll(w)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP acm_managed_cluster_count Managed cluster count
+# TYPE acm_managed_cluster_count gauge
+# EOF
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
+
+// newTestExpositionCollector returns a collector of the info and the labels
+// of managed clusters, with the count of the managed clusters, holding the
+// given managed clusters.
+func newTestExpositionCollector(t *testing.T, clusterNames ...string) MetricsCollector {
+	generators := []metric.FamilyGenerator{
+		{
+			Name: "acm_managed_cluster_info",
+			Help: "Managed cluster information",
+			Type: metric.Gauge,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{
+					LabelKeys:   []string{"hub_cluster_id", "managed_cluster_id"},
+					LabelValues: []string{"hub", obj.(metav1.Object).GetName()},
+					Value:       1,
+				}}}
+			},
+		},
+		{
+			// a family without metrics is omitted
+			Name: "acm_managed_cluster_labels",
+			Help: "Managed cluster labels",
+			Type: metric.Gauge,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{}
+			},
+		},
+	}
+	countGenerators := []metric.FamilyGenerator{
+		{
+			Name: "acm_managed_cluster_count",
+			Help: "Managed cluster count",
+			Type: metric.Gauge,
+			GenerateFunc: func(obj interface{}) *metric.Family {
+				return &metric.Family{Metrics: []*metric.Metric{{Value: float64(obj.(int))}}}
+			},
+		},
+	}
+
+	store := newClusterMetricsStore(metric.ExtractMetricFamilyHeaders(generators), metric.ComposeMetricGenFuncs(generators))
+	counterStore := newCounterMetricsStore(metric.ExtractMetricFamilyHeaders(countGenerators), metric.ComposeMetricGenFuncs(countGenerators))
+	for _, name := range clusterNames {
+		cluster := newTestManagedCluster(name)
+		if err := store.Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+		if err := counterStore.Add(cluster); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return newComposedMetricsCollector(store, counterStore)
+}
+
+func Test_WriteMetrics_Protobuf(t *testing.T) {
+	format := expfmt.NewFormat(expfmt.TypeProtoDelim)
+	collector := newTestExpositionCollector(t, "c1", "c2")
+
+	buf := new(bytes.Buffer)
+	if err := WriteMetrics(buf, format, []MetricsCollector{collector}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := expfmt.NewDecoder(buf, format)
+	families := []*dto.MetricFamily{}
+	for {
+		family := &dto.MetricFamily{}
+		if err := decoder.Decode(family); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatal(err)
+		}
+		families = append(families, family)
+	}
+
+	if len(families) != 2 {
+		t.Fatalf("expected 2 families, but got %d", len(families))
+	}
+	if families[0].GetName() != "acm_managed_cluster_info" || families[0].GetType() != dto.MetricType_GAUGE ||
+		families[0].GetHelp() != "Managed cluster information" || len(families[0].GetMetric()) != 2 {
+		t.Errorf("unexpected family %v", families[0])
+	}
+	if families[1].GetName() != "acm_managed_cluster_count" || families[1].GetMetric()[0].GetGauge().GetValue() != 2 {
+		t.Errorf("unexpected family %v", families[1])
+	}
+}
+
+func Test_ParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    expfmt.Format
+		wantErr bool
+	}{
+		{name: "text", want: TextContentType},
+		{name: "openmetrics", want: "application/openmetrics-text; version=1.0.0; charset=utf-8"},
+		{name: "json", want: JSONContentType},
+		{name: "protobuf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormat(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WriteMetrics_JSON(t *testing.T) {
+	collector := newTestExpositionCollector(t, "c1", "c2")
+
+	buf := new(bytes.Buffer)
+	filter := func(clusterName string) bool { return clusterName == "c1" }
+	if err := WriteMetrics(buf, JSONContentType, []MetricsCollector{collector}, filter); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name":"acm_managed_cluster_info","help":"Managed cluster information","type":"gauge","metrics":[{"labels":{"hub_cluster_id":"hub","managed_cluster_id":"c1"},"value":1}]}
+{"name":"acm_managed_cluster_count","help":"Managed cluster count","type":"gauge","metrics":[{"value":1}]}
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+}
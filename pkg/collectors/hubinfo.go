@@ -0,0 +1,214 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	ocpclient "github.com/openshift/client-go/config/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
+)
+
+// The sources of the ID of the hub cluster
+const (
+	// HubClusterIDSourceClusterVersion is the cluster ID of the ClusterVersion of an OpenShift hub
+	HubClusterIDSourceClusterVersion = "clusterversion"
+	// HubClusterIDSourceKubeSystem is the UID of the kube-system namespace of a hub without ClusterVersion
+	HubClusterIDSourceKubeSystem = "kube-system"
+	// HubClusterIDSourceConfigured is the ID set with WithHubClusterID
+	HubClusterIDSourceConfigured = "configured"
+)
+
+// hubInfoStore keeps the info of the hub and the metrics generated from it.
+type hubInfoStore struct {
+	// Protects info, metricFamilies and families
+	mutex          sync.RWMutex
+	info           cluster.HubInfo
+	metricFamilies [][]byte
+	families       []*metric.Family
+
+	// headers contains the header (TYPE and HELP) of each metric family.
+	headers []string
+	// familyHeaders contains the name, help and type of each metric family.
+	familyHeaders []familyHeader
+
+	// generateMetricsFunc generates metrics based on the info of the hub and
+	// returns them grouped by metric family.
+	generateMetricsFunc func(interface{}) []metricsstore.FamilyByteSlicer
+}
+
+// newHubInfoStore returns a new hubInfoStore generating the metrics of the
+// given info.
+func newHubInfoStore(headers []string, generateFunc func(interface{}) []metricsstore.FamilyByteSlicer,
+	info cluster.HubInfo) *hubInfoStore {
+	s := &hubInfoStore{
+		headers:             headers,
+		familyHeaders:       parseFamilyHeaders(headers),
+		generateMetricsFunc: generateFunc,
+	}
+	s.Update(func(i *cluster.HubInfo) {
+		*i = info
+	})
+	return s
+}
+
+// Update updates the info of the hub with the given function and regenerates
+// its metrics.
+func (s *hubInfoStore) Update(update func(info *cluster.HubInfo)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	update(&s.info)
+	info := s.info
+	s.families = metricFamilies(s.generateMetricsFunc(&info))
+	s.metricFamilies = make([][]byte, len(s.families))
+	for i, f := range s.families {
+		if f != nil {
+			s.metricFamilies[i] = f.ByteSlice()
+		}
+	}
+}
+
+// Info returns the info of the hub.
+func (s *hubInfoStore) Info() cluster.HubInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.info
+}
+
+// WriteAll writes the metrics of the hub into the given writer, zipped with
+// the help text of each metric family.
+func (s *hubInfoStore) WriteAll(w io.Writer) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i, help := range s.headers {
+		write(w, []byte(help))
+		write(w, []byte{'\n'})
+		if len(s.metricFamilies) > i {
+			write(w, s.metricFamilies[i])
+		}
+	}
+}
+
+// WriteFiltered writes the metrics of the hub, which do not belong to any
+// managed cluster.
+func (s *hubInfoStore) WriteFiltered(w io.Writer, _ ClusterFilter) {
+	s.WriteAll(w)
+}
+
+// CollectFamilies calls collect with each metric family of the hub, which
+// does not belong to any managed cluster.
+func (s *hubInfoStore) CollectFamilies(_ ClusterFilter, collect func(family *Family)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for i, header := range s.familyHeaders {
+		metrics := [][]*metric.Metric{}
+		if m := familyMetrics(s.families, i); len(m) > 0 {
+			metrics = append(metrics, m)
+		}
+		collect(newFamily(header, metrics))
+	}
+}
+
+// refreshHub detects the product of the hub, sets the hub type if the hub
+// type detection is enabled, and updates the versions of the hub info. The
+// hub type and the versions are kept if they cannot be read.
+func (b *Builder) refreshHub(dynamicClient dynamic.Interface, ocpClient ocpclient.Interface) {
+	product, err := detectHubProduct(b.ctx, dynamicClient)
+	if err != nil {
+		klog.Errorf("cannot detect the hub product: %v", err)
+	} else if b.detectHubType {
+		b.SetHubType(product.hubType)
+	}
+
+	if b.hubInfoStore == nil {
+		return
+	}
+	info := b.hubInfoStore.Info()
+	if err == nil {
+		info.ProductVersion = product.version
+	}
+	if serverVersion, err := b.discoveryClient().ServerVersion(); err != nil {
+		klog.Errorf("cannot get the kubernetes version of the hub: %v", err)
+	} else {
+		info.KubeVersion = serverVersion.GitVersion
+	}
+	// the hubs without ClusterVersion are not OpenShift clusters
+	if info.ClusterIDSource != HubClusterIDSourceKubeSystem {
+		if cv, err := ocpClient.ConfigV1().ClusterVersions().Get(b.ctx, "version", metav1.GetOptions{}); err != nil {
+			klog.V(4).Infof("cannot get the cluster version of the hub: %v", err)
+		} else {
+			info.OCPVersion = cv.Status.Desired.Version
+		}
+	}
+	b.hubInfoStore.Update(func(i *cluster.HubInfo) {
+		i.ProductVersion, i.KubeVersion, i.OCPVersion = info.ProductVersion, info.KubeVersion, info.OCPVersion
+	})
+}
+
+// discoveryClient returns the discovery client of the kubeclient, or a new
+// one created from the rest config if the builder has no kubeclient.
+func (b *Builder) discoveryClient() discovery.DiscoveryInterface {
+	if b.kubeclient != nil {
+		return b.kubeclient.Discovery()
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(b.restConfig)
+	if err != nil {
+		klog.Fatalf("cannot create the discovery client: %v", err)
+	}
+	return client
+}
+
+// newHubClients returns the clients reading the product and the versions of
+// the hub.
+func (b *Builder) newHubClients() (dynamic.Interface, ocpclient.Interface, error) {
+	dynamicClient, err := dynamic.NewForConfig(b.restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	ocpClient, err := ocpclient.NewForConfig(b.restConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dynamicClient, ocpClient, nil
+}
+
+// startRefreshingHub refreshes the hub type and the hub info once the
+// collectors are built, and every HubResyncPeriod. The hub type is detected
+// whatever the enabled collectors, while the hub info is only refreshed once
+// the managed cluster collector is built.
+func (b *Builder) startRefreshingHub() {
+	if b.hubInfoStore == nil && !b.detectHubType {
+		return
+	}
+
+	dynamicClient, ocpClient, err := b.newHubClients()
+	if err != nil {
+		klog.Fatalf("cannot create the hub clients: %v", err)
+	}
+	b.refreshHub(dynamicClient, ocpClient)
+
+	go func() {
+		ticker := time.NewTicker(HubResyncPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-ticker.C:
+				b.refreshHub(dynamicClient, ocpClient)
+			}
+		}
+	}()
+}
@@ -0,0 +1,71 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	ocpclientfake "github.com/openshift/client-go/config/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/kube-state-metrics/pkg/whiteblacklist"
+)
+
+func TestBuilder_refreshHub(t *testing.T) {
+	mch := newObject("operator.open-cluster-management.io/v1", "MultiClusterHub", "open-cluster-management", "multiclusterhub", nil)
+	mch["status"] = map[string]interface{}{"currentVersion": "2.11.0"}
+	server := newListServer(t, map[string][]map[string]interface{}{multiClusterHubsPath: {mch}}, nil)
+	defer server.Close()
+	dynamicClient, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kubeClient := kubeclientfake.NewSimpleClientset()
+	kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.29.5"}
+	ocpClient := ocpclientfake.NewSimpleClientset(&ocinfrav1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "version"},
+		Spec:       ocinfrav1.ClusterVersionSpec{ClusterID: "hub-id"},
+		Status: ocinfrav1.ClusterVersionStatus{
+			Desired: ocinfrav1.Release{Version: "4.16.3"},
+		},
+	})
+
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO()).
+		WithKubeclient(kubeClient).
+		WithHubClusterID("hub-id").
+		WithHubTypeDetection().
+		WithEnabledCollectors([]string{"managedclusteraddons", "managedclusters"}).
+		WithWhiteBlackList(w)
+	b.hubClusterIDSource = HubClusterIDSourceClusterVersion
+
+	collectors, err := b.BuildFromObjects([]runtime.Object{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.refreshHub(dynamicClient, ocpClient)
+
+	buf := &bytes.Buffer{}
+	for _, c := range collectors {
+		c.WriteFiltered(buf, func(string) bool { return false })
+	}
+	want := `acm_hub_info{hub_cluster_id="hub-id",hub_cluster_id_source="clusterversion",hub_type="acm",` +
+		`kube_version="v1.29.5",ocp_version="4.16.3",product_version="2.11.0",exporter_version="UNKNOWN",` +
+		`collectors="managedclusteraddons,managedclusters"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in:\n%s", want, buf.String())
+	}
+	if got := b.HubType(); got != HubTypeACM {
+		t.Errorf("expected hub type %q, got %q", HubTypeACM, got)
+	}
+}
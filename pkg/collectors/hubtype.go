@@ -0,0 +1,215 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
+)
+
+// The types of the hubs
+const (
+	HubTypeMCE              = "mce"
+	HubTypeACM              = "acm"
+	HubTypeStolostronEngine = "stolostron-engine"
+	HubTypeStolostron       = "stolostron"
+)
+
+// defaultMultiClusterEngineNamespace is the namespace of the multicluster
+// engine if its target namespace is not set.
+const defaultMultiClusterEngineNamespace = "multicluster-engine"
+
+// HubResyncPeriod is the period the detected hub type and the hub info are
+// refreshed, so a hub upgraded from MCE to ACM is detected.
+var HubResyncPeriod = 5 * time.Minute
+
+var (
+	multiClusterHubGVR = schema.GroupVersionResource{
+		Group:    "operator.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "multiclusterhubs",
+	}
+	multiClusterEngineGVR = schema.GroupVersionResource{
+		Group:    "multicluster.openshift.io",
+		Version:  "v1",
+		Resource: "multiclusterengines",
+	}
+	clusterServiceVersionGVR = schema.GroupVersionResource{
+		Group:    "operators.coreos.com",
+		Version:  "v1alpha1",
+		Resource: "clusterserviceversions",
+	}
+
+	multiClusterHubGroupKind       = schema.GroupKind{Group: multiClusterHubGVR.Group, Kind: "MultiClusterHub"}
+	multiClusterEngineGroupKind    = schema.GroupKind{Group: multiClusterEngineGVR.Group, Kind: "MultiClusterEngine"}
+	clusterServiceVersionGroupKind = schema.GroupKind{Group: clusterServiceVersionGVR.Group, Kind: "ClusterServiceVersion"}
+)
+
+// hubProduct is the product installed on a hub.
+type hubProduct struct {
+	hubType string
+	// version is the current version of the MultiClusterHub or MultiClusterEngine
+	version string
+}
+
+// detectHubProduct returns the product of the hub from its MultiClusterHub or
+// MultiClusterEngine, or an empty product if it has neither, following the
+// rules of hubProductOf. A missing CRD is not an error.
+func detectHubProduct(ctx context.Context, client dynamic.Interface) (hubProduct, error) {
+	mchs, err := client.Resource(multiClusterHubGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return hubProduct{}, fmt.Errorf("cannot list multiclusterhubs: %v", err)
+	}
+	if err != nil {
+		mchs = &unstructured.UnstructuredList{}
+	}
+
+	mces, err := client.Resource(multiClusterEngineGVR).List(ctx, metav1.ListOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return hubProduct{}, fmt.Errorf("cannot list multiclusterengines: %v", err)
+	}
+	if err != nil {
+		mces = &unstructured.UnstructuredList{}
+	}
+
+	return hubProductOf(mchs.Items, mces.Items, func(namespace string) ([]unstructured.Unstructured, error) {
+		csvs, err := client.Resource(clusterServiceVersionGVR).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return csvs.Items, nil
+	}), nil
+}
+
+// HubTypeFromObjects returns the type of the hub from the MultiClusterHub,
+// MultiClusterEngine and ClusterServiceVersions of the given objects, such as
+// the manifests of a must-gather, following the rules of hubProductOf. It
+// returns an empty type if the objects have no MultiClusterHub or
+// MultiClusterEngine.
+func HubTypeFromObjects(objects []runtime.Object) string {
+	mchs, mces, csvs := []unstructured.Unstructured{}, []unstructured.Unstructured{}, []unstructured.Unstructured{}
+	for _, obj := range objects {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		switch u.GroupVersionKind().GroupKind() {
+		case multiClusterHubGroupKind:
+			mchs = append(mchs, *u)
+		case multiClusterEngineGroupKind:
+			mces = append(mces, *u)
+		case clusterServiceVersionGroupKind:
+			csvs = append(csvs, *u)
+		}
+	}
+
+	return hubProductOf(mchs, mces, func(namespace string) ([]unstructured.Unstructured, error) {
+		namespaceCSVs := []unstructured.Unstructured{}
+		for _, csv := range csvs {
+			if csv.GetNamespace() == namespace {
+				namespaceCSVs = append(namespaceCSVs, csv)
+			}
+		}
+		return namespaceCSVs, nil
+	}).hubType
+}
+
+// hubProductOf returns the product of a hub with the given MultiClusterHubs
+// and MultiClusterEngines, or an empty product if it has neither. A hub with a
+// MultiClusterHub is an ACM hub, otherwise a hub with a MultiClusterEngine is
+// an MCE hub. The community distributions are told apart by the
+// ClusterServiceVersion of their operator, named after the stolostron or
+// stolostron-engine package, in the namespace of the MultiClusterHub or
+// MultiClusterEngine, which listCSVs returns.
+func hubProductOf(mchs, mces []unstructured.Unstructured, listCSVs func(namespace string) ([]unstructured.Unstructured, error)) hubProduct {
+	if len(mchs) > 0 {
+		mch := mchs[0]
+		version, _, _ := unstructured.NestedString(mch.Object, "status", "currentVersion")
+		return hubProduct{
+			hubType: hubTypeOf(listCSVs, HubTypeACM, HubTypeStolostron, mch.GetNamespace()),
+			version: version,
+		}
+	}
+
+	if len(mces) > 0 {
+		mce := mces[0]
+		namespace, _, _ := unstructured.NestedString(mce.Object, "spec", "targetNamespace")
+		if namespace == "" {
+			namespace = defaultMultiClusterEngineNamespace
+		}
+		version, _, _ := unstructured.NestedString(mce.Object, "status", "currentVersion")
+		return hubProduct{
+			hubType: hubTypeOf(listCSVs, HubTypeMCE, HubTypeStolostronEngine, namespace),
+			version: version,
+		}
+	}
+	return hubProduct{}
+}
+
+// hubTypeOf returns the community type if the namespace has a
+// ClusterServiceVersion of the community package, otherwise the product type.
+// The product type is returned if the ClusterServiceVersions cannot be listed,
+// such as on a hub without OLM.
+func hubTypeOf(listCSVs func(namespace string) ([]unstructured.Unstructured, error), productType, communityType, namespace string) string {
+	csvs, err := listCSVs(namespace)
+	if err != nil {
+		klog.V(4).Infof("cannot list the clusterserviceversions of namespace %s: %v", namespace, err)
+		return productType
+	}
+	for _, csv := range csvs {
+		if strings.HasPrefix(csv.GetName(), communityType+".") {
+			return communityType
+		}
+	}
+	return productType
+}
+
+// WithHubTypeDetection detects the type of the hub once the collectors are
+// built, and refreshes it every HubResyncPeriod, instead of the type set by
+// WithHubType.
+func (b *Builder) WithHubTypeDetection() *Builder {
+	b.detectHubType = true
+	return b
+}
+
+// HubType returns the type of the hub.
+func (b *Builder) HubType() string {
+	b.hubTypeMutex.RLock()
+	defer b.hubTypeMutex.RUnlock()
+	return b.hubType
+}
+
+// SetHubType sets the type of the hub at runtime. Once changed, the
+// ManagedClusters of all clusters are refreshed to rebuild their info metrics.
+func (b *Builder) SetHubType(hubType string) {
+	b.hubTypeMutex.Lock()
+	defer b.hubTypeMutex.Unlock()
+	if b.hubType == hubType {
+		return
+	}
+
+	klog.Infof("The hub type is changed from %q to %q", b.hubType, hubType)
+	b.hubType = hubType
+	if b.hubInfoStore != nil {
+		b.hubInfoStore.Update(func(info *cluster.HubInfo) {
+			info.Type = hubType
+		})
+	}
+	if b.built.Load() {
+		for _, clusterName := range b.clusterIdCache.ClusterNames() {
+			b.getRefreshQueue().Enqueue(refreshManagedCluster, clusterName)
+		}
+	}
+}
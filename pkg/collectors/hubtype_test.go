@@ -0,0 +1,244 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	multiClusterHubsPath    = "/apis/operator.open-cluster-management.io/v1/multiclusterhubs"
+	multiClusterEnginesPath = "/apis/multicluster.openshift.io/v1/multiclusterengines"
+)
+
+func csvsPath(namespace string) string {
+	return "/apis/operators.coreos.com/v1alpha1/namespaces/" + namespace + "/clusterserviceversions"
+}
+
+// newListServer returns a server responding the lists of the given paths, the
+// given status codes of the other given paths, and 404 otherwise.
+func newListServer(t *testing.T, lists map[string][]map[string]interface{}, codes map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if code, ok := codes[r.URL.Path]; ok {
+			w.WriteHeader(code)
+			_ = json.NewEncoder(w).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+				Status:   metav1.StatusFailure,
+				Code:     int32(code),
+			})
+			return
+		}
+		items, ok := lists[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+			})
+			return
+		}
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"metadata":   map[string]interface{}{},
+			"items":      items,
+		}); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func newObject(apiVersion, kind, namespace, name string, spec map[string]interface{}) map[string]interface{} {
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	}
+	if spec != nil {
+		obj["spec"] = spec
+	}
+	return obj
+}
+
+func Test_detectHubProduct(t *testing.T) {
+	mch := newObject("operator.open-cluster-management.io/v1", "MultiClusterHub", "open-cluster-management", "multiclusterhub", nil)
+	mch["status"] = map[string]interface{}{"currentVersion": "2.10.0"}
+	mce := newObject("multicluster.openshift.io/v1", "MultiClusterEngine", "", "multiclusterengine", nil)
+	mce["status"] = map[string]interface{}{"currentVersion": "2.5.0"}
+	csv := func(namespace, name string) map[string]interface{} {
+		return newObject("operators.coreos.com/v1alpha1", "ClusterServiceVersion", namespace, name, nil)
+	}
+
+	tests := []struct {
+		name    string
+		lists   map[string][]map[string]interface{}
+		codes   map[string]int
+		want    hubProduct
+		wantErr bool
+	}{
+		{
+			name: "no multiclusterhub or multiclusterengine crd",
+			want: hubProduct{},
+		},
+		{
+			name: "no multiclusterhub or multiclusterengine",
+			lists: map[string][]map[string]interface{}{
+				multiClusterHubsPath:    {},
+				multiClusterEnginesPath: {},
+			},
+			want: hubProduct{},
+		},
+		{
+			name: "mce without olm",
+			lists: map[string][]map[string]interface{}{
+				multiClusterEnginesPath: {mce},
+			},
+			want: hubProduct{hubType: HubTypeMCE, version: "2.5.0"},
+		},
+		{
+			name: "stolostron engine in the target namespace",
+			lists: map[string][]map[string]interface{}{
+				multiClusterEnginesPath: {newObject("multicluster.openshift.io/v1", "MultiClusterEngine", "", "engine",
+					map[string]interface{}{"targetNamespace": "engine"})},
+				csvsPath("engine"): {csv("engine", "stolostron-engine.v2.5.0")},
+			},
+			want: hubProduct{hubType: HubTypeStolostronEngine},
+		},
+		{
+			name: "acm upgraded from mce",
+			lists: map[string][]map[string]interface{}{
+				multiClusterHubsPath:                         {mch},
+				multiClusterEnginesPath:                      {mce},
+				csvsPath("open-cluster-management"):          {csv("open-cluster-management", "advanced-cluster-management.v2.10.0")},
+				csvsPath(defaultMultiClusterEngineNamespace): {csv(defaultMultiClusterEngineNamespace, "multicluster-engine.v2.5.0")},
+			},
+			want: hubProduct{hubType: HubTypeACM, version: "2.10.0"},
+		},
+		{
+			name: "stolostron",
+			lists: map[string][]map[string]interface{}{
+				multiClusterHubsPath:                {mch},
+				csvsPath("open-cluster-management"): {csv("open-cluster-management", "stolostron.v2.10.0")},
+			},
+			want: hubProduct{hubType: HubTypeStolostron, version: "2.10.0"},
+		},
+		{
+			name:    "forbidden",
+			codes:   map[string]int{multiClusterHubsPath: http.StatusForbidden},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newListServer(t, tt.lists, tt.codes)
+			defer server.Close()
+			client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := detectHubProduct(context.TODO(), client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected hub product %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_HubTypeFromObjects(t *testing.T) {
+	object := func(obj map[string]interface{}) runtime.Object {
+		return &unstructured.Unstructured{Object: obj}
+	}
+	mch := object(newObject("operator.open-cluster-management.io/v1", "MultiClusterHub", "open-cluster-management", "multiclusterhub", nil))
+	mce := object(newObject("multicluster.openshift.io/v1", "MultiClusterEngine", "", "multiclusterengine", nil))
+	csv := func(namespace, name string) runtime.Object {
+		return object(newObject("operators.coreos.com/v1alpha1", "ClusterServiceVersion", namespace, name, nil))
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		want    string
+	}{
+		{
+			name:    "no multiclusterhub or multiclusterengine",
+			objects: []runtime.Object{newTestManagedCluster("cluster1"), csv("open-cluster-management", "stolostron.v2.10.0")},
+		},
+		{
+			name:    "mce",
+			objects: []runtime.Object{mce},
+			want:    HubTypeMCE,
+		},
+		{
+			name:    "stolostron engine",
+			objects: []runtime.Object{mce, csv(defaultMultiClusterEngineNamespace, "stolostron-engine.v2.5.0")},
+			want:    HubTypeStolostronEngine,
+		},
+		{
+			name:    "acm upgraded from mce",
+			objects: []runtime.Object{mce, mch, csv(defaultMultiClusterEngineNamespace, "stolostron-engine.v2.5.0")},
+			want:    HubTypeACM,
+		},
+		{
+			name:    "stolostron",
+			objects: []runtime.Object{mch, csv("open-cluster-management", "stolostron.v2.10.0")},
+			want:    HubTypeStolostron,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HubTypeFromObjects(tt.objects); got != tt.want {
+				t.Errorf("expected hub type %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBuilder_startRefreshingHub(t *testing.T) {
+	mce := newObject("multicluster.openshift.io/v1", "MultiClusterEngine", "", "multiclusterengine", nil)
+	server := newListServer(t, map[string][]map[string]interface{}{multiClusterEnginesPath: {mce}}, nil)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the hub type is detected without the managed cluster collector
+	b := NewBuilder(ctx).
+		WithRestConfig(&rest.Config{Host: server.URL}).
+		WithHubTypeDetection()
+	b.startRefreshingHub()
+	if b.hubInfoStore != nil {
+		t.Errorf("expected no hub info without the managed cluster collector")
+	}
+	if got := b.HubType(); got != HubTypeMCE {
+		t.Errorf("expected hub type %q, got %q", HubTypeMCE, got)
+	}
+}
+
+func Test_SetHubType(t *testing.T) {
+	b := NewBuilder(context.TODO()).WithHubType(HubTypeMCE)
+	if got := b.HubType(); got != HubTypeMCE {
+		t.Errorf("expected hub type %q, got %q", HubTypeMCE, got)
+	}
+	b.SetHubType(HubTypeACM)
+	if got := b.HubType(); got != HubTypeACM {
+		t.Errorf("expected hub type %q, got %q", HubTypeACM, got)
+	}
+}
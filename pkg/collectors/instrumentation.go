@@ -0,0 +1,245 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	storeOperationAdd     = "add"
+	storeOperationUpdate  = "update"
+	storeOperationDelete  = "delete"
+	storeOperationReplace = "replace"
+)
+
+// instrumentedStore implements the k8s.io/client-go/tools/cache.Store
+// interface. It wraps the store a reflector writes into, and reports the
+// number of stored objects, store errors and the time of the last event
+// on the telemetry registry.
+type instrumentedStore struct {
+	hub      string
+	resource string
+	store    cache.Store
+
+	// Protects uids
+	mutex sync.RWMutex
+
+	// uids is a set of the UIDs of the stored objects
+	uids map[types.UID]struct{}
+}
+
+// newInstrumentedStore returns a new instrumentedStore
+func newInstrumentedStore(hub, resource string, store cache.Store) *instrumentedStore {
+	return &instrumentedStore{
+		hub:      hub,
+		resource: resource,
+		store:    store,
+		uids:     map[types.UID]struct{}{},
+	}
+}
+
+// Len returns the number of the stored objects.
+func (s *instrumentedStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.uids)
+}
+
+// Add implements the Add method of the store interface.
+func (s *instrumentedStore) Add(obj interface{}) error {
+	s.track(obj, true)
+	return s.observe(storeOperationAdd, s.store.Add(obj))
+}
+
+// Update implements the Update method of the store interface.
+func (s *instrumentedStore) Update(obj interface{}) error {
+	s.track(obj, true)
+	return s.observe(storeOperationUpdate, s.store.Update(obj))
+}
+
+// Delete implements the Delete method of the store interface.
+func (s *instrumentedStore) Delete(obj interface{}) error {
+	s.track(obj, false)
+	return s.observe(storeOperationDelete, s.store.Delete(obj))
+}
+
+// List implements the List method of the store interface.
+func (s *instrumentedStore) List() []interface{} {
+	return s.store.List()
+}
+
+// ListKeys implements the ListKeys method of the store interface.
+func (s *instrumentedStore) ListKeys() []string {
+	return s.store.ListKeys()
+}
+
+// Get implements the Get method of the store interface.
+func (s *instrumentedStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return s.store.Get(obj)
+}
+
+// GetByKey implements the GetByKey method of the store interface.
+func (s *instrumentedStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return s.store.GetByKey(key)
+}
+
+// Replace implements the Replace method of the store interface.
+func (s *instrumentedStore) Replace(list []interface{}, resourceVersion string) error {
+	s.mutex.Lock()
+	s.uids = map[types.UID]struct{}{}
+	for _, obj := range list {
+		if o, err := meta.Accessor(obj); err == nil {
+			s.uids[o.GetUID()] = struct{}{}
+		}
+	}
+	ResourceObjectsMetric.WithLabelValues(s.hub, s.resource).Set(float64(len(s.uids)))
+	s.mutex.Unlock()
+
+	return s.observe(storeOperationReplace, s.store.Replace(list, resourceVersion))
+}
+
+// Resync implements the Resync method of the store interface.
+func (s *instrumentedStore) Resync() error {
+	return s.store.Resync()
+}
+
+func (s *instrumentedStore) track(obj interface{}, exists bool) {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if exists {
+		s.uids[o.GetUID()] = struct{}{}
+	} else {
+		delete(s.uids, o.GetUID())
+	}
+	ResourceObjectsMetric.WithLabelValues(s.hub, s.resource).Set(float64(len(s.uids)))
+}
+
+func (s *instrumentedStore) observe(operation string, err error) error {
+	LastEventTimestampMetric.WithLabelValues(s.hub, s.resource).SetToCurrentTime()
+	if err != nil {
+		StoreErrorTotalMetric.WithLabelValues(s.hub, s.resource, operation).Inc()
+	}
+	return err
+}
+
+// instrumentListWatch returns a ListWatch which counts the restarts of the
+// watch on the given resource of a hub. The first watch is started after the
+// initial list, every watch after it is a restart.
+func instrumentListWatch(hub, resource string, lw *cache.ListWatch) *cache.ListWatch {
+	watches := &atomic.Int64{}
+	onWatch := func() {
+		if watches.Add(1) > 1 {
+			WatchRestartTotalMetric.WithLabelValues(hub, resource).Inc()
+		}
+	}
+
+	instrumented := &cache.ListWatch{
+		ListFunc:            lw.ListFunc,
+		ListWithContextFunc: lw.ListWithContextFunc,
+		DisableChunking:     lw.DisableChunking,
+	}
+	if lw.WatchFunc != nil {
+		instrumented.WatchFunc = func(options metav1.ListOptions) (watch.Interface, error) {
+			onWatch()
+			return lw.WatchFunc(options)
+		}
+	}
+	if lw.WatchFuncWithContext != nil {
+		instrumented.WatchFuncWithContext = func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			onWatch()
+			return lw.WatchFuncWithContext(ctx, options)
+		}
+	}
+	return instrumented
+}
+
+// instrumentedCollector wraps a MetricsCollector and reports the render time,
+// the size of the rendered metrics and the number of the rendered resources of
+// each write on the telemetry registry.
+type instrumentedCollector struct {
+	hub       string
+	resource  string
+	collector MetricsCollector
+	// objects returns the number of the objects rendered by the collector
+	objects func() int
+}
+
+func newInstrumentedCollector(hub, resource string, collector MetricsCollector, objects func() int) *instrumentedCollector {
+	return &instrumentedCollector{
+		hub:       hub,
+		resource:  resource,
+		collector: collector,
+		objects:   objects,
+	}
+}
+
+func (c *instrumentedCollector) WriteAll(w io.Writer) {
+	c.observe(w, c.collector.WriteAll)
+}
+
+func (c *instrumentedCollector) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	c.observe(w, func(w io.Writer) {
+		c.collector.WriteFiltered(w, filter)
+	})
+}
+
+// CollectFamilies collects the metric families of the wrapped collector and
+// reports the time to collect them. Their size is not known until they are
+// encoded, so it is not reported.
+func (c *instrumentedCollector) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {
+	start := time.Now()
+	c.collector.CollectFamilies(filter, collect)
+	RenderDurationMetric.WithLabelValues(c.hub, c.resource).Observe(time.Since(start).Seconds())
+	if c.objects != nil {
+		ResourcesPerScrapeMetric.WithLabelValues(c.hub, c.resource).Observe(float64(c.objects()))
+	}
+}
+
+func (c *instrumentedCollector) observe(w io.Writer, writeFunc func(w io.Writer)) {
+	start := time.Now()
+	cw := &countingWriter{writer: w}
+
+	writeFunc(cw)
+
+	RenderDurationMetric.WithLabelValues(c.hub, c.resource).Observe(time.Since(start).Seconds())
+	ResponseSizeMetric.WithLabelValues(c.hub, c.resource).Observe(float64(cw.count))
+	if cw.errs > 0 {
+		ScrapeErrorTotalMetric.WithLabelValues(c.hub, c.resource).Add(float64(cw.errs))
+	}
+	if c.objects != nil {
+		ResourcesPerScrapeMetric.WithLabelValues(c.hub, c.resource).Observe(float64(c.objects()))
+	}
+}
+
+// countingWriter counts the bytes written and the write errors.
+type countingWriter struct {
+	writer io.Writer
+	count  int
+	errs   int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += n
+	if err != nil {
+		w.errs++
+	}
+	return n, err
+}
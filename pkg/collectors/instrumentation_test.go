@@ -0,0 +1,158 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func newTestManagedCluster(name string) *mcv1.ManagedCluster {
+	return &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			UID:  ktypes.UID("uid-" + name),
+		},
+	}
+}
+
+func metricValue(t *testing.T, m prometheus.Metric) *dto.Metric {
+	out := &dto.Metric{}
+	if err := m.Write(out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func Test_InstrumentedStore(t *testing.T) {
+	resource := "test-instrumented-store"
+	store := newInstrumentedStore("hub1", resource, cache.NewStore(cache.MetaNamespaceKeyFunc))
+	// the store of the same resource on another hub is reported apart
+	otherStore := newInstrumentedStore("hub2", resource, cache.NewStore(cache.MetaNamespaceKeyFunc))
+	if err := otherStore.Add(newTestManagedCluster("cluster1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Replace([]interface{}{newTestManagedCluster("cluster1"), newTestManagedCluster("cluster2")}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(newTestManagedCluster("cluster3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(newTestManagedCluster("cluster3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(newTestManagedCluster("cluster1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.Len() != 2 {
+		t.Errorf("expected 2 objects, but got %d", store.Len())
+	}
+	if got := metricValue(t, ResourceObjectsMetric.WithLabelValues("hub1", resource)).GetGauge().GetValue(); got != 2 {
+		t.Errorf("expected objects metric 2, but got %v", got)
+	}
+	if got := metricValue(t, ResourceObjectsMetric.WithLabelValues("hub2", resource)).GetGauge().GetValue(); got != 1 {
+		t.Errorf("expected objects metric 1 on hub2, but got %v", got)
+	}
+	if got := metricValue(t, LastEventTimestampMetric.WithLabelValues("hub1", resource)).GetGauge().GetValue(); got == 0 {
+		t.Errorf("expected last event timestamp to be set")
+	}
+
+	// an object without metadata cannot be stored
+	if err := store.Add("invalid"); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+	if got := metricValue(t, StoreErrorTotalMetric.WithLabelValues("hub1", resource, storeOperationAdd)).GetCounter().GetValue(); got != 1 {
+		t.Errorf("expected 1 store error, but got %v", got)
+	}
+	if store.Len() != 2 {
+		t.Errorf("expected 2 objects, but got %d", store.Len())
+	}
+}
+
+func Test_InstrumentListWatch(t *testing.T) {
+	resource := "test-instrument-listwatch"
+	lw := instrumentListWatch("hub1", resource, &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &mcv1.ManagedClusterList{}, nil
+		},
+		WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+			return watch.NewFake(), nil
+		},
+	})
+
+	if _, err := lw.List(metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := lw.WatchWithContext(context.TODO(), metav1.ListOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := metricValue(t, WatchRestartTotalMetric.WithLabelValues("hub1", resource)).GetCounter().GetValue(); got != 2 {
+		t.Errorf("expected 2 watch restarts, but got %v", got)
+	}
+}
+
+type fakeCollector struct {
+	data string
+}
+
+func (c *fakeCollector) WriteAll(w io.Writer) {
+	_, _ = w.Write([]byte(c.data))
+}
+
+func (c *fakeCollector) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	c.WriteAll(w)
+}
+
+func (c *fakeCollector) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func Test_InstrumentedCollector(t *testing.T) {
+	resource := "test-instrumented-collector"
+	data := "acm_managed_cluster_count 3\n"
+	collector := newInstrumentedCollector("hub1", resource, &fakeCollector{data: data}, func() int { return 3 })
+
+	buf := new(bytes.Buffer)
+	collector.WriteAll(buf)
+	if buf.String() != data {
+		t.Errorf("expected %q, but got %q", data, buf.String())
+	}
+
+	size := metricValue(t, ResponseSizeMetric.WithLabelValues("hub1", resource).(prometheus.Metric)).GetHistogram()
+	if size.GetSampleCount() != 1 || size.GetSampleSum() != float64(len(data)) {
+		t.Errorf("expected response size %d, but got %v", len(data), size.GetSampleSum())
+	}
+	resources := metricValue(t, ResourcesPerScrapeMetric.WithLabelValues("hub1", resource).(prometheus.Metric)).GetSummary()
+	if resources.GetSampleCount() != 1 || resources.GetSampleSum() != 3 {
+		t.Errorf("expected 3 resources per scrape, but got %v", resources.GetSampleSum())
+	}
+	duration := metricValue(t, RenderDurationMetric.WithLabelValues("hub1", resource).(prometheus.Metric)).GetHistogram()
+	if duration.GetSampleCount() != 1 {
+		t.Errorf("expected 1 render duration sample, but got %v", duration.GetSampleCount())
+	}
+
+	collector.WriteAll(failingWriter{})
+	if got := metricValue(t, ScrapeErrorTotalMetric.WithLabelValues("hub1", resource)).GetCounter().GetValue(); got != 1 {
+		t.Errorf("expected 1 scrape error, but got %v", got)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// OptionalResourceRetryPeriod is the period the hub is checked for the
+// optional resources it does not serve, so they are watched once their CRDs
+// are installed.
+var OptionalResourceRetryPeriod = time.Minute
+
+var ResourceAvailableMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "ksm_resource_available",
+		Help: "Whether an optional resource is served by the hub and watched, 0 while its CRD is missing",
+	},
+	[]string{"hub", "resource"},
+)
+
+// optionalResource is a resource whose CRD may be missing on the hub, such as
+// the ClusterDeployments of a hub without Hive. It is watched once the hub
+// serves it.
+type optionalResource struct {
+	name string
+	gvr  schema.GroupVersionResource
+
+	// discovered is set once the hub is checked for the resource
+	discovered atomic.Bool
+	// available is set once the resource is watched
+	available atomic.Bool
+
+	// Protects synced
+	mutex sync.RWMutex
+	// synced reports whether the reflector of the resource has completed its initial list
+	synced func() bool
+}
+
+func newOptionalResource(name string, gvr schema.GroupVersionResource) *optionalResource {
+	return &optionalResource{name: name, gvr: gvr}
+}
+
+// Available returns true once the resource is watched.
+func (r *optionalResource) Available() bool {
+	return r.available.Load()
+}
+
+// HasSynced returns true once the hub is checked for the resource, and either
+// the resource is not served or its reflector has completed its initial list.
+// A missing CRD does not block the readiness of the exporter.
+func (r *optionalResource) HasSynced() bool {
+	if !r.discovered.Load() {
+		return false
+	}
+	if !r.available.Load() {
+		return true
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.synced != nil && r.synced()
+}
+
+// isServed returns true if the hub serves the resource.
+func isServed(client discovery.DiscoveryInterface, gvr schema.GroupVersionResource) (bool, error) {
+	resources, err := client.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == gvr.Resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// watchOptionalResource runs the reflector returned by start once the hub
+// serves the resource. The hub is checked every OptionalResourceRetryPeriod
+// until it serves the resource and start succeeds.
+func (b *Builder) watchOptionalResource(resource *optionalResource, start func() (*cache.Reflector, error)) {
+	b.addSyncedFunc(resource.HasSynced)
+	ResourceAvailableMetric.WithLabelValues(b.hubName, resource.name).Set(0)
+
+	discoveryClient := b.discoveryClient()
+	tryStart := func() bool {
+		served, err := isServed(discoveryClient, resource.gvr)
+		if err != nil {
+			klog.Errorf("cannot check if %s is served: %v", resource.gvr.String(), err)
+			return false
+		}
+		resource.discovered.Store(true)
+		if !served {
+			return false
+		}
+
+		reflector, err := start()
+		if err != nil {
+			klog.Errorf("cannot start watching %s: %v", resource.name, err)
+			return false
+		}
+		resource.mutex.Lock()
+		resource.synced = func() bool {
+			return reflector.LastSyncResourceVersion() != ""
+		}
+		resource.mutex.Unlock()
+		resource.available.Store(true)
+		ResourceAvailableMetric.WithLabelValues(b.hubName, resource.name).Set(1)
+		go reflector.Run(b.ctx.Done())
+		return true
+	}
+
+	if tryStart() {
+		return
+	}
+	klog.Infof("%s is not watched since the hub does not serve it, check again every %v", resource.gvr.String(), OptionalResourceRetryPeriod)
+	go func() {
+		_ = wait.PollUntilContextCancel(b.ctx, OptionalResourceRetryPeriod, false, func(context.Context) (bool, error) {
+			return tryStart(), nil
+		})
+	}()
+}
@@ -0,0 +1,153 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	kubeclientfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var testOptionalGVR = schema.GroupVersionResource{Group: "hive.openshift.io", Version: "v1", Resource: "clusterdeployments"}
+
+// servingDiscovery serves the resources of a group version once served is set.
+type servingDiscovery struct {
+	discovery.DiscoveryInterface
+	gvr    schema.GroupVersionResource
+	served atomic.Bool
+}
+
+func (d *servingDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	if !d.served.Load() || groupVersion != d.gvr.GroupVersion().String() {
+		return nil, errors.NewNotFound(schema.GroupResource{}, groupVersion)
+	}
+	return &metav1.APIResourceList{
+		GroupVersion: groupVersion,
+		APIResources: []metav1.APIResource{{Name: d.gvr.Resource}},
+	}, nil
+}
+
+// discoveryKubeclient is a kubeclient with the given discovery client.
+type discoveryKubeclient struct {
+	kubernetes.Interface
+	discovery discovery.DiscoveryInterface
+}
+
+func (c *discoveryKubeclient) Discovery() discovery.DiscoveryInterface {
+	return c.discovery
+}
+
+func Test_isServed(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*metav1.APIResourceList
+		want      bool
+	}{
+		{
+			name: "group version not served",
+		},
+		{
+			name: "resource not served",
+			resources: []*metav1.APIResourceList{{
+				GroupVersion: testOptionalGVR.GroupVersion().String(),
+				APIResources: []metav1.APIResource{{Name: "clusterpools"}},
+			}},
+		},
+		{
+			name: "resource served",
+			resources: []*metav1.APIResourceList{{
+				GroupVersion: testOptionalGVR.GroupVersion().String(),
+				APIResources: []metav1.APIResource{{Name: "clusterpools"}, {Name: "clusterdeployments"}},
+			}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubeclientfake.NewSimpleClientset()
+			kubeClient.Discovery().(*fakediscovery.FakeDiscovery).Resources = tt.resources
+
+			got, err := isServed(kubeClient.Discovery(), testOptionalGVR)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("isServed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuilder_watchOptionalResource(t *testing.T) {
+	defer func(period time.Duration) { OptionalResourceRetryPeriod = period }(OptionalResourceRetryPeriod)
+	OptionalResourceRetryPeriod = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	discoveryClient := &servingDiscovery{gvr: testOptionalGVR}
+	b := NewBuilder(ctx).WithHubName("hub1").WithKubeclient(&discoveryKubeclient{
+		Interface: kubeclientfake.NewSimpleClientset(),
+		discovery: discoveryClient,
+	})
+
+	var started atomic.Int32
+	resource := newOptionalResource("test-optional-resource", testOptionalGVR)
+	b.watchOptionalResource(resource, func() (*cache.Reflector, error) {
+		started.Add(1)
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return &corev1.ConfigMapList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}, nil
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				// fall back to list and watch like a hub without watch list
+				if options.SendInitialEvents != nil {
+					return nil, errors.NewBadRequest("watch list is not supported")
+				}
+				return watch.NewFake(), nil
+			},
+		}
+		return cache.NewReflector(lw, &corev1.ConfigMap{}, cache.NewStore(cache.MetaNamespaceKeyFunc), 0), nil
+	})
+
+	// a missing resource does not block the readiness
+	if resource.Available() {
+		t.Errorf("expected the resource not available before it is served")
+	}
+	if !resource.HasSynced() {
+		t.Errorf("expected the missing resource synced")
+	}
+	if v := metricValue(t, ResourceAvailableMetric.WithLabelValues("hub1", "test-optional-resource")).GetGauge().GetValue(); v != 0 {
+		t.Errorf("expected resource_available 0, got %v", v)
+	}
+
+	discoveryClient.served.Store(true)
+	err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		return resource.Available() && resource.HasSynced(), nil
+	})
+	if err != nil {
+		t.Fatalf("the resource is not watched once served: %v", err)
+	}
+	if v := metricValue(t, ResourceAvailableMetric.WithLabelValues("hub1", "test-optional-resource")).GetGauge().GetValue(); v != 1 {
+		t.Errorf("expected resource_available 1, got %v", v)
+	}
+
+	time.Sleep(5 * OptionalResourceRetryPeriod)
+	if n := started.Load(); n != 1 {
+		t.Errorf("expected the resource started once, got %d", n)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	refreshQueueName = "refresh"
+
+	// maxRefreshRetries is the number of times a failed refresh request is
+	// requeued before it is dropped.
+	maxRefreshRetries = 5
+)
+
+// refreshTarget identifies the metrics which have to be re-rendered for a
+// cluster once the state cached for it is changed.
+type refreshTarget string
+
+const (
+	refreshManagedCluster       refreshTarget = "managedcluster"
+	refreshManagedClusterAddOns refreshTarget = "managedclusteraddons"
+	refreshManifestWorks        refreshTarget = "manifestworks"
+)
+
+// refreshRequest is the item of the refresh queue. Requests with the same
+// target and cluster name are deduplicated by the queue.
+type refreshRequest struct {
+	target      refreshTarget
+	clusterName string
+}
+
+type refreshFunc func(clusterName string) error
+
+// refreshQueue re-renders the metrics depending on cross-resource state (cluster
+// ID, import timestamps and hibernating state) asynchronously, so that the caches
+// do not have to run the refresh inline while the reflector is adding objects.
+type refreshQueue struct {
+	queue workqueue.TypedRateLimitingInterface[refreshRequest]
+
+	mutex    sync.RWMutex
+	handlers map[refreshTarget]refreshFunc
+}
+
+// newRefreshQueue returns a new refreshQueue reporting its self metrics with
+// the name of the hub.
+func newRefreshQueue(hub string) *refreshQueue {
+	return &refreshQueue{
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[refreshRequest](),
+			workqueue.TypedRateLimitingQueueConfig[refreshRequest]{
+				Name:            refreshQueueName,
+				MetricsProvider: refreshQueueMetricsProvider{hub: hub},
+			},
+		),
+		handlers: map[refreshTarget]refreshFunc{},
+	}
+}
+
+// AddHandler registers the func which refreshes the metrics of the given target.
+func (q *refreshQueue) AddHandler(target refreshTarget, handler refreshFunc) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.handlers[target] = handler
+}
+
+// Enqueue requests a refresh of the metrics of the given target for a cluster.
+func (q *refreshQueue) Enqueue(target refreshTarget, clusterName string) {
+	q.queue.Add(refreshRequest{target: target, clusterName: clusterName})
+}
+
+// Len returns the number of requests waiting to be processed.
+func (q *refreshQueue) Len() int {
+	return q.queue.Len()
+}
+
+// Run starts the given number of workers and blocks until the context is done.
+func (q *refreshQueue) Run(ctx context.Context, workers int) {
+	defer q.queue.ShutDown()
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	klog.Infof("Starting %d refresh workers", workers)
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, q.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+	klog.Info("Shutting down refresh workers")
+}
+
+func (q *refreshQueue) runWorker(ctx context.Context) {
+	for q.processNextItem() {
+	}
+}
+
+func (q *refreshQueue) processNextItem() bool {
+	req, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(req)
+
+	q.mutex.RLock()
+	handler, ok := q.handlers[req.target]
+	q.mutex.RUnlock()
+	if !ok {
+		klog.Warningf("No refresh handler registered for %q, ignore cluster %q", req.target, req.clusterName)
+		q.queue.Forget(req)
+		return true
+	}
+
+	err := handler(req.clusterName)
+	switch {
+	case err == nil:
+		q.queue.Forget(req)
+	case q.queue.NumRequeues(req) < maxRefreshRetries:
+		klog.Warningf("Failed to refresh %s metrics of cluster %q, retrying: %v", req.target, req.clusterName, err)
+		q.queue.AddRateLimited(req)
+	default:
+		klog.Errorf("Failed to refresh %s metrics of cluster %q, giving up: %v", req.target, req.clusterName, err)
+		q.queue.Forget(req)
+	}
+
+	return true
+}
+
+var (
+	RefreshQueueDepthMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ksm_refresh_queue_depth",
+			Help: "Current depth of the refresh queue",
+		},
+		[]string{"hub", "name"},
+	)
+
+	RefreshQueueAddsMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ksm_refresh_queue_adds_total",
+			Help: "Total number of refresh requests handled by the refresh queue",
+		},
+		[]string{"hub", "name"},
+	)
+
+	RefreshQueueLatencyMetric = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ksm_refresh_queue_latency_seconds",
+			Help:    "How long in seconds a refresh request stays in the refresh queue before being processed",
+			Buckets: prometheus.ExponentialBuckets(10e-6, 10, 8),
+		},
+		[]string{"hub", "name"},
+	)
+
+	RefreshQueueWorkDurationMetric = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ksm_refresh_queue_work_duration_seconds",
+			Help:    "How long in seconds processing a refresh request takes",
+			Buckets: prometheus.ExponentialBuckets(10e-6, 10, 8),
+		},
+		[]string{"hub", "name"},
+	)
+
+	RefreshQueueUnfinishedWorkMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ksm_refresh_queue_unfinished_work_seconds",
+			Help: "How many seconds of work has been done by in-progress refresh requests",
+		},
+		[]string{"hub", "name"},
+	)
+
+	RefreshQueueLongestRunningMetric = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ksm_refresh_queue_longest_running_processor_seconds",
+			Help: "How many seconds the longest running refresh request has been running",
+		},
+		[]string{"hub", "name"},
+	)
+
+	RefreshQueueRetriesMetric = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ksm_refresh_queue_retries_total",
+			Help: "Total number of retries handled by the refresh queue",
+		},
+		[]string{"hub", "name"},
+	)
+)
+
+// RefreshQueueMetrics returns the self metrics of the refresh queue, which are
+// expected to be registered on the telemetry registry.
+func RefreshQueueMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		RefreshQueueDepthMetric,
+		RefreshQueueAddsMetric,
+		RefreshQueueLatencyMetric,
+		RefreshQueueWorkDurationMetric,
+		RefreshQueueUnfinishedWorkMetric,
+		RefreshQueueLongestRunningMetric,
+		RefreshQueueRetriesMetric,
+	}
+}
+
+// refreshQueueMetricsProvider implements the workqueue.MetricsProvider interface
+// with the prometheus metrics above, labeled with the hub of the queue.
+type refreshQueueMetricsProvider struct {
+	hub string
+}
+
+func (p refreshQueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return RefreshQueueDepthMetric.WithLabelValues(p.hub, name)
+}
+
+func (p refreshQueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return RefreshQueueAddsMetric.WithLabelValues(p.hub, name)
+}
+
+func (p refreshQueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return RefreshQueueLatencyMetric.WithLabelValues(p.hub, name)
+}
+
+func (p refreshQueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return RefreshQueueWorkDurationMetric.WithLabelValues(p.hub, name)
+}
+
+func (p refreshQueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return RefreshQueueUnfinishedWorkMetric.WithLabelValues(p.hub, name)
+}
+
+func (p refreshQueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return RefreshQueueLongestRunningMetric.WithLabelValues(p.hub, name)
+}
+
+func (p refreshQueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return RefreshQueueRetriesMetric.WithLabelValues(p.hub, name)
+}
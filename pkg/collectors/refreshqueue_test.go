@@ -0,0 +1,134 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_RefreshQueue(t *testing.T) {
+	tests := []struct {
+		name        string
+		requests    []refreshRequest
+		failures    int
+		wantCalls   map[refreshRequest]int
+		wantPending int
+	}{
+		{
+			name: "deduplicate requests",
+			requests: []refreshRequest{
+				{target: refreshManagedCluster, clusterName: "cluster1"},
+				{target: refreshManagedCluster, clusterName: "cluster1"},
+				{target: refreshManagedCluster, clusterName: "cluster1"},
+			},
+			wantCalls: map[refreshRequest]int{
+				{target: refreshManagedCluster, clusterName: "cluster1"}: 1,
+			},
+		},
+		{
+			name: "different targets of a cluster",
+			requests: []refreshRequest{
+				{target: refreshManagedClusterAddOns, clusterName: "cluster1"},
+				{target: refreshManifestWorks, clusterName: "cluster1"},
+				{target: refreshManagedCluster, clusterName: "cluster2"},
+			},
+			wantCalls: map[refreshRequest]int{
+				{target: refreshManagedClusterAddOns, clusterName: "cluster1"}: 1,
+				{target: refreshManifestWorks, clusterName: "cluster1"}:        1,
+				{target: refreshManagedCluster, clusterName: "cluster2"}:       1,
+			},
+		},
+		{
+			name: "retry on failure",
+			requests: []refreshRequest{
+				{target: refreshManagedCluster, clusterName: "cluster1"},
+			},
+			failures: 2,
+			wantCalls: map[refreshRequest]int{
+				{target: refreshManagedCluster, clusterName: "cluster1"}: 3,
+			},
+		},
+		{
+			name: "give up after max retries",
+			requests: []refreshRequest{
+				{target: refreshManagedCluster, clusterName: "cluster1"},
+			},
+			failures: maxRefreshRetries + 10,
+			wantCalls: map[refreshRequest]int{
+				{target: refreshManagedCluster, clusterName: "cluster1"}: maxRefreshRetries + 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRefreshQueue(tt.name)
+
+			mutex := sync.Mutex{}
+			calls := map[refreshRequest]int{}
+			for _, target := range []refreshTarget{refreshManagedCluster, refreshManagedClusterAddOns, refreshManifestWorks} {
+				target := target
+				q.AddHandler(target, func(clusterName string) error {
+					mutex.Lock()
+					defer mutex.Unlock()
+					req := refreshRequest{target: target, clusterName: clusterName}
+					calls[req]++
+					if calls[req] <= tt.failures {
+						return fmt.Errorf("failed to refresh %v", req)
+					}
+					return nil
+				})
+			}
+
+			// enqueue all requests before any worker starts to make sure they are deduplicated
+			for _, req := range tt.requests {
+				q.Enqueue(req.target, req.clusterName)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go q.Run(ctx, 2)
+
+			total := 0
+			for _, n := range tt.wantCalls {
+				total += n
+			}
+			err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				actual := 0
+				for _, n := range calls {
+					actual += n
+				}
+				return actual >= total && q.Len() == 0, nil
+			})
+			if err != nil {
+				t.Fatalf("timeout waiting for refresh requests to be processed: %v", err)
+			}
+
+			// wait a bit longer to catch the unexpected calls
+			time.Sleep(100 * time.Millisecond)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for req, want := range tt.wantCalls {
+				if calls[req] != want {
+					t.Errorf("expected %d calls for %v, but got %d", want, req, calls[req])
+				}
+			}
+			if len(calls) != len(tt.wantCalls) {
+				t.Errorf("expected calls %v, but got %v", tt.wantCalls, calls)
+			}
+			// every call but the last one of a request is retried
+			if got := metricValue(t, RefreshQueueRetriesMetric.WithLabelValues(tt.name, refreshQueueName)).GetCounter().GetValue(); got != float64(total-len(tt.wantCalls)) {
+				t.Errorf("expected %d retries of the queue of hub %s, but got %v", total-len(tt.wantCalls), tt.name, got)
+			}
+		})
+	}
+}
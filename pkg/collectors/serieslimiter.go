@@ -0,0 +1,83 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+)
+
+// seriesLimiter counts the series of the metric families of a collector, and
+// limits them per family and for the whole collector. The series are counted
+// by the stores of the collector once generated, so the limits cap the size
+// of the stores and of the scrapes.
+type seriesLimiter struct {
+	hub       string
+	collector string
+	// familyLimits are the limits of the families by name, overriding defaultFamilyLimit
+	familyLimits       map[string]int
+	defaultFamilyLimit int
+	collectorLimit     int
+
+	// Protects familySeries and series
+	mutex sync.Mutex
+	// familySeries are the numbers of series of the families by name
+	familySeries map[string]int
+	// series is the number of series of all families
+	series int
+}
+
+// newSeriesLimiter returns a new seriesLimiter of the collector of a hub with
+// the given limits. A limit of 0 does not limit the series.
+func newSeriesLimiter(hub, collector string, limits config.LimitsConfig) *seriesLimiter {
+	return &seriesLimiter{
+		hub:                hub,
+		collector:          collector,
+		familyLimits:       limits.Families,
+		defaultFamilyLimit: limits.SeriesPerFamily,
+		collectorLimit:     limits.Collectors[collector],
+		familySeries:       map[string]int{},
+	}
+}
+
+// admit returns the number of series of a family an object may have, given it
+// has current series, had dropped series and requests requested series. The
+// series beyond the limits are dropped, and only the ones beyond the series
+// the object had dropped are counted, so the series dropped again on every
+// update of the object are counted once.
+func (l *seriesLimiter) admit(family string, current, dropped, requested int) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	otherFamilySeries := l.familySeries[family] - current
+	otherSeries := l.series - current
+
+	admitted := requested
+	familyLimit, ok := l.familyLimits[family]
+	if !ok {
+		familyLimit = l.defaultFamilyLimit
+	}
+	if familyLimit > 0 && otherFamilySeries+admitted > familyLimit {
+		admitted = familyLimit - otherFamilySeries
+	}
+	if l.collectorLimit > 0 && otherSeries+admitted > l.collectorLimit {
+		admitted = l.collectorLimit - otherSeries
+	}
+	if admitted < 0 {
+		admitted = 0
+	}
+
+	l.familySeries[family] = otherFamilySeries + admitted
+	l.series = otherSeries + admitted
+	FamilySeriesMetric.WithLabelValues(l.hub, l.collector, family).Set(float64(l.familySeries[family]))
+
+	if newlyDropped := requested - admitted - dropped; newlyDropped > 0 {
+		klog.V(2).Infof("Drop %d series of metric family %s since the series limit of collector %s is reached",
+			newlyDropped, family, l.collector)
+		DroppedSeriesTotalMetric.WithLabelValues(l.hub, l.collector, family).Add(float64(newlyDropped))
+	}
+	return admitted
+}
@@ -0,0 +1,196 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+)
+
+func Test_SeriesLimiter(t *testing.T) {
+	type admission struct {
+		family                      string
+		current, dropped, requested int
+		want                        int
+	}
+	tests := []struct {
+		name        string
+		collector   string
+		limits      config.LimitsConfig
+		admissions  []admission
+		wantSeries  map[string]float64
+		wantDropped map[string]float64
+	}{
+		{
+			name:      "no limit",
+			collector: "test-no-limit",
+			admissions: []admission{
+				{family: "a", requested: 10, want: 10},
+				{family: "a", current: 10, requested: 20, want: 20},
+			},
+			wantSeries:  map[string]float64{"a": 20},
+			wantDropped: map[string]float64{"a": 0},
+		},
+		{
+			name:      "family limits",
+			collector: "test-family-limits",
+			limits: config.LimitsConfig{
+				SeriesPerFamily: 3,
+				Families:        map[string]int{"b": 5},
+			},
+			admissions: []admission{
+				{family: "a", requested: 2, want: 2},
+				{family: "a", requested: 2, want: 1},
+				{family: "b", requested: 4, want: 4},
+				// an object keeps its series within the limit
+				{family: "a", current: 2, requested: 2, want: 2},
+				// the series of a released object are available again
+				{family: "b", current: 4, requested: 0, want: 0},
+				{family: "b", requested: 6, want: 5},
+			},
+			wantSeries:  map[string]float64{"a": 3, "b": 5},
+			wantDropped: map[string]float64{"a": 1, "b": 1},
+		},
+		{
+			name:      "collector limit",
+			collector: "test-collector-limit",
+			limits: config.LimitsConfig{
+				Collectors: map[string]int{"test-collector-limit": 4},
+			},
+			admissions: []admission{
+				{family: "a", requested: 3, want: 3},
+				{family: "b", requested: 3, want: 1},
+				{family: "b", requested: 1, want: 0},
+			},
+			wantSeries:  map[string]float64{"a": 3, "b": 1},
+			wantDropped: map[string]float64{"a": 0, "b": 3},
+		},
+		{
+			name:      "series dropped again",
+			collector: "test-dropped-again",
+			limits:    config.LimitsConfig{SeriesPerFamily: 2},
+			admissions: []admission{
+				{family: "a", requested: 4, want: 2},
+				// the object updated with the same series drops them again
+				{family: "a", current: 2, dropped: 2, requested: 4, want: 2},
+				{family: "a", current: 2, dropped: 2, requested: 5, want: 2},
+			},
+			wantSeries:  map[string]float64{"a": 2},
+			wantDropped: map[string]float64{"a": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newSeriesLimiter("hub1", tt.collector, tt.limits)
+			for i, a := range tt.admissions {
+				if got := limiter.admit(a.family, a.current, a.dropped, a.requested); got != a.want {
+					t.Errorf("admission %d: admit() = %d, want %d", i, got, a.want)
+				}
+			}
+			for family, want := range tt.wantSeries {
+				if got := metricValue(t, FamilySeriesMetric.WithLabelValues("hub1", tt.collector, family)).GetGauge().GetValue(); got != want {
+					t.Errorf("expected %v series of family %s, got %v", want, family, got)
+				}
+			}
+			for family, want := range tt.wantDropped {
+				if got := metricValue(t, DroppedSeriesTotalMetric.WithLabelValues("hub1", tt.collector, family)).GetCounter().GetValue(); got != want {
+					t.Errorf("expected %v dropped series of family %s, got %v", want, family, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_ClusterMetricsStore_SeriesLimit(t *testing.T) {
+	headers := []string{"# HELP acm_managed_cluster_labels Managed cluster labels\n# TYPE acm_managed_cluster_labels gauge"}
+	// generate a series per label of the managed cluster
+	generateFunc := func(obj interface{}) []metricsstore.FamilyByteSlicer {
+		mc := obj.(*mcv1.ManagedCluster)
+		family := &metric.Family{Name: "acm_managed_cluster_labels"}
+		for _, key := range []string{"a", "b", "c"} {
+			if value, ok := mc.Labels[key]; ok {
+				family.Metrics = append(family.Metrics, &metric.Metric{
+					LabelKeys:   []string{"managed_cluster_name", "key", "value"},
+					LabelValues: []string{mc.Name, key, value},
+					Value:       1,
+				})
+			}
+		}
+		return []metricsstore.FamilyByteSlicer{family}
+	}
+	newCluster := func(name string, labels map[string]string) *mcv1.ManagedCluster {
+		return &mcv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: name, UID: ktypes.UID("uid-" + name), Labels: labels}}
+	}
+
+	limiter := newSeriesLimiter("hub1", "test-store", config.LimitsConfig{SeriesPerFamily: 3})
+	store := newClusterMetricsStore(headers, generateFunc).withSeriesLimiter(limiter)
+	for _, mc := range []*mcv1.ManagedCluster{
+		newCluster("cluster1", map[string]string{"a": "1", "b": "1"}),
+		newCluster("cluster2", map[string]string{"a": "2", "b": "2", "c": "2"}),
+	} {
+		if err := store.Add(mc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write := func() string {
+		buf := new(bytes.Buffer)
+		store.WriteAll(buf)
+		return buf.String()
+	}
+	for _, series := range []string{
+		`acm_managed_cluster_labels{managed_cluster_name="cluster1",key="a",value="1"} 1`,
+		`acm_managed_cluster_labels{managed_cluster_name="cluster1",key="b",value="1"} 1`,
+		`acm_managed_cluster_labels{managed_cluster_name="cluster2",key="a",value="2"} 1`,
+	} {
+		if !bytes.Contains([]byte(write()), []byte(series)) {
+			t.Errorf("expected %s in\n%s", series, write())
+		}
+	}
+	if got := bytes.Count([]byte(write()), []byte("\nacm_managed_cluster_labels{")); got != 3 {
+		t.Errorf("expected 3 series, got %d in\n%s", got, write())
+	}
+	if got := metricValue(t, DroppedSeriesTotalMetric.WithLabelValues("hub1", "test-store", "acm_managed_cluster_labels")).GetCounter().GetValue(); got != 2 {
+		t.Errorf("expected 2 dropped series, got %v", got)
+	}
+
+	// the series dropped again on update or relist are not counted again
+	if err := store.Update(newCluster("cluster2", map[string]string{"a": "2", "b": "2", "c": "2"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Replace([]interface{}{
+		newCluster("cluster1", map[string]string{"a": "1", "b": "1"}),
+		newCluster("cluster2", map[string]string{"a": "2", "b": "2", "c": "2"}),
+	}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := metricValue(t, DroppedSeriesTotalMetric.WithLabelValues("hub1", "test-store", "acm_managed_cluster_labels")).GetCounter().GetValue(); got != 2 {
+		t.Errorf("expected 2 dropped series once updated and relisted, got %v", got)
+	}
+
+	// the series of a deleted object are released
+	if err := store.Delete(newCluster("cluster1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(newCluster("cluster2", map[string]string{"a": "2", "b": "2", "c": "2"})); err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.Count([]byte(write()), []byte("\nacm_managed_cluster_labels{")); got != 3 {
+		t.Errorf("expected 3 series, got %d in\n%s", got, write())
+	}
+
+	if err := store.Replace(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := metricValue(t, FamilySeriesMetric.WithLabelValues("hub1", "test-store", "acm_managed_cluster_labels")).GetGauge().GetValue(); got != 0 {
+		t.Errorf("expected no series once replaced, got %v", got)
+	}
+}
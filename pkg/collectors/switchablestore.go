@@ -0,0 +1,154 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"io"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// switchableStore implements the k8s.io/client-go/tools/cache.Store
+// interface. It wraps a store which can be enabled and disabled at runtime.
+// The objects are passed to the wrapped store only while it is enabled, and
+// the wrapped store is cleared once it is disabled. If the wrapped store is a
+// MetricsCollector as well, its metrics are written only while it is enabled.
+type switchableStore struct {
+	// Protects enabled, and serializes the updates of the wrapped store with
+	// the switches, so no object is kept by a disabled store.
+	mutex sync.RWMutex
+
+	enabled bool
+	store   cache.Store
+}
+
+// newSwitchableStore returns a new switchableStore
+func newSwitchableStore(store cache.Store, enabled bool) *switchableStore {
+	return &switchableStore{
+		enabled: enabled,
+		store:   store,
+	}
+}
+
+// Enabled returns true if the wrapped store is enabled.
+func (s *switchableStore) Enabled() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.enabled
+}
+
+// SetEnabled enables or disables the wrapped store. It returns true if the
+// state is changed.
+func (s *switchableStore) SetEnabled(enabled bool) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.enabled == enabled {
+		return false, nil
+	}
+	s.enabled = enabled
+	if !enabled {
+		return true, s.store.Replace(nil, "")
+	}
+	return true, nil
+}
+
+// Add implements the Add method of the store interface.
+func (s *switchableStore) Add(obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.enabled {
+		return nil
+	}
+	return s.store.Add(obj)
+}
+
+// Update implements the Update method of the store interface.
+func (s *switchableStore) Update(obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.enabled {
+		return nil
+	}
+	return s.store.Update(obj)
+}
+
+// Delete implements the Delete method of the store interface.
+func (s *switchableStore) Delete(obj interface{}) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.enabled {
+		return nil
+	}
+	return s.store.Delete(obj)
+}
+
+// List implements the List method of the store interface.
+func (s *switchableStore) List() []interface{} {
+	return nil
+}
+
+// ListKeys implements the ListKeys method of the store interface.
+func (s *switchableStore) ListKeys() []string {
+	return nil
+}
+
+// Get implements the Get method of the store interface.
+func (s *switchableStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// GetByKey implements the GetByKey method of the store interface.
+func (s *switchableStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// Replace implements the Replace method of the store interface.
+func (s *switchableStore) Replace(list []interface{}, resourceVersion string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.enabled {
+		return nil
+	}
+	return s.store.Replace(list, resourceVersion)
+}
+
+// Resync implements the Resync method of the store interface.
+func (s *switchableStore) Resync() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.enabled {
+		return nil
+	}
+	return s.store.Resync()
+}
+
+// WriteAll writes the metrics of the wrapped store if it is enabled.
+func (s *switchableStore) WriteAll(w io.Writer) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if collector, ok := s.store.(MetricsCollector); ok && s.enabled {
+		collector.WriteAll(w)
+	}
+}
+
+// WriteFiltered writes the metrics of the managed clusters selected by the
+// filter of the wrapped store if it is enabled.
+func (s *switchableStore) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if collector, ok := s.store.(MetricsCollector); ok && s.enabled {
+		collector.WriteFiltered(w, filter)
+	}
+}
+
+// CollectFamilies collects the metric families of the managed clusters
+// selected by the filter of the wrapped store if it is enabled.
+func (s *switchableStore) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if collector, ok := s.store.(MetricsCollector); ok && s.enabled {
+		collector.CollectFamilies(filter, collect)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	metricsstore "k8s.io/kube-state-metrics/pkg/metrics_store"
+)
+
+func newTestClusterMetricsStore() *clusterMetricsStore {
+	headers := []string{`# HELP acm_managed_cluster_import_timestamp Import timestamp
+# TYPE acm_managed_cluster_import_timestamp gauge`}
+	return newClusterMetricsStore(headers, func(obj interface{}) []metricsstore.FamilyByteSlicer {
+		return []metricsstore.FamilyByteSlicer{
+			&metric.Family{
+				Name: "acm_managed_cluster_import_timestamp",
+				Metrics: []*metric.Metric{
+					{
+						LabelKeys:   []string{"managed_cluster_name"},
+						LabelValues: []string{obj.(metav1.Object).GetName()},
+						Value:       1,
+					},
+				},
+			},
+		}
+	})
+}
+
+func Test_SwitchableStore(t *testing.T) {
+	const (
+		headers = `# HELP acm_managed_cluster_import_timestamp Import timestamp
+# TYPE acm_managed_cluster_import_timestamp gauge
+`
+		cluster1 = `acm_managed_cluster_import_timestamp{managed_cluster_name="cluster1"} 1
+`
+		cluster2 = `acm_managed_cluster_import_timestamp{managed_cluster_name="cluster2"} 1
+`
+	)
+
+	store := newSwitchableStore(newTestClusterMetricsStore(), false)
+	writeAll := func() string {
+		buf := new(bytes.Buffer)
+		store.WriteAll(buf)
+		return buf.String()
+	}
+
+	// a disabled store neither keeps nor writes metrics
+	if err := store.Add(newTestManagedCluster("cluster1")); err != nil {
+		t.Fatal(err)
+	}
+	if got := writeAll(); got != "" {
+		t.Errorf("expected no metrics, but got\n%s", got)
+	}
+
+	if changed, err := store.SetEnabled(true); err != nil || !changed {
+		t.Fatalf("expected the store to be enabled, but got %v %v", changed, err)
+	}
+	if changed, _ := store.SetEnabled(true); changed {
+		t.Errorf("expected the store to be enabled once")
+	}
+	if err := store.Add(newTestManagedCluster("cluster2")); err != nil {
+		t.Fatal(err)
+	}
+	if got := writeAll(); got != headers+cluster2 {
+		t.Errorf("expected\n%s\nbut got\n%s", headers+cluster2, got)
+	}
+
+	// a disabled store is cleared
+	if changed, err := store.SetEnabled(false); err != nil || !changed {
+		t.Fatalf("expected the store to be disabled, but got %v %v", changed, err)
+	}
+	if _, err := store.SetEnabled(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := writeAll(); got != headers {
+		t.Errorf("expected\n%s\nbut got\n%s", headers, got)
+	}
+
+	// no duplicate series once the objects are added again
+	for i := 0; i < 2; i++ {
+		if err := store.Replace([]interface{}{newTestManagedCluster("cluster1")}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := writeAll(); got != headers+cluster1 {
+		t.Errorf("expected\n%s\nbut got\n%s", headers+cluster1, got)
+	}
+}
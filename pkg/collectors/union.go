@@ -0,0 +1,270 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+const (
+	// hubClusterIDLabel is the label identifying the hub of the metrics served
+	// by a union collector.
+	hubClusterIDLabel = "hub_cluster_id"
+	// hubTypeLabel is the label with the type of the hub of the metrics served
+	// by a union collector.
+	hubTypeLabel = "hub_type"
+)
+
+var (
+	HubRenderDurationMetric = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ksm_hub_render_duration_seconds",
+			Help:    "Time to render the metrics of a hub served with the metrics of other hubs",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"hub"},
+	)
+
+	hubSyncedDesc = prometheus.NewDesc(
+		"ksm_hub_synced",
+		"Whether every watched resource of a hub has completed its initial list, so its metrics are served",
+		[]string{"hub", hubClusterIDLabel}, nil,
+	)
+)
+
+// Hub is a hub whose metrics are served with the metrics of other hubs.
+type Hub struct {
+	// Name identifies the hub in the self metrics, such as the name of its kubeconfig
+	Name string
+	// ClusterID returns the ID of the hub cluster, empty until it is read from the hub
+	ClusterID func() string
+	// Type returns the type of the hub, empty until it is known
+	Type func() string
+	// Collectors returns the collectors of the hub, nil until they are built
+	Collectors func() []MetricsCollector
+	// HasSynced reports whether every watched resource of the hub has completed its initial list
+	HasSynced func() bool
+}
+
+// clusterID returns the ID of the hub cluster, or an empty ID if it is unknown.
+func (h Hub) clusterID() string {
+	if h.ClusterID == nil {
+		return ""
+	}
+	return h.ClusterID()
+}
+
+// hubType returns the type of the hub, or an empty type if it is unknown.
+func (h Hub) hubType() string {
+	if h.Type == nil {
+		return ""
+	}
+	return h.Type()
+}
+
+// collectors returns the collectors of the hub, or nil if they are not built.
+func (h Hub) collectors() []MetricsCollector {
+	if h.Collectors == nil {
+		return nil
+	}
+	return h.Collectors()
+}
+
+// unionCollector serves the union of the metrics of several hubs. The
+// families of the hubs are merged, so the header of each family is written
+// once, and the hub_cluster_id and hub_type labels are added to the series
+// without them, so the series of the hubs do not collide and are labeled
+// consistently. The hubs which have not synced are skipped.
+type unionCollector struct {
+	hubs []Hub
+}
+
+// NewUnionCollector returns a collector serving the union of the metrics of the hubs.
+func NewUnionCollector(hubs []Hub) MetricsCollector {
+	return &unionCollector{hubs: hubs}
+}
+
+// unionFlushSize is the size of the rendered metrics of a family written at
+// once.
+const unionFlushSize = 64 * 1024
+
+// unionFamily is a metric family merged from the hubs.
+type unionFamily struct {
+	name       string
+	help       string
+	metricType metric.Type
+	hubs       []hubFamily
+}
+
+// hubFamily is the metrics of a family of a hub, with the labels identifying
+// the hub.
+type hubFamily struct {
+	labelKeys   []string
+	labelValues []string
+	// metrics are shared with the stores of the hub and must not be modified
+	metrics [][]*metric.Metric
+}
+
+func (u *unionCollector) WriteAll(w io.Writer) {
+	u.WriteFiltered(w, nil)
+}
+
+// WriteFiltered writes the merged families of the synced hubs. The metrics
+// are rendered with the labels of their hub one family at a time, so the
+// metrics of the hubs are not buffered.
+func (u *unionCollector) WriteFiltered(w io.Writer, filter ClusterFilter) {
+	sb := &strings.Builder{}
+	labeled := &metric.Metric{}
+	flush := func() bool {
+		if _, err := io.WriteString(w, sb.String()); err != nil {
+			klog.Errorf("cannot write the metrics: %v", err)
+			return false
+		}
+		sb.Reset()
+		return true
+	}
+
+	for _, family := range u.families(filter) {
+		sb.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		sb.WriteString("# TYPE " + family.name + " " + string(family.metricType) + "\n")
+		for _, hub := range family.hubs {
+			for _, metrics := range hub.metrics {
+				for _, m := range metrics {
+					withHubLabels(labeled, m, hub.labelKeys, hub.labelValues)
+					sb.WriteString(family.name)
+					labeled.Write(sb)
+				}
+				if sb.Len() >= unionFlushSize && !flush() {
+					return
+				}
+			}
+		}
+		if !flush() {
+			return
+		}
+	}
+}
+
+// CollectFamilies collects the metric families of the synced hubs. The
+// families of the hubs are merged by name, and the hub_cluster_id and
+// hub_type labels are added to the metrics without them.
+func (u *unionCollector) CollectFamilies(filter ClusterFilter, collect func(family *Family)) {
+	for _, family := range u.families(filter) {
+		f := &Family{Name: family.name, Help: family.help, Type: family.metricType}
+		for _, hub := range family.hubs {
+			for _, metrics := range hub.metrics {
+				labeled := make([]*metric.Metric, len(metrics))
+				for i, m := range metrics {
+					labeled[i] = &metric.Metric{}
+					withHubLabels(labeled[i], m, hub.labelKeys, hub.labelValues)
+				}
+				f.Metrics = append(f.Metrics, labeled)
+			}
+		}
+		collect(f)
+	}
+}
+
+// families returns the metric families of the synced hubs merged by name, in
+// the order they are first collected. The header of a family is the one of
+// the first hub.
+func (u *unionCollector) families(filter ClusterFilter) []*unionFamily {
+	families := []*unionFamily{}
+	index := map[string]*unionFamily{}
+
+	for _, hub := range u.hubs {
+		if hub.HasSynced != nil && !hub.HasSynced() {
+			klog.V(4).Infof("Skip the metrics of hub %s since it has not synced", hub.Name)
+			continue
+		}
+
+		start := time.Now()
+		labelKeys := []string{hubClusterIDLabel, hubTypeLabel}
+		labelValues := []string{hub.clusterID(), hub.hubType()}
+		for _, c := range hub.collectors() {
+			c.CollectFamilies(filter, func(family *Family) {
+				f, ok := index[family.Name]
+				if !ok {
+					f = &unionFamily{name: family.Name, help: family.Help, metricType: family.Type}
+					index[family.Name] = f
+					families = append(families, f)
+				}
+				f.hubs = append(f.hubs, hubFamily{labelKeys: labelKeys, labelValues: labelValues, metrics: family.Metrics})
+			})
+		}
+		HubRenderDurationMetric.WithLabelValues(hub.Name).Observe(time.Since(start).Seconds())
+	}
+	return families
+}
+
+// withHubLabels sets labeled to the metric with the labels of its hub first,
+// except the labels the metric already has. The metric is not modified, since
+// it is shared with the stores.
+func withHubLabels(labeled, m *metric.Metric, labelKeys, labelValues []string) {
+	labeled.LabelKeys = labeled.LabelKeys[:0]
+	labeled.LabelValues = labeled.LabelValues[:0]
+	for i, key := range labelKeys {
+		if !hasLabel(m, key) {
+			labeled.LabelKeys = append(labeled.LabelKeys, key)
+			labeled.LabelValues = append(labeled.LabelValues, labelValues[i])
+		}
+	}
+	labeled.LabelKeys = append(labeled.LabelKeys, m.LabelKeys...)
+	labeled.LabelValues = append(labeled.LabelValues, m.LabelValues...)
+	labeled.Value = m.Value
+}
+
+// hasLabel returns true if the metric has the label.
+func hasLabel(m *metric.Metric, label string) bool {
+	for _, key := range m.LabelKeys {
+		if key == label {
+			return true
+		}
+	}
+	return false
+}
+
+// hubHealthCollector reports whether each hub has synced.
+type hubHealthCollector struct {
+	hubs []Hub
+}
+
+// NewHubHealthCollector returns a prometheus collector reporting whether each
+// hub has synced with the ksm_hub_synced metric.
+func NewHubHealthCollector(hubs []Hub) prometheus.Collector {
+	return &hubHealthCollector{hubs: hubs}
+}
+
+func (c *hubHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hubSyncedDesc
+}
+
+func (c *hubHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, hub := range c.hubs {
+		synced := 0.0
+		if hub.HasSynced == nil || hub.HasSynced() {
+			synced = 1
+		}
+		ch <- prometheus.MustNewConstMetric(hubSyncedDesc, prometheus.GaugeValue, synced, hub.Name, hub.clusterID())
+	}
+}
+
+// AnyHubSynced returns a function reporting whether any of the hubs has
+// synced, so the metrics of the synced hubs are served while the other hubs
+// are unreachable.
+func AnyHubSynced(hubs []Hub) func() bool {
+	return func() bool {
+		for _, hub := range hubs {
+			if hub.HasSynced == nil || hub.HasSynced() {
+				return true
+			}
+		}
+		return false
+	}
+}
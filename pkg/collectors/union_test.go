@@ -0,0 +1,176 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+// familyCollector collects the same metric families.
+type familyCollector []*Family
+
+func (c familyCollector) WriteAll(w io.Writer) {}
+
+func (c familyCollector) WriteFiltered(w io.Writer, _ ClusterFilter) {}
+
+func (c familyCollector) CollectFamilies(_ ClusterFilter, collect func(family *Family)) {
+	for _, family := range c {
+		collect(family)
+	}
+}
+
+// newTestHub returns a MCE hub with the given collectors, whose ID is named
+// after it.
+func newTestHub(name string, hasSynced func() bool, collectors ...MetricsCollector) Hub {
+	return Hub{
+		Name:       name,
+		ClusterID:  func() string { return name + "-id" },
+		Type:       func() string { return "mce" },
+		Collectors: func() []MetricsCollector { return collectors },
+		HasSynced:  hasSynced,
+	}
+}
+
+// newTestClusterFamilies returns the families of the managed cluster
+// local-cluster of a hub.
+func newTestClusterFamilies(hubClusterID string) familyCollector {
+	return familyCollector{
+		{
+			Name: "acm_managed_cluster_info",
+			Help: "Managed cluster information",
+			Type: metric.Gauge,
+			Metrics: [][]*metric.Metric{{{
+				LabelKeys:   []string{"hub_cluster_id", "hub_type", "managed_cluster_id"},
+				LabelValues: []string{hubClusterID, "mce", "local-cluster"},
+				Value:       1,
+			}}},
+		},
+		{
+			Name: "acm_managed_cluster_status_condition",
+			Help: "Managed cluster status condition",
+			Type: metric.Gauge,
+			Metrics: [][]*metric.Metric{{{
+				LabelKeys:   []string{"managed_cluster_id", "condition", "status"},
+				LabelValues: []string{"local-cluster", "Available", "true"},
+				Value:       1,
+			}}},
+		},
+		{
+			Name:    "acm_managed_cluster_count",
+			Help:    "Managed cluster count",
+			Type:    metric.Gauge,
+			Metrics: [][]*metric.Metric{{{Value: 1}}},
+		},
+	}
+}
+
+func Test_UnionCollector(t *testing.T) {
+	addons := familyCollector{{
+		Name: "acm_managed_cluster_addon_status_condition",
+		Help: "Managed cluster add-on status condition",
+		Type: metric.Gauge,
+	}}
+	synced := false
+
+	union := NewUnionCollector([]Hub{
+		newTestHub("hub1", nil, newTestClusterFamilies("hub1-id"), addons),
+		newTestHub("hub2", nil, newTestClusterFamilies("hub2-id"), addons),
+		newTestHub("hub3", func() bool { return synced }, newTestClusterFamilies("hub3-id")),
+	})
+
+	want := `# HELP acm_managed_cluster_info Managed cluster information
+# TYPE acm_managed_cluster_info gauge
+acm_managed_cluster_info{hub_cluster_id="hub1-id",hub_type="mce",managed_cluster_id="local-cluster"} 1
+acm_managed_cluster_info{hub_cluster_id="hub2-id",hub_type="mce",managed_cluster_id="local-cluster"} 1
+# HELP acm_managed_cluster_status_condition Managed cluster status condition
+# TYPE acm_managed_cluster_status_condition gauge
+acm_managed_cluster_status_condition{hub_cluster_id="hub1-id",hub_type="mce",managed_cluster_id="local-cluster",condition="Available",status="true"} 1
+acm_managed_cluster_status_condition{hub_cluster_id="hub2-id",hub_type="mce",managed_cluster_id="local-cluster",condition="Available",status="true"} 1
+# HELP acm_managed_cluster_count Managed cluster count
+# TYPE acm_managed_cluster_count gauge
+acm_managed_cluster_count{hub_cluster_id="hub1-id",hub_type="mce"} 1
+acm_managed_cluster_count{hub_cluster_id="hub2-id",hub_type="mce"} 1
+# HELP acm_managed_cluster_addon_status_condition Managed cluster add-on status condition
+# TYPE acm_managed_cluster_addon_status_condition gauge
+`
+	buf := &bytes.Buffer{}
+	union.WriteAll(buf)
+	if buf.String() != want {
+		t.Errorf("expected the metrics of the synced hubs:\n%s\ngot:\n%s", want, buf.String())
+	}
+
+	// the metrics collected as families are labeled like the rendered ones
+	buf.Reset()
+	if err := WriteMetrics(buf, JSONContentType, []MetricsCollector{union}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name":"acm_managed_cluster_count","help":"Managed cluster count","type":"gauge","metrics":[{"labels":{"hub_cluster_id":"hub1-id","hub_type":"mce"},"value":1},{"labels":{"hub_cluster_id":"hub2-id","hub_type":"mce"},"value":1}]}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %s in:\n%s", want, buf.String())
+	}
+
+	synced = true
+	buf.Reset()
+	union.WriteFiltered(buf, func(string) bool { return true })
+	for _, want := range []string{
+		`acm_managed_cluster_info{hub_cluster_id="hub3-id",hub_type="mce",managed_cluster_id="local-cluster"} 1`,
+		`acm_managed_cluster_count{hub_cluster_id="hub3-id",hub_type="mce"} 1`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("expected %q once hub3 has synced, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func Test_UnionCollector_largeFamily(t *testing.T) {
+	// a family larger than a scanner line or the flush size is written whole
+	value := strings.Repeat("x", 2*1024*1024)
+	family := &Family{Name: "test_large", Type: metric.Gauge}
+	for i := 0; i < 3; i++ {
+		family.Metrics = append(family.Metrics, []*metric.Metric{{
+			LabelKeys:   []string{"value"},
+			LabelValues: []string{value},
+			Value:       float64(i),
+		}})
+	}
+
+	buf := &bytes.Buffer{}
+	NewUnionCollector([]Hub{newTestHub("hub1", nil, familyCollector{family})}).WriteAll(buf)
+	if got := strings.Count(buf.String(), `test_large{hub_cluster_id="hub1-id",hub_type="mce",value="`+value+`"}`); got != 3 {
+		t.Errorf("expected 3 series, got %d", got)
+	}
+}
+
+func Test_HubHealthCollector(t *testing.T) {
+	hubs := []Hub{
+		newTestHub("hub1", func() bool { return true }),
+		newTestHub("hub2", func() bool { return false }),
+	}
+	if !AnyHubSynced(hubs)() {
+		t.Error("expected a hub synced")
+	}
+	if AnyHubSynced(hubs[1:])() {
+		t.Error("expected no hub synced")
+	}
+
+	ch := make(chan prometheus.Metric, len(hubs))
+	NewHubHealthCollector(hubs).Collect(ch)
+	close(ch)
+	got := map[string]float64{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			t.Fatal(err)
+		}
+		got[pb.GetLabel()[0].GetValue()] = pb.GetGauge().GetValue()
+	}
+	if got["hub1"] != 1 || got["hub2"] != 0 {
+		t.Errorf("expected hub1 synced and hub2 not synced, got %v", got)
+	}
+}
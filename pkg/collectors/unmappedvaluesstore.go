@@ -0,0 +1,86 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
+)
+
+// unmappedValuesStore implements the k8s.io/client-go/tools/cache.Store
+// interface. Instead of storing ManagedCluster objects, it forgets the
+// unmapped annotation values counted for the deleted ManagedClusters.
+type unmappedValuesStore struct {
+	unmappedValues *cluster.UnmappedValues
+}
+
+// newUnmappedValuesStore returns a new unmappedValuesStore
+func newUnmappedValuesStore(unmappedValues *cluster.UnmappedValues) *unmappedValuesStore {
+	return &unmappedValuesStore{
+		unmappedValues: unmappedValues,
+	}
+}
+
+// Add implements the Add method of the store interface.
+func (s *unmappedValuesStore) Add(obj interface{}) error {
+	return nil
+}
+
+// Update implements the Update method of the store interface.
+func (s *unmappedValuesStore) Update(obj interface{}) error {
+	return nil
+}
+
+// Delete implements the Delete method of the store interface.
+func (s *unmappedValuesStore) Delete(obj interface{}) error {
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	s.unmappedValues.Forget(o.GetName())
+	return nil
+}
+
+// List implements the List method of the store interface.
+func (s *unmappedValuesStore) List() []interface{} {
+	return nil
+}
+
+// ListKeys implements the ListKeys method of the store interface.
+func (s *unmappedValuesStore) ListKeys() []string {
+	return nil
+}
+
+// Get implements the Get method of the store interface.
+func (s *unmappedValuesStore) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// GetByKey implements the GetByKey method of the store interface.
+func (s *unmappedValuesStore) GetByKey(key string) (item interface{}, exists bool, err error) {
+	return nil, false, nil
+}
+
+// Replace implements the Replace method of the store interface. The values of
+// the ManagedClusters deleted while they were not watched are forgotten.
+func (s *unmappedValuesStore) Replace(list []interface{}, _ string) error {
+	clusters := sets.New[string]()
+	for _, o := range list {
+		obj, err := meta.Accessor(o)
+		if err != nil {
+			return err
+		}
+		clusters.Insert(obj.GetName())
+	}
+
+	s.unmappedValues.Retain(clusters)
+	return nil
+}
+
+// Resync implements the Resync method of the store interface.
+func (s *unmappedValuesStore) Resync() error {
+	return nil
+}
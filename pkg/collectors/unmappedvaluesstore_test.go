@@ -0,0 +1,62 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package collectors
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/kube-state-metrics/pkg/whiteblacklist"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators/cluster"
+)
+
+func TestBuilder_unmappedValuesStore(t *testing.T) {
+	w, _ := whiteblacklist.New(map[string]struct{}{}, map[string]struct{}{})
+	b := NewBuilder(context.TODO()).WithHubName("hub1").WithWhiteBlackList(w)
+	b.buildManagedClusterCollector()
+
+	counterValue := func() float64 {
+		return metricValue(t, cluster.UnmappedValuesTotalMetric.WithLabelValues("created_via", "unmapped-store-test")).GetCounter().GetValue()
+	}
+	newCluster := func() interface{} {
+		mc := newTestManagedCluster("cluster1")
+		mc.Annotations = map[string]string{"open-cluster-management/created-via": "unmapped-store-test"}
+		return mc
+	}
+
+	before := counterValue()
+	for _, step := range []func(interface{}) error{
+		b.composedClusterStore.Add,
+		b.composedClusterStore.Update,
+	} {
+		if err := step(newCluster()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := counterValue() - before; v != 1 {
+		t.Errorf("expected the value counted once, got %v", v)
+	}
+
+	// a deleted cluster created again is counted again
+	if err := b.composedClusterStore.Delete(newCluster()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.composedClusterStore.Add(newCluster()); err != nil {
+		t.Fatal(err)
+	}
+	if v := counterValue() - before; v != 2 {
+		t.Errorf("expected the value of the created cluster counted again, got %v", v)
+	}
+
+	// a cluster missing from a relist is forgotten
+	if err := b.composedClusterStore.Replace([]interface{}{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.composedClusterStore.Replace([]interface{}{newCluster()}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if v := counterValue() - before; v != 3 {
+		t.Errorf("expected the value of the relisted cluster counted again, got %v", v)
+	}
+}
@@ -0,0 +1,367 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigMapKey is the key of the configuration in the ConfigMap.
+	ConfigMapKey = "config.yaml"
+	// TimestampMetricsKey is the key of the ConfigMap enabling the timestamp
+	// metrics with the value "Enable". It is overridden by the enabled field
+	// of the timestamp metrics in the configuration.
+	TimestampMetricsKey = "collect-timestamp-metrics"
+)
+
+var (
+	metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// DefaultCollectors are the collectors enabled by default.
+var DefaultCollectors = []string{"managedclusters", "managedclusteraddons", "manifestworks"}
+
+// LoadFile loads the configuration from a file.
+func LoadFile(path string) (*Configuration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file %s: %v", path, err)
+	}
+	c, err := Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// LoadConfigMap loads the configuration from the config.yaml key of a ConfigMap.
+// The default configuration is returned if the ConfigMap does not have the key.
+func LoadConfigMap(cm *corev1.ConfigMap) (*Configuration, error) {
+	c := &Configuration{APIVersion: APIVersion, Kind: Kind}
+	if data, ok := cm.Data[ConfigMapKey]; ok {
+		var err error
+		if c, err = Load([]byte(data)); err != nil {
+			return nil, fmt.Errorf("invalid config in ConfigMap %s/%s: %v", cm.Namespace, cm.Name, err)
+		}
+	} else {
+		SetDefaults(c)
+	}
+
+	if value, ok := cm.Data[TimestampMetricsKey]; ok && c.TimestampMetrics.Enabled == nil {
+		enabled := value == "Enable"
+		c.TimestampMetrics.Enabled = &enabled
+	}
+	return c, nil
+}
+
+// Load decodes, defaults and validates the configuration. Unknown fields are
+// rejected.
+func Load(data []byte) (*Configuration, error) {
+	c := &Configuration{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, err
+	}
+	SetDefaults(c)
+	if errs := Validate(c); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	return c, nil
+}
+
+// SetDefaults sets the default values of the unset fields.
+func SetDefaults(c *Configuration) {
+	for _, m := range []*ValueMappingConfig{&c.ManagedClusterInfo.CreatedVia, &c.ManagedClusterInfo.ServiceName} {
+		if m.Fallback == "" {
+			m.Fallback = "Other"
+		}
+	}
+
+	if len(c.Collectors) == 0 {
+		for _, name := range DefaultCollectors {
+			c.Collectors = append(c.Collectors, CollectorConfig{Name: name})
+		}
+	}
+
+	s := &c.Serving
+	if s.Host == "" {
+		s.Host = "0.0.0.0"
+	}
+	if s.HTTPPort == 0 {
+		s.HTTPPort = 8080
+	}
+	if s.HTTPSPort == 0 {
+		s.HTTPSPort = 8443
+	}
+	if s.TelemetryHost == "" {
+		s.TelemetryHost = "0.0.0.0"
+	}
+	if s.HTTPTelemetryPort == 0 {
+		s.HTTPTelemetryPort = 8081
+	}
+	if s.HTTPSTelemetryPort == 0 {
+		s.HTTPSTelemetryPort = 8444
+	}
+	if s.Auth.CacheTTL == nil {
+		s.Auth.CacheTTL = &metav1.Duration{Duration: 2 * time.Minute}
+	}
+	if s.Auth.DenyCacheTTL == nil {
+		s.Auth.DenyCacheTTL = &metav1.Duration{Duration: 10 * time.Second}
+	}
+}
+
+// Validate returns the errors of the configuration, each naming the path of
+// the offending field.
+func Validate(c *Configuration) field.ErrorList {
+	errs := field.ErrorList{}
+	if c.APIVersion != APIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{APIVersion}))
+	}
+	if c.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{Kind}))
+	}
+
+	collectorsPath := field.NewPath("collectors")
+	names := sets.New[string]()
+	for i, collector := range c.Collectors {
+		path := collectorsPath.Index(i)
+		switch {
+		case !sets.New(DefaultCollectors...).Has(collector.Name):
+			errs = append(errs, field.NotSupported(path.Child("name"), collector.Name, DefaultCollectors))
+		case names.Has(collector.Name):
+			errs = append(errs, field.Duplicate(path.Child("name"), collector.Name))
+		}
+		names.Insert(collector.Name)
+
+		if _, err := labels.Parse(collector.Selector); err != nil {
+			errs = append(errs, field.Invalid(path.Child("selector"), collector.Selector, err.Error()))
+		}
+	}
+
+	metricsPath := field.NewPath("metrics")
+	if len(c.Metrics.Allow) > 0 && len(c.Metrics.Deny) > 0 {
+		errs = append(errs, field.Forbidden(metricsPath.Child("deny"), "allow and deny are mutually exclusive"))
+	}
+	errs = append(errs, validateNames(metricsPath.Child("allow"), c.Metrics.Allow)...)
+	errs = append(errs, validateNames(metricsPath.Child("deny"), c.Metrics.Deny)...)
+	errs = append(errs, validateLabels(field.NewPath("managedClusterLabels"), &c.ManagedClusterLabels)...)
+	errs = append(errs, validateNames(field.NewPath("managedClusterAnnotations", "allow"), c.ManagedClusterAnnotations.Allow)...)
+	errs = append(errs, validateValueMapping(field.NewPath("managedClusterInfo", "createdVia"), &c.ManagedClusterInfo.CreatedVia)...)
+	errs = append(errs, validateValueMapping(field.NewPath("managedClusterInfo", "serviceName"), &c.ManagedClusterInfo.ServiceName)...)
+
+	errs = append(errs, validateServing(field.NewPath("serving"), &c.Serving)...)
+
+	resourcesPath := field.NewPath("customResources")
+	metricNames := sets.New[string]()
+	for i := range c.CustomResources {
+		errs = append(errs, validateCustomResource(resourcesPath.Index(i), &c.CustomResources[i], metricNames)...)
+	}
+
+	errs = append(errs, validateLimits(field.NewPath("limits"), c)...)
+	return errs
+}
+
+func validateLimits(path *field.Path, c *Configuration) field.ErrorList {
+	errs := field.ErrorList{}
+	if c.Limits.SeriesPerFamily < 0 {
+		errs = append(errs, field.Invalid(path.Child("seriesPerFamily"), c.Limits.SeriesPerFamily, "must not be negative"))
+	}
+	for name, limit := range c.Limits.Families {
+		switch {
+		case !metricNameRegexp.MatchString(name):
+			errs = append(errs, field.Invalid(path.Child("families").Key(name), name, "must be a valid metric name"))
+		case limit < 0:
+			errs = append(errs, field.Invalid(path.Child("families").Key(name), limit, "must not be negative"))
+		}
+	}
+
+	collectors := sets.New(DefaultCollectors...)
+	for _, r := range c.CustomResources {
+		collectors.Insert(schema.GroupResource{Group: r.Group, Resource: r.Resource}.String())
+	}
+	for name, limit := range c.Limits.Collectors {
+		switch {
+		case !collectors.Has(name):
+			errs = append(errs, field.NotSupported(path.Child("collectors").Key(name), name, sets.List(collectors)))
+		case limit < 0:
+			errs = append(errs, field.Invalid(path.Child("collectors").Key(name), limit, "must not be negative"))
+		}
+	}
+	return errs
+}
+
+func validateCustomResource(path *field.Path, r *CustomResourceConfig, metricNames sets.Set[string]) field.ErrorList {
+	errs := field.ErrorList{}
+	if r.Version == "" {
+		errs = append(errs, field.Required(path.Child("version"), ""))
+	}
+	if r.Resource == "" {
+		errs = append(errs, field.Required(path.Child("resource"), ""))
+	}
+	if _, err := labels.Parse(r.Selector); err != nil {
+		errs = append(errs, field.Invalid(path.Child("selector"), r.Selector, err.Error()))
+	}
+	if len(r.Metrics) == 0 {
+		errs = append(errs, field.Required(path.Child("metrics"), ""))
+	}
+
+	for i, m := range r.Metrics {
+		metricPath := path.Child("metrics").Index(i)
+		switch {
+		case !metricNameRegexp.MatchString(m.Name):
+			errs = append(errs, field.Invalid(metricPath.Child("name"), m.Name, "must be a valid metric name"))
+		case metricNames.Has(m.Name):
+			errs = append(errs, field.Duplicate(metricPath.Child("name"), m.Name))
+		}
+		metricNames.Insert(m.Name)
+
+		switch m.Type {
+		case MetricTypeGauge:
+			if len(m.Path) == 0 {
+				errs = append(errs, field.Required(metricPath.Child("path"), "required by a Gauge"))
+			}
+		case MetricTypeInfo:
+			if len(m.Path) > 0 {
+				errs = append(errs, field.Forbidden(metricPath.Child("path"), "not supported by an Info"))
+			}
+		case MetricTypeStateSet:
+			if len(m.Path) == 0 {
+				errs = append(errs, field.Required(metricPath.Child("path"), "required by a StateSet"))
+			}
+			if len(m.List) == 0 {
+				errs = append(errs, field.Required(metricPath.Child("list"), "required by a StateSet"))
+			}
+			if m.LabelName != "" && !labelNameRegexp.MatchString(m.LabelName) {
+				errs = append(errs, field.Invalid(metricPath.Child("labelName"), m.LabelName, "must be a valid label name"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(metricPath.Child("type"), m.Type,
+				[]MetricType{MetricTypeGauge, MetricTypeInfo, MetricTypeStateSet}))
+		}
+
+		for name, labelPath := range m.LabelsFromPath {
+			if !labelNameRegexp.MatchString(name) {
+				errs = append(errs, field.Invalid(metricPath.Child("labelsFromPath").Key(name), name, "must be a valid label name"))
+			} else if len(labelPath) == 0 {
+				errs = append(errs, field.Required(metricPath.Child("labelsFromPath").Key(name), ""))
+			}
+		}
+	}
+	return errs
+}
+
+func validateLabels(path *field.Path, l *LabelsConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	errs = append(errs, validateNames(path.Child("allow"), l.Allow)...)
+	errs = append(errs, validateNames(path.Child("deny"), l.Deny)...)
+
+	for i, rename := range l.Renames {
+		renamePath := path.Child("renames").Index(i)
+		if rename.From == "" {
+			errs = append(errs, field.Required(renamePath.Child("from"), ""))
+		}
+		switch {
+		case rename.To == "*":
+			// the * may match an empty string, which is not a label name
+			errs = append(errs, field.Invalid(renamePath.Child("to"), rename.To, "must not be a bare *"))
+		case !labelNameRegexp.MatchString(strings.ReplaceAll(rename.To, "*", "x")):
+			errs = append(errs, field.Invalid(renamePath.Child("to"), rename.To, "must be a valid label name"))
+		case strings.Count(rename.To, "*") > 1:
+			errs = append(errs, field.Invalid(renamePath.Child("to"), rename.To, "must have at most one *"))
+		case strings.Contains(rename.To, "*") && strings.Count(rename.From, "*") != 1:
+			errs = append(errs, field.Invalid(renamePath.Child("to"), rename.To, "a * requires a single * in from"))
+		}
+	}
+
+	groupNames := sets.New[string]()
+	for i, group := range l.Groups {
+		groupPath := path.Child("groups").Index(i)
+		switch {
+		case group.Name == "":
+			errs = append(errs, field.Required(groupPath.Child("name"), ""))
+		case groupNames.Has(group.Name):
+			errs = append(errs, field.Duplicate(groupPath.Child("name"), group.Name))
+		}
+		groupNames.Insert(group.Name)
+		if len(group.Keys) == 0 {
+			errs = append(errs, field.Required(groupPath.Child("keys"), ""))
+		}
+		errs = append(errs, validateNames(groupPath.Child("keys"), group.Keys)...)
+	}
+	return errs
+}
+
+func validateValueMapping(path *field.Path, m *ValueMappingConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	for value, mapped := range m.Values {
+		if mapped == "" {
+			errs = append(errs, field.Required(path.Child("values").Key(value), "must not be empty"))
+		}
+	}
+	return errs
+}
+
+func validateServing(path *field.Path, s *ServingConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	ports := []struct {
+		name string
+		port int
+	}{
+		{"httpPort", s.HTTPPort},
+		{"httpsPort", s.HTTPSPort},
+		{"httpTelemetryPort", s.HTTPTelemetryPort},
+		{"httpsTelemetryPort", s.HTTPSTelemetryPort},
+	}
+	for _, p := range ports {
+		if p.port < 1 || p.port > 65535 {
+			errs = append(errs, field.Invalid(path.Child(p.name), p.port, "must be between 1 and 65535"))
+		}
+	}
+
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		errs = append(errs, field.Required(path.Child("tlsKeyFile"), "tlsCertFile and tlsKeyFile must be set together"))
+	}
+	if s.ClientCAFile != "" && s.TLSCertFile == "" {
+		errs = append(errs, field.Forbidden(path.Child("clientCAFile"), "requires tlsCertFile and tlsKeyFile"))
+	}
+	if len(s.AllowedClientNames) > 0 && s.ClientCAFile == "" {
+		errs = append(errs, field.Forbidden(path.Child("allowedClientNames"), "requires clientCAFile"))
+	}
+	errs = append(errs, validateNames(path.Child("allowedClientNames"), s.AllowedClientNames)...)
+
+	authPath := path.Child("auth")
+	if s.Auth.ClientCertificateUser != "" && s.ClientCAFile == "" {
+		errs = append(errs, field.Forbidden(authPath.Child("clientCertificateUser"), "requires clientCAFile"))
+	}
+	if s.Auth.CacheTTL != nil && s.Auth.CacheTTL.Duration < 0 {
+		errs = append(errs, field.Invalid(authPath.Child("cacheTTL"), s.Auth.CacheTTL.Duration.String(), "must not be negative"))
+	}
+	if s.Auth.DenyCacheTTL != nil && s.Auth.DenyCacheTTL.Duration < 0 {
+		errs = append(errs, field.Invalid(authPath.Child("denyCacheTTL"), s.Auth.DenyCacheTTL.Duration.String(), "must not be negative"))
+	}
+	return errs
+}
+
+// validateNames returns an error for each empty name.
+func validateNames(path *field.Path, names []string) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, name := range names {
+		if name == "" {
+			errs = append(errs, field.Required(path.Index(i), "must not be empty"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,435 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Configuration
+		wantErr []string
+	}{
+		{
+			name: "defaults",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+`,
+			want: defaultConfiguration(),
+		},
+		{
+			name: "full",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+collectors:
+- name: managedclusters
+  selector: env=prod
+- name: manifestworks
+metrics:
+  deny:
+  - acm_manifestwork_status
+managedClusterLabels:
+  allow:
+  - cloud
+  - vendor
+timestampMetrics:
+  enabled: true
+serving:
+  httpPort: 9090
+  tlsCertFile: /etc/tls/tls.crt
+  tlsKeyFile: /etc/tls/tls.key
+  clientCAFile: /etc/tls/ca.crt
+  allowedClientNames:
+  - prometheus
+  auth:
+    enabled: true
+    cacheTTL: 1m
+customResources:
+- group: policy.open-cluster-management.io
+  version: v1
+  resource: policies
+  metrics:
+  - name: acm_policy_compliance
+    type: StateSet
+    path: [status, compliant]
+    list: [Compliant, NonCompliant]
+limits:
+  seriesPerFamily: 10000
+  families:
+    acm_managed_cluster_labels: 1000
+  collectors:
+    policies.policy.open-cluster-management.io: 5000
+`,
+			want: func() *Configuration {
+				c := defaultConfiguration()
+				enabled := true
+				c.Collectors = []CollectorConfig{
+					{Name: "managedclusters", Selector: "env=prod"},
+					{Name: "manifestworks"},
+				}
+				c.Metrics.Deny = []string{"acm_manifestwork_status"}
+				c.ManagedClusterLabels.Allow = []string{"cloud", "vendor"}
+				c.TimestampMetrics.Enabled = &enabled
+				c.Serving.HTTPPort = 9090
+				c.Serving.TLSCertFile = "/etc/tls/tls.crt"
+				c.Serving.TLSKeyFile = "/etc/tls/tls.key"
+				c.Serving.ClientCAFile = "/etc/tls/ca.crt"
+				c.Serving.AllowedClientNames = []string{"prometheus"}
+				c.Serving.Auth.Enabled = true
+				c.Serving.Auth.CacheTTL = &metav1.Duration{Duration: time.Minute}
+				c.CustomResources = []CustomResourceConfig{{
+					Group:    "policy.open-cluster-management.io",
+					Version:  "v1",
+					Resource: "policies",
+					Metrics: []CustomResourceMetricConfig{{
+						Name: "acm_policy_compliance",
+						Type: MetricTypeStateSet,
+						Path: []string{"status", "compliant"},
+						List: []string{"Compliant", "NonCompliant"},
+					}},
+				}}
+				c.Limits = LimitsConfig{
+					SeriesPerFamily: 10000,
+					Families:        map[string]int{"acm_managed_cluster_labels": 1000},
+					Collectors:      map[string]int{"policies.policy.open-cluster-management.io": 5000},
+				}
+				return c
+			}(),
+		},
+		{
+			name: "unknown field",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+colectors: []
+`,
+			wantErr: []string{`unknown field "colectors"`},
+		},
+		{
+			name: "invalid version and kind",
+			data: `
+apiVersion: v1
+kind: ConfigMap
+`,
+			wantErr: []string{"apiVersion: Unsupported value", "kind: Unsupported value"},
+		},
+		{
+			name: "invalid collectors",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+collectors:
+- name: managedclusters
+- name: pods
+- name: managedclusters
+  selector: env in (prod
+`,
+			wantErr: []string{
+				"collectors[1].name: Unsupported value",
+				"collectors[2].name: Duplicate value",
+				"collectors[2].selector: Invalid value",
+			},
+		},
+		{
+			name: "invalid metrics and labels",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+metrics:
+  allow:
+  - acm_managed_cluster_info
+  deny:
+  - ""
+managedClusterLabels:
+  allow:
+  - ""
+managedClusterAnnotations:
+  allow:
+  - ""
+managedClusterInfo:
+  createdVia:
+    values:
+      capi: ""
+`,
+			wantErr: []string{
+				"metrics.deny: Forbidden",
+				"metrics.deny[0]: Required value",
+				"managedClusterLabels.allow[0]: Required value",
+				"managedClusterAnnotations.allow[0]: Required value",
+				"managedClusterInfo.createdVia.values[capi]: Required value",
+			},
+		},
+		{
+			name: "invalid label renames and groups",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+managedClusterLabels:
+  renames:
+  - from: feature.open-cluster-management.io/*
+    to: addon-*
+  - from: "*/*"
+    to: feature_*
+  - to: cloud_vendor
+  - from: vendor*
+    to: "*"
+  groups:
+  - name: addons
+    keys: [feature.open-cluster-management.io/addon-*]
+  - name: addons
+`,
+			wantErr: []string{
+				"managedClusterLabels.renames[0].to: Invalid value",
+				"managedClusterLabels.renames[1].to: Invalid value",
+				"managedClusterLabels.renames[2].from: Required value",
+				"managedClusterLabels.renames[3].to: Invalid value",
+				"managedClusterLabels.groups[1].name: Duplicate value",
+				"managedClusterLabels.groups[1].keys: Required value",
+			},
+		},
+		{
+			name: "invalid serving",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+serving:
+  httpsPort: 70000
+  tlsCertFile: /etc/tls/tls.crt
+  allowedClientNames:
+  - prometheus
+  auth:
+    denyCacheTTL: -1s
+    clientCertificateUser: prometheus
+`,
+			wantErr: []string{
+				"serving.httpsPort: Invalid value",
+				"serving.tlsKeyFile: Required value",
+				"serving.allowedClientNames: Forbidden",
+				"serving.auth.clientCertificateUser: Forbidden",
+				"serving.auth.denyCacheTTL: Invalid value",
+			},
+		},
+		{
+			name: "invalid custom resources",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+customResources:
+- group: policy.open-cluster-management.io
+  resource: policies
+  metrics:
+  - name: acm-policy
+    type: Gauge
+  - name: acm_policy_info
+    type: Info
+    path: [status]
+    labelsFromPath:
+      remediation-action: [spec, remediationAction]
+  - name: acm_policy_info
+    type: StateSet
+  - name: acm_policy_unknown
+    type: Histogram
+`,
+			wantErr: []string{
+				"customResources[0].version: Required value",
+				"customResources[0].metrics[0].name: Invalid value",
+				"customResources[0].metrics[0].path: Required value",
+				"customResources[0].metrics[1].path: Forbidden",
+				"customResources[0].metrics[1].labelsFromPath[remediation-action]: Invalid value",
+				"customResources[0].metrics[2].name: Duplicate value",
+				"customResources[0].metrics[2].list: Required value",
+				"customResources[0].metrics[3].type: Unsupported value",
+			},
+		},
+		{
+			name: "invalid limits",
+			data: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+customResources:
+- group: policy.open-cluster-management.io
+  version: v1
+  resource: policies
+  metrics:
+  - name: acm_policy_info
+    type: Info
+limits:
+  seriesPerFamily: -1
+  families:
+    acm-managed-cluster-labels: 10
+    acm_managed_cluster_info: -1
+  collectors:
+    policies.policy.open-cluster-management.io: 100
+    managedclusters: -1
+    clusterdeployments: 100
+`,
+			wantErr: []string{
+				"limits.seriesPerFamily: Invalid value",
+				"limits.families[acm-managed-cluster-labels]: Invalid value",
+				"limits.families[acm_managed_cluster_info]: Invalid value",
+				"limits.collectors[managedclusters]: Invalid value",
+				"limits.collectors[clusterdeployments]: Unsupported value",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Load([]byte(test.data))
+			if len(test.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("expected errors %v, got none", test.wantErr)
+				}
+				for _, wantErr := range test.wantErr {
+					if !strings.Contains(err.Error(), wantErr) {
+						t.Errorf("expected error %q in %q", wantErr, err.Error())
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("expected %+v, got %+v", test.want, got)
+			}
+		})
+	}
+}
+
+func Test_LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+collectors:
+- name: pods
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(path)
+	if err == nil || !strings.Contains(err.Error(), path) || !strings.Contains(err.Error(), "collectors[0].name") {
+		t.Errorf("expected an error naming the file and the field, got %v", err)
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func Test_LoadConfigMap(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name        string
+		data        map[string]string
+		wantEnabled *bool
+		wantAllow   []string
+		wantErr     bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:        "legacy key",
+			data:        map[string]string{TimestampMetricsKey: "Enable"},
+			wantEnabled: &enabled,
+		},
+		{
+			name:        "legacy key disabled",
+			data:        map[string]string{TimestampMetricsKey: "Disable"},
+			wantEnabled: &disabled,
+		},
+		{
+			name: "config",
+			data: map[string]string{ConfigMapKey: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+managedClusterLabels:
+  allow: [cloud]
+`},
+			wantAllow: []string{"cloud"},
+		},
+		{
+			name: "config overrides legacy key",
+			data: map[string]string{
+				TimestampMetricsKey: "Enable",
+				ConfigMapKey: `
+apiVersion: clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1
+kind: Configuration
+timestampMetrics:
+  enabled: false
+`},
+			wantEnabled: &disabled,
+		},
+		{
+			name:    "invalid config",
+			data:    map[string]string{ConfigMapKey: "kind: Configuration"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "config"},
+				Data:       test.data,
+			}
+			got, err := LoadConfigMap(cm)
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "ns/config") {
+					t.Errorf("expected an error naming the ConfigMap, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got.TimestampMetrics.Enabled, test.wantEnabled) {
+				t.Errorf("expected timestamp metrics enabled %v, got %v", test.wantEnabled, got.TimestampMetrics.Enabled)
+			}
+			if !reflect.DeepEqual(got.ManagedClusterLabels.Allow, test.wantAllow) {
+				t.Errorf("expected label allowlist %v, got %v", test.wantAllow, got.ManagedClusterLabels.Allow)
+			}
+			if len(got.Collectors) != len(DefaultCollectors) {
+				t.Errorf("expected the default collectors, got %v", got.Collectors)
+			}
+		})
+	}
+}
+
+func defaultConfiguration() *Configuration {
+	c := &Configuration{APIVersion: APIVersion, Kind: Kind}
+	c.ManagedClusterInfo.CreatedVia.Fallback = "Other"
+	c.ManagedClusterInfo.ServiceName.Fallback = "Other"
+	for _, name := range DefaultCollectors {
+		c.Collectors = append(c.Collectors, CollectorConfig{Name: name})
+	}
+	c.Serving = ServingConfig{
+		Host:               "0.0.0.0",
+		HTTPPort:           8080,
+		HTTPSPort:          8443,
+		TelemetryHost:      "0.0.0.0",
+		HTTPTelemetryPort:  8081,
+		HTTPSTelemetryPort: 8444,
+		Auth: AuthConfig{
+			CacheTTL:     &metav1.Duration{Duration: 2 * time.Minute},
+			DenyCacheTTL: &metav1.Duration{Duration: 10 * time.Second},
+		},
+	}
+	return c
+}
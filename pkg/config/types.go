@@ -0,0 +1,269 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIVersion is the version of the configuration.
+	APIVersion = "clusterlifecycle-state-metrics.open-cluster-management.io/v1alpha1"
+	// Kind is the kind of the configuration.
+	Kind = "Configuration"
+)
+
+// Configuration is the configuration of clusterlifecycle-state-metrics. It is
+// loaded from the file of the --config flag or from the config.yaml key of the
+// clusterlifecycle-state-metrics-config ConfigMap.
+type Configuration struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+
+	// Collectors are the enabled collectors. Defaults to all collectors.
+	Collectors []CollectorConfig `json:"collectors,omitempty"`
+
+	// Metrics selects the exposed metric families.
+	Metrics MetricsConfig `json:"metrics,omitempty"`
+
+	// ManagedClusterLabels selects the labels of the managed clusters exposed
+	// by acm_managed_cluster_labels.
+	ManagedClusterLabels LabelsConfig `json:"managedClusterLabels,omitempty"`
+
+	// ManagedClusterInfo configures the label values of acm_managed_cluster_info.
+	ManagedClusterInfo ClusterInfoConfig `json:"managedClusterInfo,omitempty"`
+
+	// ManagedClusterAnnotations selects the annotations of the managed
+	// clusters exposed by acm_managed_cluster_annotations.
+	ManagedClusterAnnotations AnnotationsConfig `json:"managedClusterAnnotations,omitempty"`
+
+	// TimestampMetrics configures the timestamp metrics.
+	TimestampMetrics TimestampMetricsConfig `json:"timestampMetrics,omitempty"`
+
+	// Serving configures the metrics and telemetry servers.
+	Serving ServingConfig `json:"serving,omitempty"`
+
+	// CustomResources configures the state metrics of custom resources.
+	CustomResources []CustomResourceConfig `json:"customResources,omitempty"`
+
+	// Limits limits the number of series of the metric families.
+	Limits LimitsConfig `json:"limits,omitempty"`
+}
+
+// CollectorConfig configures a collector.
+type CollectorConfig struct {
+	// Name is the name of the collector, one of managedclusters,
+	// managedclusteraddons and manifestworks.
+	Name string `json:"name"`
+
+	// Selector is a label selector of the objects watched by the collector.
+	// Defaults to all objects.
+	Selector string `json:"selector,omitempty"`
+}
+
+// MetricsConfig selects the exposed metric families. Allow and Deny are
+// mutually exclusive.
+type MetricsConfig struct {
+	// Allow is a list of the metric families to expose.
+	Allow []string `json:"allow,omitempty"`
+	// Deny is a list of the metric families not to expose.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// LabelsConfig selects the labels of the managed clusters. A key pattern is a
+// glob whose * matches any sequence of characters.
+type LabelsConfig struct {
+	// Allow is a list of the key patterns to expose. Defaults to all labels.
+	Allow []string `json:"allow,omitempty"`
+	// Deny is a list of the key patterns not to expose.
+	Deny []string `json:"deny,omitempty"`
+	// Renames map the keys to label names instead of sanitizing them. The
+	// first matching rename is used.
+	Renames []LabelRenameConfig `json:"renames,omitempty"`
+	// Groups expose the labels matching their key patterns as series of a
+	// separate metric, one per label, instead of a label name each.
+	Groups []LabelGroupConfig `json:"groups,omitempty"`
+}
+
+// ClusterInfoConfig configures the label values of acm_managed_cluster_info.
+type ClusterInfoConfig struct {
+	// CreatedVia maps the values of the open-cluster-management/created-via
+	// annotation to the created_via label.
+	CreatedVia ValueMappingConfig `json:"createdVia,omitempty"`
+	// ServiceName maps the values of the open-cluster-management/service-name
+	// annotation to the service_name label.
+	ServiceName ValueMappingConfig `json:"serviceName,omitempty"`
+}
+
+// ValueMappingConfig maps the values of an annotation to label values. A
+// missing annotation is reported as the fallback. An annotation value without
+// a mapping is counted once per managed cluster by
+// acm_managed_cluster_unmapped_values_total, and reported as the fallback, or
+// as is if passThrough is set.
+type ValueMappingConfig struct {
+	// Values maps the annotation values to label values, in addition to the
+	// built-in mappings which they override.
+	Values map[string]string `json:"values,omitempty"`
+	// Fallback is the label value of the missing and unmapped annotation
+	// values. Defaults to Other.
+	Fallback string `json:"fallback,omitempty"`
+	// PassThrough reports the unmapped annotation values as is.
+	PassThrough bool `json:"passThrough,omitempty"`
+}
+
+// AnnotationsConfig selects the annotations of the managed clusters.
+type AnnotationsConfig struct {
+	// Allow is a list of the key patterns to expose, a glob whose * matches
+	// any sequence of characters. No annotation is exposed if empty.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// LabelRenameConfig maps the keys matching a pattern to a label name.
+type LabelRenameConfig struct {
+	// From is the key pattern.
+	From string `json:"from"`
+	// To is the label name. A * is replaced with the sanitized part of the key
+	// matched by the * of From, which must have a single *.
+	To string `json:"to"`
+}
+
+// LabelGroupConfig is a named group of labels.
+type LabelGroupConfig struct {
+	// Name is the name of the group.
+	Name string `json:"name"`
+	// Keys are the key patterns of the labels of the group.
+	Keys []string `json:"keys"`
+}
+
+// LimitsConfig limits the number of series of the metric families. Once a
+// limit is reached, the new series are dropped and counted by
+// ksm_dropped_series_total. A limit of 0 does not limit the series.
+type LimitsConfig struct {
+	// SeriesPerFamily is the maximum number of series of each metric family.
+	SeriesPerFamily int `json:"seriesPerFamily,omitempty"`
+	// Families maps the metric families to their maximum number of series,
+	// overriding SeriesPerFamily.
+	Families map[string]int `json:"families,omitempty"`
+	// Collectors maps the collectors to the maximum number of series of all
+	// their metric families. A custom resource collector is named after the
+	// resource and group of the custom resource, such as
+	// clusterdeployments.hive.openshift.io.
+	Collectors map[string]int `json:"collectors,omitempty"`
+}
+
+// TimestampMetricsConfig configures the timestamp metrics.
+type TimestampMetricsConfig struct {
+	// Enabled enables the timestamp metrics. If unset, the timestamp metrics
+	// are enabled by the collect-timestamp-metrics key of the
+	// clusterlifecycle-state-metrics-config ConfigMap.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// ServingConfig configures the metrics and telemetry servers.
+type ServingConfig struct {
+	// Host to expose metrics on. Defaults to 0.0.0.0.
+	Host string `json:"host,omitempty"`
+	// HTTPPort to expose metrics on. Defaults to 8080.
+	HTTPPort int `json:"httpPort,omitempty"`
+	// HTTPSPort to expose metrics on. Defaults to 8443.
+	HTTPSPort int `json:"httpsPort,omitempty"`
+	// TelemetryHost to expose the self metrics on. Defaults to 0.0.0.0.
+	TelemetryHost string `json:"telemetryHost,omitempty"`
+	// HTTPTelemetryPort to expose the self metrics on. Defaults to 8081.
+	HTTPTelemetryPort int `json:"httpTelemetryPort,omitempty"`
+	// HTTPSTelemetryPort to expose the self metrics on. Defaults to 8444.
+	HTTPSTelemetryPort int `json:"httpsTelemetryPort,omitempty"`
+
+	// TLSCertFile and TLSKeyFile are the serving certificate of the https
+	// ports. The https ports are served only if both are set.
+	TLSCertFile string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty"`
+	// ClientCAFile requires a client certificate signed by the CA on the https ports.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+	// AllowedClientNames are the common names or subject alternative names of
+	// the client certificates allowed on the https ports.
+	AllowedClientNames []string `json:"allowedClientNames,omitempty"`
+
+	// EnableGZIPEncoding gzips the responses when requested by the clients.
+	EnableGZIPEncoding bool `json:"enableGZIPEncoding,omitempty"`
+	// WaitForCacheSync responds 503 on the metrics path until every watched
+	// resource has completed its initial list.
+	WaitForCacheSync bool `json:"waitForCacheSync,omitempty"`
+
+	// Auth configures the authentication and authorization on the https ports.
+	Auth AuthConfig `json:"auth,omitempty"`
+}
+
+// AuthConfig configures the authentication and authorization on the https ports.
+type AuthConfig struct {
+	// Enabled authenticates the requests with a TokenReview and authorizes
+	// them with a SubjectAccessReview.
+	Enabled bool `json:"enabled,omitempty"`
+	// CacheTTL is the time to cache the authenticated tokens and the allowed
+	// requests. Defaults to 2m.
+	CacheTTL *metav1.Duration `json:"cacheTTL,omitempty"`
+	// DenyCacheTTL is the time to cache the denied requests. Defaults to 10s.
+	DenyCacheTTL *metav1.Duration `json:"denyCacheTTL,omitempty"`
+	// ClientCertificateUser is the user the requests with a client
+	// certificate signed by the client CA and without bearer token are
+	// authorized as. If not set, these requests are not authenticated.
+	ClientCertificateUser string `json:"clientCertificateUser,omitempty"`
+}
+
+// MetricType is the type of a custom resource state metric.
+type MetricType string
+
+const (
+	// MetricTypeGauge reports the number at the path of the metric.
+	MetricTypeGauge MetricType = "Gauge"
+	// MetricTypeInfo reports 1 with the labels of the metric.
+	MetricTypeInfo MetricType = "Info"
+	// MetricTypeStateSet reports 1 for the state at the path of the metric and
+	// 0 for the other states of the list.
+	MetricTypeStateSet MetricType = "StateSet"
+)
+
+// CustomResourceConfig configures the state metrics of a custom resource. The
+// metrics of an object are attributed to the managed cluster named after its
+// namespace, or after its name if the resource is cluster-scoped. The service
+// account has to be allowed to list and watch the resource.
+type CustomResourceConfig struct {
+	// Group, Version and Resource identify the watched resource.
+	Group    string `json:"group"`
+	Version  string `json:"version"`
+	Resource string `json:"resource"`
+
+	// Selector is a label selector of the watched objects. Defaults to all objects.
+	Selector string `json:"selector,omitempty"`
+
+	// Metrics are the metrics generated for each object.
+	Metrics []CustomResourceMetricConfig `json:"metrics"`
+}
+
+// CustomResourceMetricConfig configures a state metric of a custom resource.
+// A path is a list of fields from the root of the object; an element of a list
+// is selected by its index or by a "[key=value]" field match, for example
+// [status, conditions, "[type=Ready]", status].
+type CustomResourceMetricConfig struct {
+	// Name is the name of the metric family.
+	Name string `json:"name"`
+	// Help is the help text of the metric family.
+	Help string `json:"help,omitempty"`
+	// Type is the type of the metric, one of Gauge, Info and StateSet.
+	Type MetricType `json:"type"`
+
+	// Path is the path of the value of a Gauge, or of the state of a StateSet.
+	Path []string `json:"path,omitempty"`
+	// NilIsZero reports 0 for a Gauge whose path does not exist instead of
+	// skipping the metric.
+	NilIsZero bool `json:"nilIsZero,omitempty"`
+
+	// List is the list of the states of a StateSet.
+	List []string `json:"list,omitempty"`
+	// LabelName is the label of the state of a StateSet. Defaults to state.
+	LabelName string `json:"labelName,omitempty"`
+
+	// LabelsFromPath are the labels of the metric indexed by label name, with
+	// the path of their value.
+	LabelsFromPath map[string][]string `json:"labelsFromPath,omitempty"`
+}
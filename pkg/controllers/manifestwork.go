@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -22,8 +23,13 @@ import (
 type manifestworkReconciler struct {
 	client.Client
 
+	// Protects StartTime and disabled
+	mutex sync.RWMutex
 	// StartTime is the start time of the controller
 	StartTime time.Time
+	// disabled is set while the controller is disabled, the ManifestWorks are
+	// ignored until it is enabled again
+	disabled bool
 }
 
 func NewManifestworkReconciler(c client.Client, startTime time.Time) *manifestworkReconciler {
@@ -33,11 +39,38 @@ func NewManifestworkReconciler(c client.Client, startTime time.Time) *manifestwo
 	}
 }
 
+// SetEnabled enables or disables the controller. Once enabled again, the
+// start time is reset, so only the manifestworks created afterwards are
+// handled, as if the controller was restarted.
+func (r *manifestworkReconciler) SetEnabled(enabled bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.disabled == !enabled {
+		return
+	}
+	r.disabled = !enabled
+	if enabled {
+		r.StartTime = time.Now()
+		log.Log.Info("The manifestwork controller start time has been set", "startTime", r.StartTime)
+	}
+}
+
+// Enabled returns true if the controller is enabled.
+func (r *manifestworkReconciler) Enabled() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return !r.disabled
+}
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *manifestworkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	if !r.Enabled() {
+		return ctrl.Result{}, nil
+	}
+
 	omw := &workv1.ManifestWork{}
 	err := r.Client.Get(ctx, req.NamespacedName, omw)
 	if err != nil {
@@ -101,22 +134,31 @@ func (r *manifestworkReconciler) updateAnnotations(ctx context.Context,
 func (r *manifestworkReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&workv1.ManifestWork{}).
-		WithEventFilter(predicate.NewPredicateFuncs(
-			func(object client.Object) bool {
-				mw, ok := object.(*workv1.ManifestWork)
-				if !ok {
-					return false
-				}
-
-				// Only handle the manifestworks created after the controller starts
-				if mw.CreationTimestamp.Time.Before(r.StartTime) {
-					return false
-				}
-
-				report, _ := common.FilterTimestampManifestwork(mw)
-				return report
-			},
-		)).
+		WithEventFilter(predicate.NewPredicateFuncs(r.accept)).
 		Named("ManifestWork").
 		Complete(r)
 }
+
+// accept returns true if the controller is enabled and the object is a
+// manifestwork whose timestamp is reported.
+func (r *manifestworkReconciler) accept(object client.Object) bool {
+	mw, ok := object.(*workv1.ManifestWork)
+	if !ok {
+		return false
+	}
+
+	r.mutex.RLock()
+	disabled, startTime := r.disabled, r.StartTime
+	r.mutex.RUnlock()
+	if disabled {
+		return false
+	}
+
+	// Only handle the manifestworks created after the controller starts
+	if mw.CreationTimestamp.Time.Before(startTime) {
+		return false
+	}
+
+	report, _ := common.FilterTimestampManifestwork(mw)
+	return report
+}
@@ -0,0 +1,57 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// Runner runs the controller recording the timestamps of the ManifestWorks,
+// which can be enabled and disabled at runtime. The controller is registered
+// with the manager once, since controller-runtime rejects a second controller
+// with the same name in the process, and it ignores the ManifestWorks while it
+// is disabled.
+type Runner struct {
+	reconciler *manifestworkReconciler
+	// run runs the manager until the context is done, once the controllers are
+	// set up with the given setup func
+	run  func(ctx context.Context, setup func(mgr ctrl.Manager) error)
+	once sync.Once
+}
+
+// NewRunner returns a disabled Runner running the manager with run once it is
+// started.
+func NewRunner(run func(ctx context.Context, setup func(mgr ctrl.Manager) error)) *Runner {
+	return &Runner{
+		reconciler: &manifestworkReconciler{disabled: true},
+		run:        run,
+	}
+}
+
+// Start enables the controller. The manager is run the first time, until the
+// context is done.
+func (r *Runner) Start(ctx context.Context) {
+	r.reconciler.SetEnabled(true)
+	r.once.Do(func() {
+		go r.run(ctx, r.setup)
+	})
+}
+
+// Stop disables the controller.
+func (r *Runner) Stop() {
+	r.reconciler.SetEnabled(false)
+}
+
+// Enabled returns true if the controller is enabled.
+func (r *Runner) Enabled() bool {
+	return r.reconciler.Enabled()
+}
+
+// setup sets up the controller with the manager.
+func (r *Runner) setup(mgr ctrl.Manager) error {
+	r.reconciler.Client = mgr.GetClient()
+	return r.reconciler.SetupWithManager(mgr)
+}
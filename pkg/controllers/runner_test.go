@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	workv1 "open-cluster-management.io/api/work/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/common"
+)
+
+func TestRunner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mutex sync.Mutex
+		runs  int
+		errs  []error
+	)
+	setupDone := make(chan struct{})
+	runner := NewRunner(func(ctx context.Context, setup func(mgr ctrl.Manager) error) {
+		defer close(setupDone)
+		mgr, err := ctrl.NewManager(&rest.Config{Host: "https://127.0.0.1:1"}, ctrl.Options{
+			Scheme:  testscheme,
+			Metrics: metricsserver.Options{BindAddress: "0"},
+		})
+		mutex.Lock()
+		defer mutex.Unlock()
+		runs++
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if err := setup(mgr); err != nil {
+			errs = append(errs, err)
+		}
+	})
+
+	// disable, enable, disable and enable again
+	runner.Stop()
+	if runner.Enabled() {
+		t.Errorf("expected the runner disabled")
+	}
+	runner.Start(ctx)
+	if !runner.Enabled() {
+		t.Errorf("expected the runner enabled")
+	}
+	select {
+	case <-setupDone:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the controllers are not set up")
+	}
+	firstStartTime := runner.reconciler.StartTime
+	runner.Stop()
+	if runner.Enabled() {
+		t.Errorf("expected the runner disabled")
+	}
+	time.Sleep(10 * time.Millisecond)
+	runner.Start(ctx)
+	if !runner.Enabled() {
+		t.Errorf("expected the runner enabled")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if runs != 1 {
+		t.Errorf("expected the manager run once, got %d", runs)
+	}
+	if len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if !runner.reconciler.StartTime.After(firstStartTime) {
+		t.Errorf("expected the start time reset once enabled again, got %v, was %v",
+			runner.reconciler.StartTime, firstStartTime)
+	}
+}
+
+func TestReconcile_disabled(t *testing.T) {
+	mw := &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-work1",
+			Namespace: "test",
+			Labels: map[string]string{
+				common.LabelHostedCluster: "hosting1",
+			},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+		},
+		Status: workv1.ManifestWorkStatus{
+			Conditions: []metav1.Condition{
+				{
+					Type:               workv1.WorkApplied,
+					Status:             metav1.ConditionTrue,
+					LastTransitionTime: metav1.Now(),
+				},
+			},
+		},
+	}
+	runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).
+		WithObjects(mw).WithStatusSubresource(mw).Build()
+	r := NewManifestworkReconciler(runtimeClient, time.Now())
+	r.SetEnabled(false)
+
+	if r.accept(mw) {
+		t.Errorf("expected the manifestwork ignored while the controller is disabled")
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test-work1", Namespace: "test"}}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if timestamp := observedTimestamp(t, runtimeClient); timestamp != nil {
+		t.Errorf("unexpected timestamp recorded while the controller is disabled: %v", timestamp)
+	}
+
+	r.SetEnabled(true)
+	if !r.accept(mw) {
+		t.Errorf("expected the manifestwork accepted once the controller is enabled")
+	}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if timestamp := observedTimestamp(t, runtimeClient); timestamp == nil {
+		t.Errorf("expected the timestamp recorded once the controller is enabled")
+	}
+}
+
+func observedTimestamp(t *testing.T, runtimeClient client.Client) *common.ObservedTimestamp {
+	mw := &workv1.ManifestWork{}
+	if err := runtimeClient.Get(context.TODO(),
+		types.NamespacedName{Name: "test-work1", Namespace: "test"}, mw); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	return common.GetObservedTimestamp(mw)
+}
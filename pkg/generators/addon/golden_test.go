@@ -0,0 +1,25 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package addon
+
+import (
+	"testing"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+)
+
+// Test_GoldenMetrics compares the metrics of the addons of testdata/*.yaml
+// with the golden files beside them. Run
+// UPDATE_GOLDEN=1 go test ./pkg/generators/addon -run Test_GoldenMetrics
+// to regenerate them.
+func Test_GoldenMetrics(t *testing.T) {
+	getClusterID := func(clusterName string) string {
+		return clusterName + "-id"
+	}
+
+	testcommon.RunGoldenTests(t, "testdata", "status", []metric.FamilyGenerator{
+		GetManagedClusterAddOnStatusMetricFamilies(getClusterID),
+	})
+}
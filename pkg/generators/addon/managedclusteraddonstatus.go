@@ -20,6 +20,17 @@ var (
 	}
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descAddOnStatusName,
+		Help:      descAddOnStatusHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"addon_name", "managed_cluster_id", "managed_cluster_name", "condition", "status"},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManagedClusterAddOns,
+	})
+}
+
 func GetManagedClusterAddOnStatusMetricFamilies(getClusterIdFunc func(string) string) metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descAddOnStatusName,
@@ -0,0 +1,80 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package cluster
+
+import (
+	"testing"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+)
+
+// Test_GoldenMetrics compares the metrics of the managed clusters of
+// testdata/*.yaml with the golden files beside them. Run
+// UPDATE_GOLDEN=1 go test ./pkg/generators/cluster -run Test_GoldenMetrics
+// to regenerate them.
+func Test_GoldenMetrics(t *testing.T) {
+	getClusterTimestamps := func(clusterName string) map[string]float64 {
+		if clusterName != "ocp-cluster" {
+			return nil
+		}
+		return map[string]float64{"ImportSucceeded": 1704067260, "AgentRunning": 1704067320}
+	}
+	isHibernating := func(clusterName string) bool {
+		return clusterName == "eks-cluster"
+	}
+
+	labelOptions := LabelOptions{
+		Deny:   []string{"name"},
+		Groups: []LabelGroup{{Name: "team", Keys: []string{"team.example.com/*"}}},
+	}
+
+	tests := []struct {
+		name     string
+		families []metric.FamilyGenerator
+	}{
+		{
+			name: "info",
+			families: []metric.FamilyGenerator{
+				GetManagedClusterInfoMetricFamilies("hub-id", func() string { return "mce" }, InfoOptions{}, NewUnmappedValues("")),
+			},
+		},
+		{
+			name: "labels",
+			families: []metric.FamilyGenerator{
+				GetManagedClusterLabelMetricFamilies("hub-id", labelOptions),
+				GetManagedClusterLabelGroupMetricFamilies("hub-id", labelOptions),
+				GetManagedClusterLabelDroppedMetricFamilies("hub-id", labelOptions),
+			},
+		},
+		{
+			name: "annotations",
+			families: []metric.FamilyGenerator{
+				GetManagedClusterAnnotationMetricFamilies("hub-id", []string{"open-cluster-management/*"}),
+			},
+		},
+		{
+			name:     "status",
+			families: []metric.FamilyGenerator{GetManagedClusterStatusMetricFamilies()},
+		},
+		{
+			name: "workercores",
+			families: []metric.FamilyGenerator{
+				GetManagedClusterWorkerCoresMetricFamilies("hub-id", isHibernating),
+			},
+		},
+		{
+			name: "timestamp",
+			families: []metric.FamilyGenerator{
+				GetManagedClusterTimestampMetricFamilies("hub-id", getClusterTimestamps),
+			},
+		},
+	}
+
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			testcommon.RunGoldenTests(t, "testdata", c.name, c.families)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package cluster
+
+import (
+	"strings"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
+)
+
+// HubInfo describes the hub of the managed clusters.
+type HubInfo struct {
+	ClusterID string
+	// ClusterIDSource is where the ID of the hub cluster is read from
+	ClusterIDSource string
+	Type            string
+	// KubeVersion is the Kubernetes version of the hub
+	KubeVersion string
+	// OCPVersion is the OpenShift version of the hub, if it is an OpenShift cluster
+	OCPVersion string
+	// ProductVersion is the version of the MultiClusterHub or MultiClusterEngine
+	ProductVersion string
+	// ExporterVersion is the version of clusterlifecycle-state-metrics
+	ExporterVersion string
+	// Collectors are the enabled collectors
+	Collectors []string
+}
+
+var (
+	descHubInfoName          = "acm_hub_info"
+	descHubInfoHelp          = "Hub cluster information"
+	descHubInfoDefaultLabels = []string{"hub_cluster_id",
+		"hub_cluster_id_source",
+		"hub_type",
+		"kube_version",
+		"ocp_version",
+		"product_version",
+		"exporter_version",
+		"collectors"}
+)
+
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descHubInfoName,
+		Help:      descHubInfoHelp,
+		Type:      metric.Gauge,
+		Labels:    descHubInfoDefaultLabels,
+		Stability: generators.StabilityAlpha,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
+// GetHubInfoMetricFamilies returns the info metric family of the hub. The
+// collectors are joined with commas.
+func GetHubInfoMetricFamilies() metric.FamilyGenerator {
+	return metric.FamilyGenerator{
+		Name: descHubInfoName,
+		Type: metric.Gauge,
+		Help: descHubInfoHelp,
+		GenerateFunc: func(obj interface{}) *metric.Family {
+			info, ok := obj.(*HubInfo)
+			if !ok {
+				return &metric.Family{Metrics: []*metric.Metric{}}
+			}
+			return &metric.Family{Metrics: []*metric.Metric{
+				{
+					LabelKeys: descHubInfoDefaultLabels,
+					LabelValues: []string{info.ClusterID,
+						info.ClusterIDSource,
+						info.Type,
+						info.KubeVersion,
+						info.OCPVersion,
+						info.ProductVersion,
+						info.ExporterVersion,
+						strings.Join(info.Collectors, ","),
+					},
+					Value: 1,
+				},
+			}}
+		},
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package cluster
+
+import (
+	"testing"
+
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+func Test_getHubInfoMetricFamilies(t *testing.T) {
+	tests := []testcommon.GenerateMetricsTestCase{
+		{
+			Name: "test hub info",
+			Obj: &HubInfo{
+				ClusterID:       "hub-id",
+				ClusterIDSource: "clusterversion",
+				Type:            "acm",
+				KubeVersion:     "v1.29.5+29c95f3",
+				OCPVersion:      "4.16.3",
+				ProductVersion:  "2.11.0",
+				ExporterVersion: "2.11.0",
+				Collectors:      []string{"managedclusteraddons", "managedclusters"},
+			},
+			MetricNames: []string{"acm_hub_info"},
+			Want: `acm_hub_info{hub_cluster_id="hub-id",hub_cluster_id_source="clusterversion",hub_type="acm",` +
+				`kube_version="v1.29.5+29c95f3",ocp_version="4.16.3",product_version="2.11.0",exporter_version="2.11.0",` +
+				`collectors="managedclusteraddons,managedclusters"} 1`,
+		},
+		{
+			Name: "test hub info of a kubernetes hub",
+			Obj: &HubInfo{
+				ClusterID:       "hub-id",
+				ClusterIDSource: "kube-system",
+				Type:            "mce",
+				KubeVersion:     "v1.30.0",
+				Collectors:      []string{"managedclusters"},
+			},
+			MetricNames: []string{"acm_hub_info"},
+			Want: `acm_hub_info{hub_cluster_id="hub-id",hub_cluster_id_source="kube-system",hub_type="mce",` +
+				`kube_version="v1.30.0",ocp_version="",product_version="",exporter_version="",collectors="managedclusters"} 1`,
+		},
+		{
+			Name:        "test hub info with invalid input",
+			Obj:         "abc",
+			MetricNames: []string{"acm_hub_info"},
+			Want:        ``,
+		},
+	}
+
+	for i, c := range tests {
+		t.Run(c.Name, func(t *testing.T) {
+			c.Func = metric.ComposeMetricGenFuncs(
+				[]metric.FamilyGenerator{GetHubInfoMetricFamilies()},
+			)
+			if err := c.Run(); err != nil {
+				t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package cluster
+
+import (
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
+)
+
+var (
+	descManagedClusterAnnotationInfoName = "acm_managed_cluster_annotations"
+	descManagedClusterAnnotationInfoHelp = "Managed cluster annotations"
+)
+
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:           descManagedClusterAnnotationInfoName,
+		Help:           descManagedClusterAnnotationInfoHelp,
+		Type:           metric.Gauge,
+		Labels:         descManagedClusterLabelDefaultLabel,
+		VariableLabels: "One label per allowed annotation of the managed cluster, named after its sanitized key",
+		Stability:      generators.StabilityAlpha,
+		Collector:      generators.CollectorManagedClusters,
+	})
+}
+
+// GetManagedClusterAnnotationMetricFamilies returns a metric family with the
+// annotations of the managed clusters matching the allowlist of key patterns.
+// The keys are sanitized like the label keys. No metric is generated if the
+// allowlist is empty, since annotations often carry large values.
+func GetManagedClusterAnnotationMetricFamilies(hubClusterID string, allow []string) metric.FamilyGenerator {
+	mapper := newLabelMapper(LabelOptions{Allow: allow})
+	return metric.FamilyGenerator{
+		Name: descManagedClusterAnnotationInfoName,
+		Type: metric.Gauge,
+		Help: descManagedClusterAnnotationInfoHelp,
+		GenerateFunc: wrapManagedClusterLabelFunc(func(mc *mcv1.ManagedCluster) metric.Family {
+			if len(allow) == 0 {
+				return metric.Family{Metrics: []*metric.Metric{}}
+			}
+
+			mapped := mapper.mapLabels(mc.GetName(), mc.Annotations, descManagedClusterLabelDefaultLabel)
+			f := metric.Family{Metrics: []*metric.Metric{
+				{
+					LabelKeys:   append(append([]string{}, descManagedClusterLabelDefaultLabel...), mapped.keys...),
+					LabelValues: append([]string{hubClusterID, getClusterID(mc)}, mapped.values...),
+					Value:       1,
+				},
+			}}
+
+			klog.V(4).Infof("Returning %v", string(f.ByteSlice()))
+			return f
+		}),
+	}
+}
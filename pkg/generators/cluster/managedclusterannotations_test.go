@@ -0,0 +1,72 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package cluster
+
+import (
+	"testing"
+
+	mciv1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func Test_getManagedClusterAnnotationMetricFamilies(t *testing.T) {
+	mc := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-cluster",
+			Labels: map[string]string{
+				mciv1beta1.LabelClusterID: "managed_cluster_id",
+			},
+			Annotations: map[string]string{
+				"example.com/owner-team":                           "sre",
+				"example.com/cost-center":                          "1234",
+				"example.com/ticket":                               "OPS-1",
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		allow []string
+		want  string
+	}{
+		{
+			name: "no allowlist",
+			want: ``,
+		},
+		{
+			name:  "allow keys",
+			allow: []string{"example.com/owner-team", "example.com/cost-center"},
+			want:  `acm_managed_cluster_annotations{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",example_com_cost_center="1234",example_com_owner_team="sre"} 1`,
+		},
+		{
+			name:  "allow a glob",
+			allow: []string{"example.com/*"},
+			want:  `acm_managed_cluster_annotations{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",example_com_cost_center="1234",example_com_owner_team="sre",example_com_ticket="OPS-1"} 1`,
+		},
+		{
+			name:  "allow a missing key",
+			allow: []string{"example.com/region"},
+			want:  `acm_managed_cluster_annotations{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id"} 1`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := testcommon.GenerateMetricsTestCase{
+				Obj:         mc,
+				MetricNames: []string{"acm_managed_cluster_annotations"},
+				Want:        test.want,
+				Func: metric.ComposeMetricGenFuncs([]metric.FamilyGenerator{
+					GetManagedClusterAnnotationMetricFamilies("hub_cluster_id", test.allow),
+				}),
+			}
+			if err := c.Run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"k8s.io/kube-state-metrics/pkg/metric"
 
 	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
 )
 
 var (
@@ -14,6 +16,17 @@ var (
 	descClusterCountHelp = "Managed cluster count"
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descClusterCountName,
+		Help:      descClusterCountHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
 func GetManagedClusterCountMetricFamilies() metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descClusterCountName,
@@ -5,13 +5,18 @@ package cluster
 
 import (
 	"strconv"
+	"sync"
 
+	"github.com/prometheus/client_golang/prometheus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kube-state-metrics/pkg/metric"
 
 	mciv1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	"k8s.io/klog/v2"
 	mcv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
 )
 
 const (
@@ -20,13 +25,170 @@ const (
 )
 
 const (
-	createdViaAnnotation       = "open-cluster-management/created-via"
-	createdViaAnnotationOther  = "Other"
-	serviceNameAnnotation      = "open-cluster-management/service-name"
-	serviceNameAnnotationOther = "Other"
-	productClaimKey            = "product.open-cluster-management.io"
+	createdViaAnnotation      = "open-cluster-management/created-via"
+	createdViaAnnotationOther = "Other"
+	serviceNameAnnotation     = "open-cluster-management/service-name"
+	productClaimKey           = "product.open-cluster-management.io"
+)
+
+// UnmappedValuesTotalMetric counts the annotation values without a mapping to
+// a label value of acm_managed_cluster_info, so the unknown values surface
+// instead of being reported as the fallback. A value is counted once per
+// managed cluster, and the values are bounded by UnmappedValues.
+var UnmappedValuesTotalMetric = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "acm_managed_cluster_unmapped_values_total",
+		Help: "Total annotation values of the managed clusters without a mapping to a label value of acm_managed_cluster_info, counted once per managed cluster and value",
+	},
+	[]string{"label", "value"},
 )
 
+const (
+	// maxUnmappedValueLength is the length the unmapped values are truncated to.
+	maxUnmappedValueLength = 63
+	// maxUnmappedValues is the number of distinct unmapped values counted per
+	// label, the other values are counted as unmappedValueOverflow.
+	maxUnmappedValues = 100
+	// unmappedValueOverflow is the value label of the unmapped values beyond
+	// maxUnmappedValues.
+	unmappedValueOverflow = "_overflow"
+)
+
+// UnmappedValues records the unmapped value counted for each label of each
+// managed cluster of a hub, so the values are counted once instead of on every
+// regeneration of the metrics. The values of a managed cluster are forgotten
+// once it is deleted.
+type UnmappedValues struct {
+	// hub is the name of the hub of the managed clusters
+	hub   string
+	mutex sync.Mutex
+	// counted is the last counted value per label and managed cluster
+	counted map[unmappedValueKey]string
+	// distinct is the set of distinct values counted per label
+	distinct map[string]sets.Set[string]
+}
+
+type unmappedValueKey struct {
+	hub     string
+	label   string
+	cluster string
+}
+
+// NewUnmappedValues returns the unmapped values of the managed clusters of
+// the hub.
+func NewUnmappedValues(hub string) *UnmappedValues {
+	return &UnmappedValues{
+		hub:      hub,
+		counted:  map[unmappedValueKey]string{},
+		distinct: map[string]sets.Set[string]{},
+	}
+}
+
+// Forget forgets the values counted for the managed cluster, so they are
+// counted again if a managed cluster with the same name is created.
+func (u *UnmappedValues) Forget(cluster string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	for key := range u.counted {
+		if key.hub == u.hub && key.cluster == cluster {
+			delete(u.counted, key)
+		}
+	}
+}
+
+// Retain forgets the values counted for the managed clusters which are not
+// in the given set.
+func (u *UnmappedValues) Retain(clusters sets.Set[string]) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	for key := range u.counted {
+		if key.hub == u.hub && !clusters.Has(key.cluster) {
+			delete(u.counted, key)
+		}
+	}
+}
+
+// count counts the unmapped value of the label of the managed cluster, unless
+// it is already counted for the managed cluster.
+func (u *UnmappedValues) count(label, cluster, value string) {
+	if len(value) > maxUnmappedValueLength {
+		value = value[:maxUnmappedValueLength]
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	distinct, ok := u.distinct[label]
+	if !ok {
+		distinct = sets.New[string]()
+		u.distinct[label] = distinct
+	}
+	if !distinct.Has(value) {
+		if distinct.Len() >= maxUnmappedValues {
+			value = unmappedValueOverflow
+		} else {
+			distinct.Insert(value)
+		}
+	}
+
+	key := unmappedValueKey{hub: u.hub, label: label, cluster: cluster}
+	if counted, ok := u.counted[key]; ok && counted == value {
+		return
+	}
+	u.counted[key] = value
+	UnmappedValuesTotalMetric.WithLabelValues(label, value).Inc()
+}
+
+// InfoOptions configures the label values of acm_managed_cluster_info.
+type InfoOptions struct {
+	// CreatedVia maps the values of the created-via annotation.
+	CreatedVia ValueMapping
+	// ServiceName maps the values of the service-name annotation.
+	ServiceName ValueMapping
+}
+
+// ValueMapping maps the values of an annotation to label values. A missing
+// annotation is reported as the fallback. An annotation value without a
+// mapping is counted once per managed cluster by
+// acm_managed_cluster_unmapped_values_total, and reported as the fallback, or
+// as is if PassThrough is set.
+type ValueMapping struct {
+	// Values maps the annotation values to label values, in addition to the
+	// built-in mappings which they override.
+	Values map[string]string
+	// Fallback is the label value of the missing and unmapped annotation
+	// values. Defaults to Other.
+	Fallback string
+	// PassThrough reports the unmapped annotation values as is.
+	PassThrough bool
+}
+
+// mapValue returns the label value of the annotation of the managed cluster,
+// and counts the unmapped value in unmapped.
+func (m ValueMapping) mapValue(mc *mcv1.ManagedCluster, annotation, label string, builtin map[string]string, unmapped *UnmappedValues) string {
+	fallback := m.Fallback
+	if fallback == "" {
+		fallback = "Other"
+	}
+
+	value, ok := mc.GetAnnotations()[annotation]
+	if !ok {
+		return fallback
+	}
+	if mapped, ok := m.Values[value]; ok {
+		return mapped
+	}
+	if mapped, ok := builtin[value]; ok {
+		return mapped
+	}
+
+	klog.V(2).Infof("Value %q of annotation %s of managed cluster %s has no mapping", value, annotation, mc.GetName())
+	unmapped.count(label, mc.GetName(), value)
+	if m.PassThrough {
+		return value
+	}
+	return fallback
+}
+
 var serviceNameMapping map[string]string = map[string]string{
 	"compute": "Compute",
 	"other":   "Other",
@@ -57,20 +219,36 @@ var (
 		"product"}
 )
 
-func GetManagedClusterInfoMetricFamilies(hubClusterID, hub_type string) metric.FamilyGenerator {
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descClusterInfoName,
+		Help:      descClusterInfoHelp,
+		Type:      metric.Gauge,
+		Labels:    descClusterInfoDefaultLabels,
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
+// GetManagedClusterInfoMetricFamilies returns the info metric family of the
+// managed clusters. The hub type is read once the metrics of a managed cluster
+// are generated, so a changed hub type is exposed once they are refreshed. The
+// annotation values without a mapping are counted in unmapped.
+func GetManagedClusterInfoMetricFamilies(hubClusterID string, getHubType func() string, options InfoOptions, unmapped *UnmappedValues) metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descClusterInfoName,
 		Type: metric.Gauge,
 		Help: descClusterInfoHelp,
 		GenerateFunc: wrapManagedClusterInfoFunc(func(mc *mcv1.ManagedCluster) metric.Family {
+			hub_type := getHubType()
 			klog.Infof("Wrap %s", mc.GetName())
 			kubeVendor := mc.ObjectMeta.Labels[mciv1beta1.LabelKubeVendor]
 			cloudVendor := mc.ObjectMeta.Labels[mciv1beta1.LabelCloudVendor]
 
 			clusterID := getClusterID(mc)
 			version := getVersion(mc)
-			createdVia := getCreatedVia(mc)
-			serviceName := getServiceName(mc)
+			createdVia := options.CreatedVia.mapValue(mc, createdViaAnnotation, "created_via", createdViaMapping, unmapped)
+			serviceName := options.ServiceName.mapValue(mc, serviceNameAnnotation, "service_name", serviceNameMapping, unmapped)
 			available := getAvailableStatus(mc)
 			core_worker, socket_worker := getCapacity(mc)
 			product := getProduct(mc)
@@ -203,26 +381,6 @@ func wrapManagedClusterInfoFunc(f func(obj *mcv1.ManagedCluster) metric.Family)
 	}
 }
 
-func getCreatedVia(mc *mcv1.ManagedCluster) string {
-	if mc.GetAnnotations() == nil {
-		return createdViaAnnotationOther
-	}
-	if a, ok := mc.GetAnnotations()[createdViaAnnotation]; ok {
-		return createdViaMapping[a]
-	}
-	return createdViaAnnotationOther
-}
-
-func getServiceName(mc *mcv1.ManagedCluster) string {
-	if mc.GetAnnotations() == nil {
-		return serviceNameAnnotationOther
-	}
-	if a, ok := mc.GetAnnotations()[serviceNameAnnotation]; ok {
-		return serviceNameMapping[a]
-	}
-	return serviceNameAnnotationOther
-}
-
 func getProduct(mc *mcv1.ManagedCluster) string {
 	for _, claim := range mc.Status.ClusterClaims {
 		if claim.Name == productClaimKey {
@@ -4,12 +4,16 @@
 package cluster
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	dto "github.com/prometheus/client_model/go"
 	mciv1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/kube-state-metrics/pkg/metric"
 	mcv1 "open-cluster-management.io/api/cluster/v1"
 )
@@ -267,10 +271,148 @@ func Test_getManagedClusterMetricFamilies(t *testing.T) {
 	}
 	for i, c := range tests {
 		c.Func = metric.ComposeMetricGenFuncs(
-			[]metric.FamilyGenerator{GetManagedClusterInfoMetricFamilies("mycluster_id", hubType)},
+			[]metric.FamilyGenerator{GetManagedClusterInfoMetricFamilies("mycluster_id", func() string { return hubType }, InfoOptions{}, NewUnmappedValues(""))},
 		)
 		if err := c.Run(); err != nil {
 			t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
 		}
 	}
 }
+
+func Test_ValueMapping(t *testing.T) {
+	newCluster := func(name string, annotations map[string]string) *mcv1.ManagedCluster {
+		return &mcv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		mapping       ValueMapping
+		annotations   map[string]string
+		want          string
+		wantUnmapped  string
+		wantIncrement float64
+	}{
+		{
+			name: "missing annotation",
+			want: "Other",
+		},
+		{
+			name:        "builtin mapping",
+			annotations: map[string]string{createdViaAnnotation: "hive"},
+			want:        "Hive",
+		},
+		{
+			name:        "configured mapping",
+			mapping:     ValueMapping{Values: map[string]string{"capi": "CAPI", "hive": "OpenShiftHive"}},
+			annotations: map[string]string{createdViaAnnotation: "hive"},
+			want:        "OpenShiftHive",
+		},
+		{
+			name:          "unmapped value",
+			annotations:   map[string]string{createdViaAnnotation: "rosa"},
+			want:          "Other",
+			wantUnmapped:  "rosa",
+			wantIncrement: 1,
+		},
+		{
+			name:          "unmapped value with a fallback",
+			mapping:       ValueMapping{Fallback: "Unknown"},
+			annotations:   map[string]string{createdViaAnnotation: "rosa"},
+			want:          "Unknown",
+			wantUnmapped:  "rosa",
+			wantIncrement: 1,
+		},
+		{
+			name:          "unmapped value passed through",
+			mapping:       ValueMapping{PassThrough: true},
+			annotations:   map[string]string{createdViaAnnotation: "capi"},
+			want:          "capi",
+			wantUnmapped:  "capi",
+			wantIncrement: 1,
+		},
+	}
+
+	counterValue := func(value string) float64 {
+		out := &dto.Metric{}
+		if err := UnmappedValuesTotalMetric.WithLabelValues("created_via", value).Write(out); err != nil {
+			t.Fatal(err)
+		}
+		return out.GetCounter().GetValue()
+	}
+
+	unmapped := NewUnmappedValues("hub1")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := counterValue(test.wantUnmapped)
+			// the value of a managed cluster is counted once
+			for i := 0; i < 2; i++ {
+				got := test.mapping.mapValue(newCluster(test.name, test.annotations), createdViaAnnotation, "created_via", createdViaMapping, unmapped)
+				if got != test.want {
+					t.Errorf("expected %q, got %q", test.want, got)
+				}
+			}
+			if increment := counterValue(test.wantUnmapped) - before; increment != test.wantIncrement {
+				t.Errorf("expected the unmapped values counter to increase by %v, got %v", test.wantIncrement, increment)
+			}
+		})
+	}
+}
+
+func Test_unmappedValues_count(t *testing.T) {
+	counterValue := func(value string) float64 {
+		out := &dto.Metric{}
+		if err := UnmappedValuesTotalMetric.WithLabelValues("test_label", value).Write(out); err != nil {
+			t.Fatal(err)
+		}
+		return out.GetCounter().GetValue()
+	}
+
+	u := NewUnmappedValues("hub1")
+	u.count("test_label", "cluster1", "capi")
+	u.count("test_label", "cluster1", "capi")
+	u.count("test_label", "cluster2", "capi")
+	if v := counterValue("capi"); v != 2 {
+		t.Errorf("expected the value counted once per managed cluster, got %v", v)
+	}
+	u.count("test_label", "cluster1", "rosa")
+	if v := counterValue("rosa"); v != 1 {
+		t.Errorf("expected the changed value counted, got %v", v)
+	}
+
+	// a same-named cluster of another hub is counted on its own
+	NewUnmappedValues("hub2").count("test_label", "cluster1", "rosa")
+	if v := counterValue("rosa"); v != 2 {
+		t.Errorf("expected the value counted once per hub, got %v", v)
+	}
+
+	// the values of a deleted cluster are counted again once it is created
+	u.Forget("cluster1")
+	u.count("test_label", "cluster1", "rosa")
+	if v := counterValue("rosa"); v != 3 {
+		t.Errorf("expected the value of a deleted cluster counted again, got %v", v)
+	}
+	u.Retain(sets.New("cluster1"))
+	u.count("test_label", "cluster1", "rosa")
+	u.count("test_label", "cluster2", "capi")
+	if v, w := counterValue("rosa"), counterValue("capi"); v != 3 || w != 3 {
+		t.Errorf("expected only the values of the missing clusters counted again, got %v and %v", v, w)
+	}
+
+	long := strings.Repeat("x", 2*maxUnmappedValueLength)
+	u.count("test_label", "cluster1", long)
+	if v := counterValue(long[:maxUnmappedValueLength]); v != 1 {
+		t.Errorf("expected the long value truncated, got %v", v)
+	}
+
+	for i := 0; i < maxUnmappedValues; i++ {
+		u.count("test_label", fmt.Sprintf("cluster%d", i), fmt.Sprintf("value%d", i))
+	}
+	if n := u.distinct["test_label"].Len(); n != maxUnmappedValues {
+		t.Errorf("expected %d distinct values, got %d", maxUnmappedValues, n)
+	}
+	if v := counterValue(unmappedValueOverflow); v == 0 {
+		t.Errorf("expected the values beyond the limit counted as %s", unmappedValueOverflow)
+	}
+}
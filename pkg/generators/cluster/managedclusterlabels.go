@@ -5,11 +5,15 @@ package cluster
 
 import (
 	"regexp"
+	"sort"
+	"strings"
 
 	"k8s.io/klog/v2"
 	"k8s.io/kube-state-metrics/pkg/metric"
 
 	mcv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
 )
 
 var (
@@ -19,9 +23,265 @@ var (
 		"hub_cluster_id",
 		"managed_cluster_id",
 	}
+
+	descManagedClusterLabelGroupName = "acm_managed_cluster_label_group"
+	descManagedClusterLabelGroupHelp = "Managed cluster labels of a label group, one series per label"
+
+	descManagedClusterLabelDroppedName = "acm_managed_cluster_labels_dropped"
+	descManagedClusterLabelDroppedHelp = "Number of managed cluster labels not exposed by acm_managed_cluster_labels, " +
+		"either filtered out by the configuration or colliding with another label once sanitized"
+
+	// Regex patterns which is following the Prometheus metric label validation rules.
+	nonWordRegex    = regexp.MustCompile(`[^\w]+`) // Regex to check non-word characters
+	firstDigitRegex = regexp.MustCompile(`^\d`)    // Regex to check if the first character is a digit
+)
+
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:           descManagedClusterLabelInfoName,
+		Help:           descManagedClusterLabelInfoHelp,
+		Type:           metric.Gauge,
+		Labels:         descManagedClusterLabelDefaultLabel,
+		VariableLabels: "One label per label of the managed cluster, named after its renamed or sanitized key",
+		Stability:      generators.StabilityStable,
+		Collector:      generators.CollectorManagedClusters,
+	})
+	generators.Register(generators.FamilyDesc{
+		Name:      descManagedClusterLabelGroupName,
+		Help:      descManagedClusterLabelGroupHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"hub_cluster_id", "managed_cluster_id", "group", "key", "value"},
+		Stability: generators.StabilityAlpha,
+		Collector: generators.CollectorManagedClusters,
+	})
+	generators.Register(generators.FamilyDesc{
+		Name:      descManagedClusterLabelDroppedName,
+		Help:      descManagedClusterLabelDroppedHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"hub_cluster_id", "managed_cluster_id", "reason"},
+		Stability: generators.StabilityAlpha,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
+const (
+	droppedReasonFiltered  = "filtered"
+	droppedReasonCollision = "collision"
 )
 
-func GetManagedClusterLabelMetricFamilies(hubClusterID string) metric.FamilyGenerator {
+// LabelOptions configures the labels of the managed clusters exposed by
+// acm_managed_cluster_labels. A key pattern is a glob whose * matches any
+// sequence of characters.
+type LabelOptions struct {
+	// Allow is the list of the key patterns to expose, all of them if empty.
+	Allow []string
+	// Deny is the list of the key patterns not to expose.
+	Deny []string
+	// Renames map the keys to label names instead of sanitizing them. The
+	// first matching rename is used.
+	Renames []LabelRename
+	// Groups expose the labels matching their key patterns as series of
+	// acm_managed_cluster_label_group instead of labels of
+	// acm_managed_cluster_labels.
+	Groups []LabelGroup
+}
+
+// LabelRename maps the keys matching From to the label name To. A * in To is
+// replaced with the part of the key matched by the single * of From.
+type LabelRename struct {
+	From string
+	To   string
+}
+
+// LabelGroup is a named group of labels.
+type LabelGroup struct {
+	Name string
+	Keys []string
+}
+
+// labelMapper maps the labels of a managed cluster to metric labels.
+type labelMapper struct {
+	allow   []*regexp.Regexp
+	deny    []*regexp.Regexp
+	renames []labelRename
+	groups  []labelGroup
+}
+
+type labelRename struct {
+	from *regexp.Regexp
+	to   string
+}
+
+type labelGroup struct {
+	name string
+	keys []*regexp.Regexp
+}
+
+// mappedLabels are the metric labels of a managed cluster.
+type mappedLabels struct {
+	keys   []string
+	values []string
+	// groups are the labels of each label group
+	groups map[string][]groupedLabel
+	// dropped are the numbers of dropped labels indexed by reason
+	dropped map[string]int
+}
+
+type groupedLabel struct {
+	key   string
+	value string
+}
+
+func newLabelMapper(options LabelOptions) *labelMapper {
+	m := &labelMapper{
+		allow: compileGlobs(options.Allow),
+		deny:  compileGlobs(options.Deny),
+	}
+	for _, rename := range options.Renames {
+		m.renames = append(m.renames, labelRename{from: compileGlob(rename.From), to: rename.To})
+	}
+	for _, group := range options.Groups {
+		m.groups = append(m.groups, labelGroup{name: group.Name, keys: compileGlobs(group.Keys)})
+	}
+	return m
+}
+
+// compileGlob returns a regexp matching the glob, with a group capturing each *.
+func compileGlob(glob string) *regexp.Regexp {
+	return regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(glob), `\*`, "(.*)") + "$")
+}
+
+func compileGlobs(globs []string) []*regexp.Regexp {
+	regexps := []*regexp.Regexp{}
+	for _, glob := range globs {
+		regexps = append(regexps, compileGlob(glob))
+	}
+	return regexps
+}
+
+func matchAny(regexps []*regexp.Regexp, key string) bool {
+	for _, r := range regexps {
+		if r.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapLabels maps the labels of the object named name, skipping the ignored
+// keys. The keys are mapped in order, so the first of the colliding keys wins.
+func (m *labelMapper) mapLabels(name string, labels map[string]string, reservedKeys []string, ignoredKeys ...string) mappedLabels {
+	mapped := mappedLabels{
+		groups:  map[string][]groupedLabel{},
+		dropped: map[string]int{},
+	}
+
+	// Use a map to track already-used label keys for O(1) duplicate detection
+	usedKeys := make(map[string]bool, len(labels)+len(reservedKeys))
+	for _, key := range reservedKeys {
+		usedKeys[key] = true
+	}
+
+	keys := []string{}
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if containsString(ignoredKeys, key) {
+			continue
+		}
+		value := labels[key]
+
+		if (len(m.allow) > 0 && !matchAny(m.allow, key)) || matchAny(m.deny, key) {
+			mapped.dropped[droppedReasonFiltered]++
+			continue
+		}
+
+		if group, ok := m.group(key); ok {
+			mapped.groups[group] = append(mapped.groups[group], groupedLabel{key: key, value: value})
+			continue
+		}
+
+		modifiedKey, renamed := m.rename(key)
+		if !renamed {
+			modifiedKey = sanitizeLabelKey(key)
+		}
+
+		// Check for duplicate or empty label names to prevent invalid Prometheus
+		// metrics, a renamed key is empty once its * matches an empty string
+		if modifiedKey == "" || usedKeys[modifiedKey] {
+			klog.Warningf("Skipping label '%s' (would convert to '%s') - conflicts with existing label. This may indicate duplicate labels on '%s'",
+				key, modifiedKey, name)
+			mapped.dropped[droppedReasonCollision]++
+			continue // Skip this label to avoid Prometheus duplicate label error
+		}
+
+		// If the key was converted, log a warning
+		if !renamed && key != modifiedKey {
+			klog.Infof("Label key '%s' was converted to '%s' since it contains non-word characters or a first digit", key, modifiedKey)
+		}
+
+		// Add the modified key and value to the label slices
+		mapped.keys = append(mapped.keys, modifiedKey)
+		mapped.values = append(mapped.values, value)
+		usedKeys[modifiedKey] = true
+	}
+	return mapped
+}
+
+// group returns the name of the first label group of the key.
+func (m *labelMapper) group(key string) (string, bool) {
+	for _, group := range m.groups {
+		if matchAny(group.keys, key) {
+			return group.name, true
+		}
+	}
+	return "", false
+}
+
+// rename returns the label name of the key from the first matching rename.
+func (m *labelMapper) rename(key string) (string, bool) {
+	for _, rename := range m.renames {
+		submatches := rename.from.FindStringSubmatch(key)
+		if submatches == nil {
+			continue
+		}
+		to := rename.to
+		if len(submatches) == 2 {
+			to = strings.ReplaceAll(to, "*", sanitizeLabelKey(submatches[1]))
+		}
+		return to, true
+	}
+	return "", false
+}
+
+// sanitizeLabelKey converts a key to a valid Prometheus label name.
+func sanitizeLabelKey(key string) string {
+	// Replace non-word characters with underscores
+	// For example, label key velero.io/exclude-from-backup will be replaced with velero_io_exclude_from_backup,
+	modifiedKey := nonWordRegex.ReplaceAllString(key, "_")
+
+	// If the first character is a digit, prepend an underscore
+	// For example, label key 5g-dev01 will be replaced with _5g_dev01.
+	if firstDigitRegex.MatchString(modifiedKey) {
+		modifiedKey = "_" + modifiedKey
+	}
+	return modifiedKey
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func GetManagedClusterLabelMetricFamilies(hubClusterID string, options LabelOptions) metric.FamilyGenerator {
+	mapper := newLabelMapper(options)
 	return metric.FamilyGenerator{
 		Name: descManagedClusterLabelInfoName,
 		Type: metric.Gauge,
@@ -29,66 +289,74 @@ func GetManagedClusterLabelMetricFamilies(hubClusterID string) metric.FamilyGene
 		GenerateFunc: wrapManagedClusterLabelFunc(func(mc *mcv1.ManagedCluster) metric.Family {
 			klog.Infof("Wrap %s", mc.GetName())
 
-			mangedClusterID := getClusterID(mc)
+			// Ignore the clusterID label since it is being set within the hub and managed cluster IDs
+			mapped := mapper.mapLabels(mc.GetName(), mc.Labels, descManagedClusterLabelDefaultLabel, "clusterID")
 
-			labelsKeys := descManagedClusterLabelDefaultLabel
-			labelsValues := []string{
-				hubClusterID,
-				mangedClusterID,
-			}
+			f := metric.Family{Metrics: []*metric.Metric{
+				{
+					LabelKeys:   append(append([]string{}, descManagedClusterLabelDefaultLabel...), mapped.keys...),
+					LabelValues: append([]string{hubClusterID, getClusterID(mc)}, mapped.values...),
+					Value:       1,
+				},
+			}}
 
-			// Regex patterns which is following the Prometheus metric label validation rules.
-			nonWordRegex := regexp.MustCompile(`[^\w]+`) // Regex to check non-word characters
-			firstDigitRegex := regexp.MustCompile(`^\d`) // Regex to check if the first character is a digit
+			klog.V(4).Infof("Returning %v", string(f.ByteSlice()))
+			return f
+		}),
+	}
+}
 
-			// Use a map to track already-used label keys for O(1) duplicate detection
-			usedKeys := make(map[string]bool, len(mc.Labels)+len(descManagedClusterLabelDefaultLabel))
-			for _, key := range descManagedClusterLabelDefaultLabel {
-				usedKeys[key] = true
-			}
+// GetManagedClusterLabelGroupMetricFamilies returns a metric family with a
+// series per label of a label group, so the labels matching a key pattern do
+// not add a label name to acm_managed_cluster_labels each.
+func GetManagedClusterLabelGroupMetricFamilies(hubClusterID string, options LabelOptions) metric.FamilyGenerator {
+	mapper := newLabelMapper(options)
+	return metric.FamilyGenerator{
+		Name: descManagedClusterLabelGroupName,
+		Type: metric.Gauge,
+		Help: descManagedClusterLabelGroupHelp,
+		GenerateFunc: wrapManagedClusterLabelFunc(func(mc *mcv1.ManagedCluster) metric.Family {
+			mapped := mapper.mapLabels(mc.GetName(), mc.Labels, descManagedClusterLabelDefaultLabel, "clusterID")
+			mangedClusterID := getClusterID(mc)
 
-			for key, value := range mc.Labels {
-				// Ignore the clusterID label since it is being set within the hub and managed cluster IDs
-				if key != "clusterID" {
-					// Preserve the original key for logging
-					originalKey := key
-
-					// Replace non-word characters with underscores
-					// For example, label key velero.io/exclude-from-backup will be replaced with velero_io_exclude_from_backup,
-					modifiedKey := nonWordRegex.ReplaceAllString(key, "_")
-
-					// If the first character is a digit, prepend an underscore
-					// For example, label key 5g-dev01 will be replaced with _5g_dev01.
-					if firstDigitRegex.MatchString(modifiedKey) {
-						modifiedKey = "_" + modifiedKey
-					}
-
-					// Check for duplicate label names to prevent invalid Prometheus metrics
-					if usedKeys[modifiedKey] {
-						klog.Warningf("Skipping label '%s' (would convert to '%s') - conflicts with existing label. This may indicate duplicate labels on ManagedCluster '%s'",
-							originalKey, modifiedKey, mc.GetName())
-						continue // Skip this label to avoid Prometheus duplicate label error
-					}
-
-					// If the key was converted, log a warning
-					if originalKey != modifiedKey {
-						klog.Infof("Label key '%s' was converted to '%s' since it contains non-word characters or a first digit", originalKey, modifiedKey)
-					}
-
-					// Add the modified key and value to the label slices
-					labelsKeys = append(labelsKeys, modifiedKey)
-					labelsValues = append(labelsValues, value)
-					usedKeys[modifiedKey] = true
+			f := metric.Family{Metrics: []*metric.Metric{}}
+			for _, group := range options.Groups {
+				for _, label := range mapped.groups[group.Name] {
+					f.Metrics = append(f.Metrics, &metric.Metric{
+						LabelKeys:   []string{"hub_cluster_id", "managed_cluster_id", "group", "key", "value"},
+						LabelValues: []string{hubClusterID, mangedClusterID, group.Name, label.key, label.value},
+						Value:       1,
+					})
 				}
 			}
 
-			f := metric.Family{Metrics: []*metric.Metric{
-				{
-					LabelKeys:   labelsKeys,
-					LabelValues: labelsValues,
-					Value:       1,
-				},
-			}}
+			klog.V(4).Infof("Returning %v", string(f.ByteSlice()))
+			return f
+		}),
+	}
+}
+
+// GetManagedClusterLabelDroppedMetricFamilies returns a metric family with the
+// number of labels of each managed cluster not exposed by
+// acm_managed_cluster_labels, by reason.
+func GetManagedClusterLabelDroppedMetricFamilies(hubClusterID string, options LabelOptions) metric.FamilyGenerator {
+	mapper := newLabelMapper(options)
+	return metric.FamilyGenerator{
+		Name: descManagedClusterLabelDroppedName,
+		Type: metric.Gauge,
+		Help: descManagedClusterLabelDroppedHelp,
+		GenerateFunc: wrapManagedClusterLabelFunc(func(mc *mcv1.ManagedCluster) metric.Family {
+			mapped := mapper.mapLabels(mc.GetName(), mc.Labels, descManagedClusterLabelDefaultLabel, "clusterID")
+			mangedClusterID := getClusterID(mc)
+
+			f := metric.Family{Metrics: []*metric.Metric{}}
+			for _, reason := range []string{droppedReasonFiltered, droppedReasonCollision} {
+				f.Metrics = append(f.Metrics, &metric.Metric{
+					LabelKeys:   []string{"hub_cluster_id", "managed_cluster_id", "reason"},
+					LabelValues: []string{hubClusterID, mangedClusterID, reason},
+					Value:       float64(mapped.dropped[reason]),
+				})
+			}
 
 			klog.V(4).Infof("Returning %v", string(f.ByteSlice()))
 			return f
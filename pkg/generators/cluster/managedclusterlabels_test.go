@@ -109,7 +109,7 @@ func Test_getManagedClusterLabelMetricFamilies(t *testing.T) {
 	for i, c := range tests {
 		t.Run(c.Name, func(t *testing.T) {
 			c.Func = metric.ComposeMetricGenFuncs(
-				[]metric.FamilyGenerator{GetManagedClusterLabelMetricFamilies("hub_cluster_id")},
+				[]metric.FamilyGenerator{GetManagedClusterLabelMetricFamilies("hub_cluster_id", LabelOptions{})},
 			)
 			if err := c.Run(); err != nil {
 				t.Errorf("unexpected collecting result in %v run:\n%s", i, err)
@@ -117,3 +117,114 @@ func Test_getManagedClusterLabelMetricFamilies(t *testing.T) {
 		})
 	}
 }
+
+func Test_getManagedClusterLabelMetricFamilies_Options(t *testing.T) {
+	mc := &mcv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-cluster",
+			Labels: map[string]string{
+				mciv1beta1.LabelClusterID:                                      "managed_cluster_id",
+				mciv1beta1.LabelCloudVendor:                                    string(mciv1beta1.CloudVendorAWS),
+				mciv1beta1.LabelKubeVendor:                                     string(mciv1beta1.KubeVendorAKS),
+				"velero.io/exclude":                                            "true",
+				"feature.open-cluster-management.io/addon-work-manager":        "available",
+				"feature.open-cluster-management.io/addon-application-manager": "unhealthy",
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		options LabelOptions
+		want    string
+	}{
+		{
+			name:    "allow one label",
+			options: LabelOptions{Allow: []string{"cloud"}},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",cloud="Amazon"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 4
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 0`,
+		},
+		{
+			name:    "allow labels by original key",
+			options: LabelOptions{Allow: []string{"vendor", "velero.io/exclude"}},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",vendor="AKS",velero_io_exclude="true"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 3
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 0`,
+		},
+		{
+			name:    "deny labels by glob",
+			options: LabelOptions{Deny: []string{"feature.open-cluster-management.io/*", "velero.io/*"}},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",cloud="Amazon",vendor="AKS"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 3
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 0`,
+		},
+		{
+			name: "rename labels",
+			options: LabelOptions{
+				Allow: []string{"cloud", "feature.open-cluster-management.io/*"},
+				Renames: []LabelRename{
+					{From: "cloud", To: "cloud_vendor"},
+					{From: "feature.open-cluster-management.io/addon-*", To: "addon_*"},
+				},
+			},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",cloud_vendor="Amazon",addon_application_manager="unhealthy",addon_work_manager="available"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 2
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 0`,
+		},
+		{
+			name: "colliding renames",
+			options: LabelOptions{
+				Allow:   []string{"feature.open-cluster-management.io/*"},
+				Renames: []LabelRename{{From: "feature.open-cluster-management.io/*", To: "feature"}},
+			},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",feature="unhealthy"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 3
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 1`,
+		},
+		{
+			name: "renames to an empty label name",
+			options: LabelOptions{
+				Allow:   []string{"vendor", "velero.io/*"},
+				Renames: []LabelRename{{From: "vendor*", To: "*"}, {From: "velero.io/*", To: "velero_*"}},
+			},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",velero_exclude="true"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 3
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 1`,
+		},
+		{
+			name: "group labels",
+			options: LabelOptions{
+				Deny:   []string{"velero.io/*"},
+				Groups: []LabelGroup{{Name: "addons", Keys: []string{"feature.open-cluster-management.io/addon-*"}}},
+			},
+			want: `acm_managed_cluster_labels{managed_cluster_id="managed_cluster_id",hub_cluster_id="hub_cluster_id",cloud="Amazon",vendor="AKS"} 1
+acm_managed_cluster_label_group{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",group="addons",key="feature.open-cluster-management.io/addon-application-manager",value="unhealthy"} 1
+acm_managed_cluster_label_group{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",group="addons",key="feature.open-cluster-management.io/addon-work-manager",value="available"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="filtered"} 1
+acm_managed_cluster_labels_dropped{hub_cluster_id="hub_cluster_id",managed_cluster_id="managed_cluster_id",reason="collision"} 0`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := testcommon.GenerateMetricsTestCase{
+				Obj: mc,
+				MetricNames: []string{
+					"acm_managed_cluster_labels",
+					"acm_managed_cluster_label_group",
+					"acm_managed_cluster_labels_dropped",
+				},
+				Want: test.want,
+				Func: metric.ComposeMetricGenFuncs([]metric.FamilyGenerator{
+					GetManagedClusterLabelMetricFamilies("hub_cluster_id", test.options),
+					GetManagedClusterLabelGroupMetricFamilies("hub_cluster_id", test.options),
+					GetManagedClusterLabelDroppedMetricFamilies("hub_cluster_id", test.options),
+				}),
+			}
+			if err := c.Run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+		})
+	}
+}
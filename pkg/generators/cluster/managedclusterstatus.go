@@ -20,6 +20,17 @@ var (
 	}
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descClusterStatusName,
+		Help:      descClusterStatusHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"managed_cluster_id", "managed_cluster_name", "condition", "status"},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
 func GetManagedClusterStatusMetricFamilies() metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descClusterStatusName,
@@ -4,6 +4,8 @@
 package cluster
 
 import (
+	"sort"
+
 	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,6 +20,17 @@ var (
 	hostingClusterNameAnnotation string = "import.open-cluster-management.io/hosting-cluster-name"
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descTimestampName,
+		Help:      descTimestampHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"status", "managed_cluster_name", "managed_cluster_id", "hosting_cluster_name"},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
 func GetManagedClusterTimestampMetricFamilies(hubClusterID string,
 	getClusterTimestamps func(clusterName string) map[string]float64) metric.FamilyGenerator {
 	return metric.FamilyGenerator{
@@ -83,7 +96,14 @@ func buildManagedClusterTimestampMetricFamily(mc *mcv1.ManagedCluster, labelKeys
 		return family
 	}
 
-	for status, timestamp := range timestamps {
+	// sort the statuses, so the order of the metrics is stable
+	statuses := make([]string, 0, len(timestamps))
+	for status := range timestamps {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		timestamp := timestamps[status]
 		family.Metrics = append(family.Metrics,
 			&metric.Metric{
 				// do not use 'LabelValues: append(labelValues, status),',
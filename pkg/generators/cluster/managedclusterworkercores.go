@@ -8,6 +8,8 @@ import (
 
 	"k8s.io/klog/v2"
 	mcv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
 )
 
 var (
@@ -18,6 +20,17 @@ var (
 	}
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descWorkerCoresName,
+		Help:      descWorkerCoresHelp,
+		Type:      metric.Gauge,
+		Labels:    descWorkerCoresDefaultLabels,
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManagedClusters,
+	})
+}
+
 func GetManagedClusterWorkerCoresMetricFamilies(hubClusterID string, isHibernatingFn func(string) bool) metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descWorkerCoresName,
@@ -0,0 +1,238 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package customresource
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+)
+
+const defaultStateLabelName = "state"
+
+// GetCustomResourceMetricFamilies returns the metric families of a custom
+// resource described by the configuration. Each metric has the name and
+// namespace of the object, and the name and ID of the managed cluster the
+// object belongs to if the cluster is known: lookupClusterIdFunc returns the
+// ID of a cluster and whether it is known.
+func GetCustomResourceMetricFamilies(c config.CustomResourceConfig, lookupClusterIdFunc func(string) (string, bool)) []metric.FamilyGenerator {
+	families := []metric.FamilyGenerator{}
+	for _, m := range c.Metrics {
+		families = append(families, newFamilyGenerator(m, lookupClusterIdFunc))
+	}
+	return families
+}
+
+func newFamilyGenerator(m config.CustomResourceMetricConfig, lookupClusterIdFunc func(string) (string, bool)) metric.FamilyGenerator {
+	help := m.Help
+	if help == "" {
+		help = fmt.Sprintf("%s of the custom resource", m.Name)
+	}
+
+	// sort the labels from path to generate stable label keys
+	labelNames := []string{}
+	for name := range m.LabelsFromPath {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	return metric.FamilyGenerator{
+		Name: m.Name,
+		Type: metric.Gauge,
+		Help: help,
+		GenerateFunc: func(obj interface{}) *metric.Family {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				klog.Errorf("Invalid custom resource: %v", obj)
+				return &metric.Family{Metrics: []*metric.Metric{}}
+			}
+
+			keys, values := objectLabels(u, lookupClusterIdFunc)
+			for _, name := range labelNames {
+				value, _ := resolvePath(u.Object, m.LabelsFromPath[name])
+				keys = append(keys, name)
+				values = append(values, labelValue(value))
+			}
+
+			f := metric.Family{Metrics: generateMetrics(m, u, keys, values)}
+			klog.V(4).Infof("Returning %v", string(f.ByteSlice()))
+			return &f
+		},
+	}
+}
+
+// objectLabels returns the labels identifying the object and its managed
+// cluster. The managed cluster is named after the namespace of the object, or
+// after a cluster-scoped object, and its labels are only set if the cluster is
+// known, so an object out of the namespace of a cluster, such as a root
+// Policy, does not belong to a bogus cluster.
+func objectLabels(u *unstructured.Unstructured, lookupClusterIdFunc func(string) (string, bool)) ([]string, []string) {
+	keys := []string{"name"}
+	values := []string{u.GetName()}
+	clusterName := u.GetName()
+	if namespace := u.GetNamespace(); len(namespace) > 0 {
+		keys = append(keys, "namespace")
+		values = append(values, namespace)
+		clusterName = namespace
+	}
+	clusterId, ok := lookupClusterIdFunc(clusterName)
+	if !ok {
+		return keys, values
+	}
+	if len(clusterId) > 0 {
+		keys = append(keys, "managed_cluster_id")
+		values = append(values, clusterId)
+	}
+	keys = append(keys, "managed_cluster_name")
+	values = append(values, clusterName)
+	return keys, values
+}
+
+func generateMetrics(m config.CustomResourceMetricConfig, u *unstructured.Unstructured, keys, values []string) []*metric.Metric {
+	switch m.Type {
+	case config.MetricTypeGauge:
+		raw, found := resolvePath(u.Object, m.Path)
+		if !found || raw == nil {
+			if !m.NilIsZero {
+				return []*metric.Metric{}
+			}
+			raw = 0.0
+		}
+		value, err := toFloat64(raw)
+		if err != nil {
+			klog.V(2).Infof("Skip metric %s of %s/%s: %v", m.Name, u.GetNamespace(), u.GetName(), err)
+			return []*metric.Metric{}
+		}
+		return []*metric.Metric{{LabelKeys: keys, LabelValues: values, Value: value}}
+	case config.MetricTypeInfo:
+		return []*metric.Metric{{LabelKeys: keys, LabelValues: values, Value: 1}}
+	case config.MetricTypeStateSet:
+		raw, _ := resolvePath(u.Object, m.Path)
+		state := labelValue(raw)
+		labelName := m.LabelName
+		if labelName == "" {
+			labelName = defaultStateLabelName
+		}
+		metrics := []*metric.Metric{}
+		for _, s := range m.List {
+			value := 0.0
+			if s == state {
+				value = 1
+			}
+			metrics = append(metrics, &metric.Metric{
+				LabelKeys:   append(append([]string{}, keys...), labelName),
+				LabelValues: append(append([]string{}, values...), s),
+				Value:       value,
+			})
+		}
+		return metrics
+	default:
+		return []*metric.Metric{}
+	}
+}
+
+// resolvePath returns the value at the path of the object. A field of a map
+// is selected by its name, and an element of a list by its index or by a
+// "[key=value]" field match.
+func resolvePath(obj interface{}, path []string) (interface{}, bool) {
+	current := obj
+	for _, element := range path {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			value, ok := v[element]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			value, ok := selectListElement(v, element)
+			if !ok {
+				return nil, false
+			}
+			current = value
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func selectListElement(list []interface{}, element string) (interface{}, bool) {
+	if strings.HasPrefix(element, "[") && strings.HasSuffix(element, "]") {
+		key, value, ok := strings.Cut(element[1:len(element)-1], "=")
+		if !ok {
+			return nil, false
+		}
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok && labelValue(m[key]) == value {
+				return item, true
+			}
+		}
+		return nil, false
+	}
+
+	index, err := strconv.Atoi(element)
+	if err != nil || index < 0 || index >= len(list) {
+		return nil, false
+	}
+	return list[index], true
+}
+
+// labelValue returns the string of a scalar value, or an empty string.
+func labelValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, int64, int32, int, float64:
+		return fmt.Sprint(v)
+	default:
+		return ""
+	}
+}
+
+// toFloat64 converts the value of a Gauge. Booleans are 1 or 0 and RFC 3339
+// timestamps are seconds since the epoch.
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return 1, nil
+		case "false":
+			return 0, nil
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(f) {
+			return f, nil
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return float64(t.Unix()), nil
+		}
+		return 0, fmt.Errorf("cannot convert %q to a number", v)
+	default:
+		return 0, fmt.Errorf("cannot convert %v to a number", value)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package customresource
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+)
+
+func newTestPolicy() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "policy.open-cluster-management.io/v1",
+		"kind":       "Policy",
+		"metadata": map[string]interface{}{
+			"name":      "default.policy-pod",
+			"namespace": "cluster1",
+		},
+		"spec": map[string]interface{}{
+			"disabled":          false,
+			"remediationAction": "inform",
+		},
+		"status": map[string]interface{}{
+			"compliant":       "NonCompliant",
+			"lastTimestamp":   "2024-01-02T03:04:05Z",
+			"violationsCount": int64(3),
+			"details": []interface{}{
+				map[string]interface{}{"templateName": "pod", "compliant": "Compliant"},
+				map[string]interface{}{"templateName": "namespace", "compliant": "NonCompliant"},
+			},
+		},
+	}}
+}
+
+func Test_GetCustomResourceMetricFamilies(t *testing.T) {
+	lookupClusterId := func(clusterName string) (string, bool) {
+		switch clusterName {
+		case "cluster1":
+			return "cluster1-id", true
+		case "cluster2":
+			return "", true
+		}
+		return "", false
+	}
+	labels := `name="default.policy-pod",namespace="cluster1",managed_cluster_id="cluster1-id",managed_cluster_name="cluster1"`
+
+	tests := []struct {
+		name   string
+		obj    interface{}
+		metric config.CustomResourceMetricConfig
+		want   string
+	}{
+		{
+			name: "gauge",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_violations",
+				Type: config.MetricTypeGauge,
+				Path: []string{"status", "violationsCount"},
+			},
+			want: `acm_policy_violations{` + labels + `} 3`,
+		},
+		{
+			name: "gauge of a timestamp",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_last_timestamp",
+				Type: config.MetricTypeGauge,
+				Path: []string{"status", "lastTimestamp"},
+			},
+			want: `acm_policy_last_timestamp{` + labels + `} 1.704164645e+09`,
+		},
+		{
+			name: "gauge of a boolean",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_disabled",
+				Type: config.MetricTypeGauge,
+				Path: []string{"spec", "disabled"},
+			},
+			want: `acm_policy_disabled{` + labels + `} 0`,
+		},
+		{
+			name: "gauge of a missing path",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_missing",
+				Type: config.MetricTypeGauge,
+				Path: []string{"status", "missing"},
+			},
+			want: ``,
+		},
+		{
+			name: "gauge of a missing path with nil is zero",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name:      "acm_policy_missing",
+				Type:      config.MetricTypeGauge,
+				Path:      []string{"status", "missing"},
+				NilIsZero: true,
+			},
+			want: `acm_policy_missing{` + labels + `} 0`,
+		},
+		{
+			name: "info with labels from path",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_info",
+				Type: config.MetricTypeInfo,
+				LabelsFromPath: map[string][]string{
+					"remediation_action": {"spec", "remediationAction"},
+					"namespace_template": {"status", "details", "[templateName=namespace]", "compliant"},
+					"first_template":     {"status", "details", "0", "templateName"},
+				},
+			},
+			want: `acm_policy_info{` + labels + `,first_template="pod",namespace_template="NonCompliant",remediation_action="inform"} 1`,
+		},
+		{
+			name: "stateset",
+			obj:  newTestPolicy(),
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_compliance",
+				Type: config.MetricTypeStateSet,
+				Path: []string{"status", "compliant"},
+				List: []string{"Compliant", "NonCompliant", "Pending"},
+			},
+			want: `acm_policy_compliance{` + labels + `,state="Compliant"} 0
+acm_policy_compliance{` + labels + `,state="NonCompliant"} 1
+acm_policy_compliance{` + labels + `,state="Pending"} 0`,
+		},
+		{
+			name: "cluster-scoped resource",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "cluster2"},
+			}},
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_cluster_info",
+				Type: config.MetricTypeInfo,
+			},
+			want: `acm_cluster_info{name="cluster2",managed_cluster_name="cluster2"} 1`,
+		},
+		{
+			name: "cluster-scoped resource of no cluster",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "vendor-config"},
+			}},
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_cluster_info",
+				Type: config.MetricTypeInfo,
+			},
+			want: `acm_cluster_info{name="vendor-config"} 1`,
+		},
+		{
+			name: "resource out of the namespace of a cluster",
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "policy-pod", "namespace": "policies"},
+				"status":   map[string]interface{}{"compliant": "Compliant"},
+			}},
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_info",
+				Type: config.MetricTypeInfo,
+			},
+			want: `acm_policy_info{name="policy-pod",namespace="policies"} 1`,
+		},
+		{
+			name: "invalid object",
+			obj:  "invalid",
+			metric: config.CustomResourceMetricConfig{
+				Name: "acm_policy_info",
+				Type: config.MetricTypeInfo,
+			},
+			want: ``,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := testcommon.GenerateMetricsTestCase{
+				Obj:         test.obj,
+				MetricNames: []string{test.metric.Name},
+				Want:        test.want,
+				Func: metric.ComposeMetricGenFuncs(GetCustomResourceMetricFamilies(
+					config.CustomResourceConfig{Metrics: []config.CustomResourceMetricConfig{test.metric}},
+					lookupClusterId,
+				)),
+			}
+			if err := c.Run(); err != nil {
+				t.Errorf("unexpected collecting result:\n%s", err)
+			}
+		})
+	}
+}
+
+func Test_toFloat64(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    float64
+		wantErr bool
+	}{
+		{value: 1.5, want: 1.5},
+		{value: int64(2), want: 2},
+		{value: true, want: 1},
+		{value: "True", want: 1},
+		{value: "false", want: 0},
+		{value: "3.25", want: 3.25},
+		{value: "1970-01-01T00:01:00Z", want: 60},
+		{value: "Ready", wantErr: true},
+		{value: map[string]interface{}{}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := toFloat64(test.value)
+		if test.wantErr != (err != nil) {
+			t.Errorf("toFloat64(%v): unexpected error %v", test.value, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("toFloat64(%v): expected %v, got %v", test.value, test.want, got)
+		}
+	}
+}
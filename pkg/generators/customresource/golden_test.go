@@ -0,0 +1,60 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package customresource
+
+import (
+	"testing"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+)
+
+// Test_GoldenMetrics compares the metrics of the custom resources of
+// testdata/*.yaml with the golden files beside them. Run
+// UPDATE_GOLDEN=1 go test ./pkg/generators/customresource -run Test_GoldenMetrics
+// to regenerate them.
+func Test_GoldenMetrics(t *testing.T) {
+	// the root policies of the policies namespace do not belong to a managed cluster
+	lookupClusterID := func(clusterName string) (string, bool) {
+		if clusterName == "policies" {
+			return "", false
+		}
+		return clusterName + "-id", true
+	}
+
+	c := config.CustomResourceConfig{
+		Group:    "policy.open-cluster-management.io",
+		Version:  "v1",
+		Resource: "policies",
+		Metrics: []config.CustomResourceMetricConfig{
+			{
+				Name: "acm_policy_violations",
+				Type: config.MetricTypeGauge,
+				Path: []string{"status", "violationsCount"},
+			},
+			{
+				Name: "acm_policy_last_timestamp",
+				Type: config.MetricTypeGauge,
+				Path: []string{"status", "lastTimestamp"},
+			},
+			{
+				Name: "acm_policy_info",
+				Help: "Policy information",
+				Type: config.MetricTypeInfo,
+				LabelsFromPath: map[string][]string{
+					"remediation_action": {"spec", "remediationAction"},
+					"disabled":           {"spec", "disabled"},
+					"first_template":     {"status", "details", "0", "templateName"},
+				},
+			},
+			{
+				Name: "acm_policy_compliance",
+				Type: config.MetricTypeStateSet,
+				Path: []string{"status", "compliant"},
+				List: []string{"Compliant", "NonCompliant", "Pending"},
+			},
+		},
+	}
+
+	testcommon.RunGoldenTests(t, "testdata", "policies", GetCustomResourceMetricFamilies(c, lookupClusterID))
+}
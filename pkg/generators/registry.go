@@ -0,0 +1,91 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package generators
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+// StabilityLevel is the stability level of a metric family. A stable family is
+// not renamed and its labels are not removed, an alpha family may change.
+type StabilityLevel string
+
+const (
+	StabilityStable StabilityLevel = "STABLE"
+	StabilityAlpha  StabilityLevel = "ALPHA"
+)
+
+// The collectors exposing the metric families.
+const (
+	CollectorManagedClusters      = "managedclusters"
+	CollectorManagedClusterAddOns = "managedclusteraddons"
+	CollectorManifestWorks        = "manifestworks"
+)
+
+// FamilyDesc describes a metric family exposed by a collector.
+type FamilyDesc struct {
+	Name string      `json:"name"`
+	Help string      `json:"help"`
+	Type metric.Type `json:"type"`
+	// Labels are the label names of the family. Some of them are only set if
+	// they are known, such as managed_cluster_id.
+	Labels []string `json:"labels"`
+	// VariableLabels describes the labels named after the resources, if any.
+	VariableLabels string         `json:"variableLabels,omitempty"`
+	Stability      StabilityLevel `json:"stability"`
+	// Collector is the collector exposing the family.
+	Collector string `json:"collector"`
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[string]FamilyDesc{}
+)
+
+// Register registers the description of a metric family. It is called by the
+// packages of the generators once initialized, and panics if the family is
+// already registered.
+func Register(desc FamilyDesc) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if desc.Name == "" || desc.Collector == "" {
+		panic(fmt.Sprintf("metric family %q should have a name and a collector", desc.Name))
+	}
+	if _, ok := registry[desc.Name]; ok {
+		panic(fmt.Sprintf("metric family %q is already registered", desc.Name))
+	}
+	registry[desc.Name] = desc
+}
+
+// RegisteredFamily returns the description of the registered metric family with the given name.
+func RegisteredFamily(name string) (FamilyDesc, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	desc, ok := registry[name]
+	return desc, ok
+}
+
+// RegisteredFamilies returns the descriptions of all registered metric
+// families, sorted by collector and name.
+func RegisteredFamilies() []FamilyDesc {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	families := make([]FamilyDesc, 0, len(registry))
+	for _, desc := range registry {
+		families = append(families, desc)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		if families[i].Collector != families[j].Collector {
+			return families[i].Collector < families[j].Collector
+		}
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
@@ -0,0 +1,51 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package generators
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+)
+
+func TestRegister(t *testing.T) {
+	families := []FamilyDesc{
+		{Name: "test_work_count", Type: metric.Gauge, Collector: CollectorManifestWorks},
+		{Name: "test_cluster_info", Type: metric.Gauge, Collector: CollectorManagedClusters},
+		{Name: "test_cluster_count", Type: metric.Gauge, Collector: CollectorManagedClusters},
+	}
+	for _, desc := range families {
+		Register(desc)
+	}
+
+	want := []string{"test_cluster_count", "test_cluster_info", "test_work_count"}
+	got := []string{}
+	for _, desc := range RegisteredFamilies() {
+		got = append(got, desc.Name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RegisteredFamilies() = %v, want %v", got, want)
+	}
+
+	if desc, ok := RegisteredFamily("test_cluster_info"); !ok || desc.Collector != CollectorManagedClusters {
+		t.Errorf("RegisteredFamily() = %v, %v", desc, ok)
+	}
+	if _, ok := RegisteredFamily("test_unknown"); ok {
+		t.Errorf("expected test_unknown not to be registered")
+	}
+
+	for _, desc := range []FamilyDesc{
+		families[0],
+		{Name: "test_no_collector"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected Register(%q) to panic", desc.Name)
+				}
+			}()
+			Register(desc)
+		}()
+	}
+}
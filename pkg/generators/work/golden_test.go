@@ -0,0 +1,41 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package work
+
+import (
+	"testing"
+
+	"k8s.io/kube-state-metrics/pkg/metric"
+
+	testcommon "github.com/stolostron/clusterlifecycle-state-metrics/test/unit/common"
+)
+
+// Test_GoldenMetrics compares the metrics of the ManifestWorks of
+// testdata/*.yaml with the golden files beside them. Run
+// UPDATE_GOLDEN=1 go test ./pkg/generators/work -run Test_GoldenMetrics
+// to regenerate them.
+func Test_GoldenMetrics(t *testing.T) {
+	getClusterID := func(clusterName string) string {
+		return clusterName + "-id"
+	}
+
+	tests := []struct {
+		name     string
+		families []metric.FamilyGenerator
+	}{
+		{
+			name:     "status",
+			families: []metric.FamilyGenerator{GetManifestWorkStatusMetricFamilies(getClusterID)},
+		},
+		{
+			name:     "timestamp",
+			families: []metric.FamilyGenerator{GetManifestWorkTimestampMetricFamilies(getClusterID)},
+		},
+	}
+
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			testcommon.RunGoldenTests(t, "testdata", c.name, c.families)
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"k8s.io/kube-state-metrics/pkg/metric"
 
 	"k8s.io/klog/v2"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/generators"
 )
 
 var (
@@ -14,6 +16,17 @@ var (
 	descWorkCountHelp = "ManifestWork count"
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descWorkCountName,
+		Help:      descWorkCountHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManifestWorks,
+	})
+}
+
 func GetManifestWorkCountMetricFamilies() metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descWorkCountName,
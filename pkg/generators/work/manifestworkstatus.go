@@ -21,6 +21,17 @@ var (
 	}
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descWorkStatusName,
+		Help:      descWorkStatusHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"manifestwork", "managed_cluster_id", "managed_cluster_name", "condition", "status"},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManifestWorks,
+	})
+}
+
 func GetManifestWorkStatusMetricFamilies(getClusterIdFunc func(string) string) metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descWorkStatusName,
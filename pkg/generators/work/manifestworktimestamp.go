@@ -18,6 +18,17 @@ var (
 	descWorkTimestampHelp = "The timestamp of the manifestwork appled"
 )
 
+func init() {
+	generators.Register(generators.FamilyDesc{
+		Name:      descWorkTimestampName,
+		Help:      descWorkTimestampHelp,
+		Type:      metric.Gauge,
+		Labels:    []string{"status", "manifestwork", "managed_cluster_name", "hosted_cluster_name", "managed_cluster_id"},
+		Stability: generators.StabilityStable,
+		Collector: generators.CollectorManifestWorks,
+	})
+}
+
 func GetManifestWorkTimestampMetricFamilies(getClusterIdFunc func(string) string) metric.FamilyGenerator {
 	return metric.FamilyGenerator{
 		Name: descWorkTimestampName,
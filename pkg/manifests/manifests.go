@@ -0,0 +1,152 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package manifests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+var clusterVersionGroupKind = schema.GroupKind{Group: "config.openshift.io", Kind: "ClusterVersion"}
+
+// newTypedObject returns an empty typed object of the kinds with a generator,
+// or nil for the other kinds.
+func newTypedObject(gk schema.GroupKind) runtime.Object {
+	switch gk {
+	case schema.GroupKind{Group: mcv1.GroupName, Kind: "ManagedCluster"}:
+		return &mcv1.ManagedCluster{}
+	case schema.GroupKind{Group: addonv1alpha1.GroupName, Kind: "ManagedClusterAddOn"}:
+		return &addonv1alpha1.ManagedClusterAddOn{}
+	case schema.GroupKind{Group: workv1.GroupName, Kind: "ManifestWork"}:
+		return &workv1.ManifestWork{}
+	default:
+		return nil
+	}
+}
+
+// Load reads the objects of the YAML or JSON files in the path, which is a file
+// or a directory walked recursively such as a must-gather. A file may hold
+// several documents and lists of objects. The ManagedClusters,
+// ManagedClusterAddOns and ManifestWorks are returned as typed objects, and the
+// other objects as unstructured. The files which cannot be decoded are skipped.
+func Load(path string) ([]runtime.Object, error) {
+	objects := []runtime.Object{}
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isManifest(file) {
+			return nil
+		}
+
+		fileObjects, err := loadFile(file)
+		if err != nil {
+			klog.Warningf("Skip file %s: %v", file, err)
+			return nil
+		}
+		objects = append(objects, fileObjects...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func isManifest(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func loadFile(file string) ([]runtime.Object, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	objects := []runtime.Object{}
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		raw := runtime.RawExtension{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		// skip the empty documents and the files which are not manifests
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+		decoded, _, err := unstructured.UnstructuredJSONScheme.Decode(raw.Raw, nil, nil)
+		if runtime.IsMissingKind(err) || runtime.IsMissingVersion(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch u := decoded.(type) {
+		case *unstructured.Unstructured:
+			obj, err := toObject(u)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, obj)
+		case *unstructured.UnstructuredList:
+			for index := range u.Items {
+				obj, err := toObject(&u.Items[index])
+				if err != nil {
+					return nil, err
+				}
+				objects = append(objects, obj)
+			}
+		}
+	}
+}
+
+// toObject converts the unstructured object to a typed object if its kind has
+// a generator.
+func toObject(u *unstructured.Unstructured) (runtime.Object, error) {
+	obj := newTypedObject(u.GroupVersionKind().GroupKind())
+	if obj == nil {
+		return u, nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj); err != nil {
+		return nil, fmt.Errorf("cannot convert %s %s/%s: %v", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	return obj, nil
+}
+
+// HubClusterID returns the cluster ID of the ClusterVersion of the hub in the
+// objects, or an empty string if there is none.
+func HubClusterID(objects []runtime.Object) string {
+	for _, obj := range objects {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || u.GroupVersionKind().GroupKind() != clusterVersionGroupKind || u.GetName() != "version" {
+			continue
+		}
+		clusterID, _, _ := unstructured.NestedString(u.Object, "spec", "clusterID")
+		return clusterID
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package manifests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Load(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "cluster-scoped-resources", "managedclusters.yaml"), `
+apiVersion: cluster.open-cluster-management.io/v1
+kind: ManagedCluster
+metadata:
+  name: cluster1
+  labels:
+    clusterID: cluster1-id
+spec:
+  hubAcceptsClient: true
+---
+apiVersion: cluster.open-cluster-management.io/v1
+kind: ManagedCluster
+metadata:
+  name: cluster2
+status:
+  capacity:
+    core_worker: 4
+`)
+	writeFile(t, filepath.Join(dir, "namespaces", "cluster1", "list.json"), `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "addon.open-cluster-management.io/v1alpha1", "kind": "ManagedClusterAddOn",
+     "metadata": {"name": "work-manager", "namespace": "cluster1"}},
+    {"apiVersion": "work.open-cluster-management.io/v1", "kind": "ManifestWork",
+     "metadata": {"name": "work1", "namespace": "cluster1"}},
+    {"apiVersion": "hive.openshift.io/v1", "kind": "ClusterDeployment",
+     "metadata": {"name": "cluster1", "namespace": "cluster1"}}
+  ]
+}`)
+	writeFile(t, filepath.Join(dir, "cluster-scoped-resources", "clusterversion.yml"), `
+apiVersion: config.openshift.io/v1
+kind: ClusterVersion
+metadata:
+  name: version
+spec:
+  clusterID: hub-id
+`)
+	writeFile(t, filepath.Join(dir, "invalid.yaml"), "key: [unclosed")
+	writeFile(t, filepath.Join(dir, "not-a-manifest.yaml"), "key: value")
+	writeFile(t, filepath.Join(dir, "ignored.txt"), "apiVersion: v1\nkind: ConfigMap")
+
+	objects, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *mcv1.ManagedCluster:
+			counts["ManagedCluster"]++
+			if q := o.Status.Capacity["core_worker"]; o.Name == "cluster2" && q.Value() != 4 {
+				t.Errorf("unexpected capacity %v", o.Status.Capacity)
+			}
+		case *addonv1alpha1.ManagedClusterAddOn:
+			counts["ManagedClusterAddOn"]++
+		case *workv1.ManifestWork:
+			counts["ManifestWork"]++
+		case *unstructured.Unstructured:
+			counts[o.GetKind()]++
+		}
+	}
+	want := map[string]int{
+		"ManagedCluster":      2,
+		"ManagedClusterAddOn": 1,
+		"ManifestWork":        1,
+		"ClusterDeployment":   1,
+		"ClusterVersion":      1,
+	}
+	for kind, count := range want {
+		if counts[kind] != count {
+			t.Errorf("expected %d %s, got %d", count, kind, counts[kind])
+		}
+	}
+	if len(objects) != 6 {
+		t.Errorf("expected 6 objects, got %d", len(objects))
+	}
+
+	if id := HubClusterID(objects); id != "hub-id" {
+		t.Errorf("expected hub cluster ID %q, got %q", "hub-id", id)
+	}
+	if id := HubClusterID([]runtime.Object{}); id != "" {
+		t.Errorf("expected no hub cluster ID, got %q", id)
+	}
+}
+
+func Test_Load_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cluster.yaml")
+	writeFile(t, file, `
+apiVersion: cluster.open-cluster-management.io/v1
+kind: ManagedCluster
+metadata:
+  name: cluster1
+`)
+	objects, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Errorf("expected 1 object, got %d", len(objects))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected an error for a missing path")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package options
+
+import (
+	"flag"
+
+	koptions "k8s.io/kube-state-metrics/pkg/options"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
+)
+
+// ApplyConfig applies the configuration to the options which are not set by a
+// command-line flag, so the flags take precedence over the configuration.
+func (o *Options) ApplyConfig(c *config.Configuration) {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	o.applyConfig(c, set)
+}
+
+// applyConfig applies the configuration to the options whose flags are not in set.
+func (o *Options) applyConfig(c *config.Configuration, set map[string]bool) {
+	o.Selectors = map[string]string{}
+	if !set["collectors"] {
+		o.Collectors = koptions.CollectorSet{}
+	}
+	for _, collector := range c.Collectors {
+		if !set["collectors"] {
+			o.Collectors[collector.Name] = struct{}{}
+		}
+		if collector.Selector != "" {
+			o.Selectors[collector.Name] = collector.Selector
+		}
+	}
+
+	if !set["metric-whitelist"] && !set["metric-blacklist"] {
+		o.MetricWhitelist = koptions.MetricSet{}
+		for _, name := range c.Metrics.Allow {
+			o.MetricWhitelist[name] = struct{}{}
+		}
+		o.MetricBlacklist = koptions.MetricSet{}
+		for _, name := range c.Metrics.Deny {
+			o.MetricBlacklist[name] = struct{}{}
+		}
+	}
+
+	o.ManagedClusterLabels = c.ManagedClusterLabels
+	o.ManagedClusterInfo = c.ManagedClusterInfo
+	o.ManagedClusterAnnotationAllowlist = c.ManagedClusterAnnotations.Allow
+	o.TimestampMetricsEnabled = c.TimestampMetrics.Enabled
+	o.CustomResources = c.CustomResources
+	seriesPerFamily := o.SeriesLimits.SeriesPerFamily
+	o.SeriesLimits = c.Limits
+	if set["series-limit-per-family"] {
+		o.SeriesLimits.SeriesPerFamily = seriesPerFamily
+	}
+
+	s := c.Serving
+	setString := func(name string, value *string, configValue string) {
+		if !set[name] {
+			*value = configValue
+		}
+	}
+	setInt := func(name string, value *int, configValue int) {
+		if !set[name] {
+			*value = configValue
+		}
+	}
+	setBool := func(name string, value *bool, configValue bool) {
+		if !set[name] {
+			*value = configValue
+		}
+	}
+	setString("host", &o.Host, s.Host)
+	setInt("http-port", &o.HTTPPort, s.HTTPPort)
+	setInt("https-port", &o.HTTPSPort, s.HTTPSPort)
+	setString("telemetry-host", &o.TelemetryHost, s.TelemetryHost)
+	setInt("http-telemetry-port", &o.HTTPTelemetryPort, s.HTTPTelemetryPort)
+	setInt("https-telemetry-port", &o.HTTPSTelemetryPort, s.HTTPSTelemetryPort)
+	setString("tls-crt-file", &o.TLSCrtFile, s.TLSCertFile)
+	setString("tls-key-file", &o.TLSKeyFile, s.TLSKeyFile)
+	setString("client-ca-file", &o.ClientCAFile, s.ClientCAFile)
+	if !set["allowed-client-names"] {
+		o.AllowedClientNames = s.AllowedClientNames
+	}
+	setBool("enable-gzip-encoding", &o.EnableGZIPEncoding, s.EnableGZIPEncoding)
+	setBool("wait-for-cache-sync", &o.WaitForCacheSync, s.WaitForCacheSync)
+	setBool("enable-auth", &o.EnableAuth, s.Auth.Enabled)
+	setString("client-certificate-user", &o.ClientCertificateUser, s.Auth.ClientCertificateUser)
+	if !set["auth-cache-ttl"] && s.Auth.CacheTTL != nil {
+		o.AuthCacheTTL = s.Auth.CacheTTL.Duration
+	}
+	if !set["auth-deny-cache-ttl"] && s.Auth.DenyCacheTTL != nil {
+		o.AuthDenyCacheTTL = s.Auth.DenyCacheTTL.Duration
+	}
+}
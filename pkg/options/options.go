@@ -7,9 +7,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 	koptions "k8s.io/kube-state-metrics/pkg/options"
+
+	"github.com/stolostron/clusterlifecycle-state-metrics/pkg/config"
 )
 
 type Options struct {
@@ -34,6 +38,38 @@ type Options struct {
 	EnableGZIPEncoding       bool
 	EnableLeaderElection     bool
 	ControllerMetricsAddress string
+	RefreshWorkers           int
+	WaitForCacheSync         bool
+	EnableAuth               bool
+	AuthCacheTTL             time.Duration
+	AuthDenyCacheTTL         time.Duration
+	ClientCAFile             string
+	AllowedClientNames       []string
+	ClientCertificateUser    string
+	Once                     bool
+	OnceOutput               string
+	OnceFormat               string
+	// HubKubeconfigs are the kubeconfig files of the hubs whose metrics are served together
+	HubKubeconfigs []string
+	// HubKubeconfigDir is the directory of the kubeconfigs of the hubs whose metrics are served together
+	HubKubeconfigDir string
+
+	// Config is the path of the configuration file
+	Config string
+	// Selectors are the label selectors of the watched resources indexed by collector
+	Selectors map[string]string
+	// ManagedClusterLabels selects the exposed labels of the managed clusters
+	ManagedClusterLabels config.LabelsConfig
+	// ManagedClusterInfo configures the label values of the info metrics of the managed clusters
+	ManagedClusterInfo config.ClusterInfoConfig
+	// ManagedClusterAnnotationAllowlist are the key patterns of the exposed annotations of the managed clusters
+	ManagedClusterAnnotationAllowlist []string
+	// TimestampMetricsEnabled overrides the collect-timestamp-metrics key of the ConfigMap if set
+	TimestampMetricsEnabled *bool
+	// CustomResources configures the state metrics of custom resources
+	CustomResources []config.CustomResourceConfig
+	// SeriesLimits limits the number of series of the metric families
+	SeriesLimits config.LimitsConfig
 }
 
 func NewOptions() *Options {
@@ -73,7 +109,8 @@ func (o *Options) AddFlags() {
 	flag.Var(&o.MetricWhitelist, "metric-whitelist", "Comma-separated list of metrics to be exposed. The whitelist and blacklist are mutually exclusive.")
 	flag.Var(&o.MetricBlacklist, "metric-blacklist", "Comma-separated list of metrics not to be enabled. The whitelist and blacklist are mutually exclusive.")
 	flag.BoolVar(&o.Version, "version", false, "openshift-state-metrics build version information")
-	flag.StringVar(&o.HubType, "hub-type", "", `The type of the hub (mce|acm|stolostron-engine|stolostron).`)
+	flag.StringVar(&o.HubType, "hub-type", "",
+		`The type of the hub (mce|acm|stolostron-engine|stolostron). Detected from the MultiClusterHub or MultiClusterEngine of the hub if not set.`)
 
 	flag.BoolVar(&o.EnableGZIPEncoding, "enable-gzip-encoding", false, "Gzip responses when requested by clients via 'Accept-Encoding: gzip' header.")
 	flag.BoolVar(&o.EnableLeaderElection, "leader-elect", true,
@@ -83,6 +120,58 @@ func (o *Options) AddFlags() {
 	// of the controller runtime metrics server will conflict with the clusterlifecycle-state-metrics server's port.
 	flag.StringVar(&o.ControllerMetricsAddress, "controller-metrics-bind-address", "0", "The address the metrics"+
 		"endpoint binds to. Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
+	flag.IntVar(&o.RefreshWorkers, "refresh-workers", 2,
+		"The number of workers refreshing the metrics once the cluster ID, import timestamps or hibernating state of a cluster is changed.")
+	flag.BoolVar(&o.WaitForCacheSync, "wait-for-cache-sync", false,
+		"Respond 503 on the metrics path until every watched resource has completed its initial list.")
+	flag.BoolVar(&o.EnableAuth, "enable-auth", false,
+		"Authenticate the bearer token of the requests to the https ports with a TokenReview and authorize them "+
+			"with a SubjectAccessReview on the non-resource URL, except for the health probes.")
+	flag.DurationVar(&o.AuthCacheTTL, "auth-cache-ttl", 2*time.Minute,
+		"The time to cache the authenticated tokens and the allowed requests, 0 disables the cache.")
+	flag.DurationVar(&o.AuthDenyCacheTTL, "auth-deny-cache-ttl", 10*time.Second,
+		"The time to cache the denied requests, 0 disables the cache.")
+	flag.StringVar(&o.ClientCAFile, "client-ca-file", "",
+		"CA certificate file path. If set, the https ports require a client certificate signed by the CA.")
+	flag.Func("allowed-client-names",
+		"Comma-separated list of the common names or subject alternative names of the client certificates "+
+			"allowed by the https ports. Defaults to any client certificate signed by the client CA.",
+		func(value string) error {
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					o.AllowedClientNames = append(o.AllowedClientNames, name)
+				}
+			}
+			return nil
+		})
+	flag.StringVar(&o.ClientCertificateUser, "client-certificate-user", "",
+		"The user the requests with a client certificate signed by the client CA and without bearer token are authorized as "+
+			"with --enable-auth. The subject of the client certificate is not used as the user. "+
+			"If not set, these requests are not authenticated.")
+	flag.BoolVar(&o.Once, "once", false,
+		"List every watched resource once, write the metrics to the once output and exit instead of serving them. "+
+			"Exits with a nonzero code if a list fails.")
+	flag.StringVar(&o.OnceOutput, "once-output", "-", `The file the metrics are written to with --once, "-" for stdout.`)
+	flag.StringVar(&o.OnceFormat, "once-format", "text", "The format of the metrics written with --once (text|openmetrics|json).")
+	flag.Func("hub-kubeconfigs",
+		"Comma-separated list of the kubeconfig files of hubs. If set, the union of the metrics of the hubs is served, "+
+			"and the hub_cluster_id label is added to the series without it.",
+		func(value string) error {
+			for _, file := range strings.Split(value, ",") {
+				if file = strings.TrimSpace(file); file != "" {
+					o.HubKubeconfigs = append(o.HubKubeconfigs, file)
+				}
+			}
+			return nil
+		})
+	flag.StringVar(&o.HubKubeconfigDir, "hub-kubeconfig-dir", "",
+		"Directory of the kubeconfigs of hubs, either files or mounted secrets with a kubeconfig key. "+
+			"If set, the union of the metrics of the hubs is served with the hubs of --hub-kubeconfigs.")
+	flag.IntVar(&o.SeriesLimits.SeriesPerFamily, "series-limit-per-family", 0,
+		"The maximum number of series of each metric family, 0 for no limit. The new series beyond it are dropped.")
+	flag.StringVar(&o.Config, "config", "",
+		"Path of the configuration file. Defaults to the config.yaml key of the clusterlifecycle-state-metrics-config "+
+			"ConfigMap. The command-line flags take precedence over the configuration.")
 	klog.Info("End add args")
 }
 
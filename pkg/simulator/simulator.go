@@ -0,0 +1,465 @@
+// Copyright Contributors to the Open Cluster Management project
+
+// Package simulator simulates the managed clusters of a hub with their
+// addons and ManifestWorks, and their churn, for the scale and soak tests of
+// the collectors.
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	mciv1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// ChurnType is the type of a change of the simulated hub.
+type ChurnType string
+
+const (
+	// ChurnJoin is a managed cluster joining the hub with its addons, ManifestWorks and ClusterDeployment
+	ChurnJoin ChurnType = "Join"
+	// ChurnLeave is a managed cluster detached from the hub with its addons, ManifestWorks and ClusterDeployment
+	ChurnLeave ChurnType = "Leave"
+	// ChurnAvailability is a managed cluster becoming available or unknown
+	ChurnAvailability ChurnType = "Availability"
+	// ChurnHibernation is a managed cluster hibernating or resuming
+	ChurnHibernation ChurnType = "Hibernation"
+	// ChurnClusterID is the ID of a managed cluster changing, such as once it is reinstalled
+	ChurnClusterID ChurnType = "ClusterID"
+	// ChurnAddOnAvailability is an addon becoming available or unavailable
+	ChurnAddOnAvailability ChurnType = "AddOnAvailability"
+	// ChurnWorkApplied is a ManifestWork being applied or failing to apply
+	ChurnWorkApplied ChurnType = "WorkApplied"
+)
+
+// churnWeights are the relative frequencies of the changes, the availability
+// and conditions flapping more often than the clusters join or leave.
+var churnWeights = []struct {
+	churn  ChurnType
+	weight int
+}{
+	{ChurnAvailability, 30},
+	{ChurnAddOnAvailability, 25},
+	{ChurnWorkApplied, 25},
+	{ChurnHibernation, 10},
+	{ChurnClusterID, 5},
+	// a cluster joins or leaves, so the number of clusters stays close to Options.Clusters
+	{ChurnJoin, 5},
+}
+
+var addOnNames = []string{
+	"application-manager",
+	"cert-policy-controller",
+	"cluster-proxy",
+	"config-policy-controller",
+	"governance-policy-framework",
+	"managed-serviceaccount",
+	"search-collector",
+	"work-manager",
+}
+
+var (
+	clusterDeploymentGVK = schema.GroupVersionKind{Group: "hive.openshift.io", Version: "v1", Kind: "ClusterDeployment"}
+	clusterDeploymentGVR = clusterDeploymentGVK.GroupVersion().WithResource("clusterdeployments")
+)
+
+// Options configures the simulated hub.
+type Options struct {
+	// Clusters is the initial number of managed clusters
+	Clusters int
+	// AddOnsPerCluster is the number of ManagedClusterAddOns of each managed cluster
+	AddOnsPerCluster int
+	// WorksPerCluster is the number of ManifestWorks of each managed cluster
+	WorksPerCluster int
+	// Seed seeds the churn, so a simulation is reproducible
+	Seed int64
+}
+
+// Event is a change of an object of the simulated hub.
+type Event struct {
+	Type   watch.EventType
+	Churn  ChurnType
+	Object runtime.Object
+}
+
+// managedCluster is a simulated managed cluster with its objects.
+type managedCluster struct {
+	cluster           *mcv1.ManagedCluster
+	clusterDeployment *unstructured.Unstructured
+	addons            []*addonv1alpha1.ManagedClusterAddOn
+	works             []*workv1.ManifestWork
+}
+
+// Hub simulates the managed clusters of a hub. It is not safe for concurrent use.
+type Hub struct {
+	opts  Options
+	rand  *rand.Rand
+	clock time.Time
+	// clusters are the simulated managed clusters indexed by name
+	clusters map[string]*managedCluster
+	// names are the sorted names of the clusters, so the churn only depends on the seed
+	names []string
+	// nextIndex is the index of the next cluster to join
+	nextIndex int
+}
+
+// NewHub returns a new simulated hub with the given options.
+func NewHub(opts Options) *Hub {
+	h := &Hub{
+		opts:     opts,
+		rand:     rand.New(rand.NewSource(opts.Seed)), // #nosec G404 -- the churn is not security sensitive
+		clock:    time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		clusters: map[string]*managedCluster{},
+	}
+	for i := 0; i < opts.Clusters; i++ {
+		h.join()
+	}
+	return h
+}
+
+// Len returns the number of managed clusters of the hub.
+func (h *Hub) Len() int {
+	return len(h.names)
+}
+
+// Objects returns copies of the ManagedClusters, ClusterDeployments,
+// ManagedClusterAddOns and ManifestWorks of the hub.
+func (h *Hub) Objects() []runtime.Object {
+	objects := []runtime.Object{}
+	for _, name := range h.names {
+		objects = append(objects, h.clusters[name].objects()...)
+	}
+	return objects
+}
+
+// Next simulates a change of the hub, and returns the events of the changed
+// objects. A cluster joining or leaving the hub changes several objects.
+func (h *Hub) Next() []Event {
+	h.clock = h.clock.Add(time.Second)
+	if len(h.names) == 0 {
+		return h.join()
+	}
+
+	churn := h.nextChurn()
+	mc := h.clusters[h.names[h.rand.Intn(len(h.names))]]
+	switch churn {
+	case ChurnJoin:
+		if len(h.names) > h.opts.Clusters {
+			return h.leave(mc)
+		}
+		return h.join()
+	case ChurnAvailability:
+		status := metav1.ConditionTrue
+		if getConditionStatus(mc.cluster.Status.Conditions, mcv1.ManagedClusterConditionAvailable) == metav1.ConditionTrue {
+			status = metav1.ConditionUnknown
+		}
+		h.setCondition(&mc.cluster.Status.Conditions, mcv1.ManagedClusterConditionAvailable, status)
+		return modified(churn, mc.cluster)
+	case ChurnHibernation:
+		status := metav1.ConditionTrue
+		if isHibernating(mc.clusterDeployment) {
+			status = metav1.ConditionFalse
+		}
+		h.setHibernating(mc.clusterDeployment, status)
+		return modified(churn, mc.clusterDeployment)
+	case ChurnClusterID:
+		// only the OpenShift clusters have a cluster ID label
+		if _, ok := mc.cluster.Labels[mciv1beta1.LabelClusterID]; !ok {
+			return nil
+		}
+		mc.cluster.Labels[mciv1beta1.LabelClusterID] = h.newUUID()
+		return modified(churn, mc.cluster)
+	case ChurnAddOnAvailability:
+		if len(mc.addons) == 0 {
+			return nil
+		}
+		addon := mc.addons[h.rand.Intn(len(mc.addons))]
+		h.toggleCondition(&addon.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionAvailable)
+		return modified(churn, addon)
+	case ChurnWorkApplied:
+		if len(mc.works) == 0 {
+			return nil
+		}
+		work := mc.works[h.rand.Intn(len(mc.works))]
+		h.toggleCondition(&work.Status.Conditions, workv1.WorkApplied)
+		return modified(churn, work)
+	}
+	return nil
+}
+
+// nextChurn returns a random churn type according to churnWeights.
+func (h *Hub) nextChurn() ChurnType {
+	total := 0
+	for _, c := range churnWeights {
+		total += c.weight
+	}
+	n := h.rand.Intn(total)
+	for _, c := range churnWeights {
+		if n < c.weight {
+			return c.churn
+		}
+		n -= c.weight
+	}
+	return ChurnAvailability
+}
+
+// join adds a new managed cluster to the hub, and returns the events of its objects.
+func (h *Hub) join() []Event {
+	name := fmt.Sprintf("cluster-%05d", h.nextIndex)
+	h.nextIndex++
+
+	mc := &managedCluster{
+		cluster:           h.newManagedCluster(name),
+		clusterDeployment: h.newClusterDeployment(name),
+	}
+	for i := 0; i < h.opts.AddOnsPerCluster; i++ {
+		mc.addons = append(mc.addons, h.newManagedClusterAddOn(name, i))
+	}
+	for i := 0; i < h.opts.WorksPerCluster; i++ {
+		mc.works = append(mc.works, h.newManifestWork(name, i))
+	}
+
+	h.clusters[name] = mc
+	index := sort.SearchStrings(h.names, name)
+	h.names = append(h.names, "")
+	copy(h.names[index+1:], h.names[index:])
+	h.names[index] = name
+
+	events := []Event{}
+	for _, obj := range mc.objects() {
+		events = append(events, Event{Type: watch.Added, Churn: ChurnJoin, Object: obj})
+	}
+	return events
+}
+
+// leave removes a managed cluster from the hub, and returns the events of its
+// objects, the ManagedCluster being deleted last.
+func (h *Hub) leave(mc *managedCluster) []Event {
+	name := mc.cluster.Name
+	delete(h.clusters, name)
+	index := sort.SearchStrings(h.names, name)
+	h.names = append(h.names[:index], h.names[index+1:]...)
+
+	events := []Event{}
+	objects := mc.objects()
+	for i := len(objects) - 1; i >= 0; i-- {
+		events = append(events, Event{Type: watch.Deleted, Churn: ChurnLeave, Object: objects[i]})
+	}
+	return events
+}
+
+// objects returns copies of the objects of the cluster, the ManagedCluster first.
+func (mc *managedCluster) objects() []runtime.Object {
+	objects := []runtime.Object{mc.cluster.DeepCopy(), mc.clusterDeployment.DeepCopy()}
+	for _, addon := range mc.addons {
+		objects = append(objects, addon.DeepCopy())
+	}
+	for _, work := range mc.works {
+		objects = append(objects, work.DeepCopy())
+	}
+	return objects
+}
+
+func modified(churn ChurnType, obj runtime.Object) []Event {
+	return []Event{{Type: watch.Modified, Churn: churn, Object: obj.DeepCopyObject()}}
+}
+
+func (h *Hub) newManagedCluster(name string) *mcv1.ManagedCluster {
+	labels := map[string]string{
+		"name":                         name,
+		clusterv1beta2.ClusterSetLabel: fmt.Sprintf("clusterset-%d", h.rand.Intn(4)),
+	}
+	claims := []mcv1.ManagedClusterClaim{
+		{Name: "id.k8s.io", Value: name},
+	}
+	// most managed clusters are OpenShift clusters with a cluster ID
+	if h.rand.Intn(10) < 8 {
+		labels[mciv1beta1.LabelKubeVendor] = string(mciv1beta1.KubeVendorOpenShift)
+		labels[mciv1beta1.LabelCloudVendor] = string(mciv1beta1.CloudVendorAWS)
+		labels[mciv1beta1.LabelClusterID] = h.newUUID()
+		labels[mciv1beta1.OCPVersion] = fmt.Sprintf("4.%d.%d", 14+h.rand.Intn(4), h.rand.Intn(30))
+		claims = append(claims, mcv1.ManagedClusterClaim{Name: "product.open-cluster-management.io", Value: "OpenShift"})
+	} else {
+		labels[mciv1beta1.LabelKubeVendor] = "EKS"
+		labels[mciv1beta1.LabelCloudVendor] = string(mciv1beta1.CloudVendorAWS)
+		claims = append(claims,
+			mcv1.ManagedClusterClaim{Name: "kubeversion.open-cluster-management.io", Value: "v1.29.3-eks"},
+			mcv1.ManagedClusterClaim{Name: "product.open-cluster-management.io", Value: "EKS"})
+	}
+
+	cluster := &mcv1.ManagedCluster{
+		TypeMeta: metav1.TypeMeta{APIVersion: mcv1.GroupVersion.String(), Kind: "ManagedCluster"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			UID:               ktypes.UID(h.newUUID()),
+			Labels:            labels,
+			Annotations:       map[string]string{"open-cluster-management/created-via": "hive"},
+			CreationTimestamp: metav1.NewTime(h.clock),
+		},
+		Spec: mcv1.ManagedClusterSpec{HubAcceptsClient: true},
+		Status: mcv1.ManagedClusterStatus{
+			Capacity: mcv1.ResourceList{
+				"core_worker":   *resource.NewQuantity(int64(4*(1+h.rand.Intn(16))), resource.DecimalSI),
+				"socket_worker": *resource.NewQuantity(int64(1+h.rand.Intn(4)), resource.DecimalSI),
+			},
+			ClusterClaims: claims,
+		},
+	}
+	h.setCondition(&cluster.Status.Conditions, mcv1.ManagedClusterConditionHubAccepted, metav1.ConditionTrue)
+	h.setCondition(&cluster.Status.Conditions, mcv1.ManagedClusterConditionJoined, metav1.ConditionTrue)
+	h.setCondition(&cluster.Status.Conditions, mcv1.ManagedClusterConditionAvailable, metav1.ConditionTrue)
+	return cluster
+}
+
+func (h *Hub) newClusterDeployment(name string) *unstructured.Unstructured {
+	cd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": name,
+		},
+		"spec": map[string]interface{}{
+			"baseDomain":  "example.com",
+			"clusterName": name,
+		},
+	}}
+	cd.SetGroupVersionKind(clusterDeploymentGVK)
+	cd.SetUID(ktypes.UID(h.newUUID()))
+	h.setHibernating(cd, metav1.ConditionFalse)
+	return cd
+}
+
+func (h *Hub) newManagedClusterAddOn(clusterName string, index int) *addonv1alpha1.ManagedClusterAddOn {
+	name := fmt.Sprintf("addon-%d", index)
+	if index < len(addOnNames) {
+		name = addOnNames[index]
+	}
+	addon := &addonv1alpha1.ManagedClusterAddOn{
+		TypeMeta: metav1.TypeMeta{APIVersion: addonv1alpha1.GroupVersion.String(), Kind: "ManagedClusterAddOn"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         clusterName,
+			UID:               ktypes.UID(h.newUUID()),
+			CreationTimestamp: metav1.NewTime(h.clock),
+		},
+		Spec: addonv1alpha1.ManagedClusterAddOnSpec{InstallNamespace: "open-cluster-management-agent-addon"},
+	}
+	h.setCondition(&addon.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionAvailable, metav1.ConditionTrue)
+	h.setCondition(&addon.Status.Conditions, addonv1alpha1.ManagedClusterAddOnConditionDegraded, metav1.ConditionFalse)
+	return addon
+}
+
+func (h *Hub) newManifestWork(clusterName string, index int) *workv1.ManifestWork {
+	work := &workv1.ManifestWork{
+		TypeMeta: metav1.TypeMeta{APIVersion: workv1.GroupVersion.String(), Kind: "ManifestWork"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("%s-work-%d", clusterName, index),
+			Namespace:         clusterName,
+			UID:               ktypes.UID(h.newUUID()),
+			CreationTimestamp: metav1.NewTime(h.clock),
+		},
+	}
+	h.setCondition(&work.Status.Conditions, workv1.WorkApplied, metav1.ConditionTrue)
+	h.setCondition(&work.Status.Conditions, workv1.WorkAvailable, metav1.ConditionTrue)
+	return work
+}
+
+// newUUID returns a random UUID generated from the seed.
+func (h *Hub) newUUID() string {
+	return fmt.Sprintf("%08x-%04x-4%03x-8%03x-%012x",
+		h.rand.Uint32(), h.rand.Intn(1<<16), h.rand.Intn(1<<12), h.rand.Intn(1<<12), h.rand.Int63n(1<<48))
+}
+
+// setCondition sets the status of a condition at the current time of the hub.
+func (h *Hub) setCondition(conditions *[]metav1.Condition, conditionType string, status metav1.ConditionStatus) {
+	for i := range *conditions {
+		if (*conditions)[i].Type == conditionType {
+			(*conditions)[i].Status = status
+			(*conditions)[i].LastTransitionTime = metav1.NewTime(h.clock)
+			return
+		}
+	}
+	*conditions = append(*conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             "Simulated",
+		LastTransitionTime: metav1.NewTime(h.clock),
+	})
+}
+
+// toggleCondition sets a true condition to false and the other way round.
+func (h *Hub) toggleCondition(conditions *[]metav1.Condition, conditionType string) {
+	status := metav1.ConditionTrue
+	if getConditionStatus(*conditions, conditionType) == metav1.ConditionTrue {
+		status = metav1.ConditionFalse
+	}
+	h.setCondition(conditions, conditionType, status)
+}
+
+func getConditionStatus(conditions []metav1.Condition, conditionType string) metav1.ConditionStatus {
+	for _, c := range conditions {
+		if c.Type == conditionType {
+			return c.Status
+		}
+	}
+	return metav1.ConditionUnknown
+}
+
+// setHibernating sets the Hibernating condition of a ClusterDeployment.
+func (h *Hub) setHibernating(cd *unstructured.Unstructured, status metav1.ConditionStatus) {
+	powerState := "Running"
+	if status == metav1.ConditionTrue {
+		powerState = "Hibernating"
+	}
+	_ = unstructured.SetNestedField(cd.Object, powerState, "spec", "powerState")
+	_ = unstructured.SetNestedSlice(cd.Object, []interface{}{
+		map[string]interface{}{
+			"type":               "Hibernating",
+			"status":             string(status),
+			"reason":             powerState,
+			"lastTransitionTime": h.clock.Format(time.RFC3339),
+		},
+	}, "status", "conditions")
+}
+
+func isHibernating(cd *unstructured.Unstructured) bool {
+	powerState, _, _ := unstructured.NestedString(cd.Object, "spec", "powerState")
+	return powerState == "Hibernating"
+}
+
+// Target applies the events of a simulated hub.
+type Target interface {
+	Apply(ctx context.Context, event Event) error
+}
+
+// Run applies a change of the hub to the target at every interval, until
+// the context is done or the target fails to apply an event.
+func (h *Hub) Run(ctx context.Context, target Target, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			for _, event := range h.Next() {
+				if err := target.Apply(ctx, event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
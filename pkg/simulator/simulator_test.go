@@ -0,0 +1,103 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package simulator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+func countObjects(objects []interface{}) map[string]int {
+	counts := map[string]int{}
+	for _, obj := range objects {
+		switch obj.(type) {
+		case *mcv1.ManagedCluster:
+			counts["clusters"]++
+		case *addonv1alpha1.ManagedClusterAddOn:
+			counts["addons"]++
+		case *workv1.ManifestWork:
+			counts["works"]++
+		case *unstructured.Unstructured:
+			counts["clusterdeployments"]++
+		}
+	}
+	return counts
+}
+
+func Test_NewHub(t *testing.T) {
+	hub := NewHub(Options{Clusters: 10, AddOnsPerCluster: 3, WorksPerCluster: 2, Seed: 1})
+	objects := []interface{}{}
+	for _, obj := range hub.Objects() {
+		objects = append(objects, obj)
+	}
+
+	want := map[string]int{"clusters": 10, "addons": 30, "works": 20, "clusterdeployments": 10}
+	if got := countObjects(objects); !reflect.DeepEqual(got, want) {
+		t.Errorf("want objects %v, but got %v", want, got)
+	}
+	if hub.Len() != 10 {
+		t.Errorf("want 10 clusters, but got %d", hub.Len())
+	}
+}
+
+func Test_Hub_Next(t *testing.T) {
+	opts := Options{Clusters: 20, AddOnsPerCluster: 2, WorksPerCluster: 2, Seed: 42}
+	hub, other := NewHub(opts), NewHub(opts)
+	if !reflect.DeepEqual(hub.Objects(), other.Objects()) {
+		t.Fatal("want the same objects with the same seed")
+	}
+
+	// the objects of the hub are applied to a store as a reflector would, so
+	// the events are consistent with the objects
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, obj := range hub.Objects() {
+		if err := store.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target := &StoreTarget{
+		ManagedClusters:      store,
+		ManagedClusterAddOns: store,
+		ManifestWorks:        store,
+		ClusterDeployments:   store,
+	}
+
+	churns := map[ChurnType]int{}
+	for i := 0; i < 2000; i++ {
+		events := hub.Next()
+		if !reflect.DeepEqual(events, other.Next()) {
+			t.Fatalf("want the same events with the same seed at step %d", i)
+		}
+		for _, event := range events {
+			churns[event.Churn]++
+			if err := target.Apply(context.TODO(), event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, churn := range []ChurnType{ChurnJoin, ChurnLeave, ChurnAvailability, ChurnHibernation,
+		ChurnClusterID, ChurnAddOnAvailability, ChurnWorkApplied} {
+		if churns[churn] == 0 {
+			t.Errorf("want %s events, but got none", churn)
+		}
+	}
+	if hub.Len() < opts.Clusters-1 || hub.Len() > opts.Clusters+1 {
+		t.Errorf("want about %d clusters, but got %d", opts.Clusters, hub.Len())
+	}
+
+	objects := []interface{}{}
+	for _, obj := range hub.Objects() {
+		objects = append(objects, obj)
+	}
+	if got, want := countObjects(store.List()), countObjects(objects); !reflect.DeepEqual(got, want) {
+		t.Errorf("want the store with objects %v, but got %v", want, got)
+	}
+}
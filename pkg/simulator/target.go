@@ -0,0 +1,206 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package simulator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	addonclient "open-cluster-management.io/api/client/addon/clientset/versioned"
+	clusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
+	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// StoreTarget applies the events of a simulated hub to stores, as the
+// reflectors watching the hub would.
+type StoreTarget struct {
+	ManagedClusters      cache.Store
+	ManagedClusterAddOns cache.Store
+	ManifestWorks        cache.Store
+	ClusterDeployments   cache.Store
+}
+
+// Apply adds, updates or deletes the object of the event in its store. The
+// objects without a store are ignored.
+func (t *StoreTarget) Apply(_ context.Context, event Event) error {
+	var store cache.Store
+	switch event.Object.(type) {
+	case *mcv1.ManagedCluster:
+		store = t.ManagedClusters
+	case *addonv1alpha1.ManagedClusterAddOn:
+		store = t.ManagedClusterAddOns
+	case *workv1.ManifestWork:
+		store = t.ManifestWorks
+	case *unstructured.Unstructured:
+		store = t.ClusterDeployments
+	}
+	if store == nil {
+		return nil
+	}
+
+	switch event.Type {
+	case watch.Added:
+		return store.Add(event.Object)
+	case watch.Modified:
+		return store.Update(event.Object)
+	case watch.Deleted:
+		return store.Delete(event.Object)
+	}
+	return fmt.Errorf("unexpected event type %q", event.Type)
+}
+
+// ClientTarget applies the events of a simulated hub to a hub through its
+// API, such as an envtest environment with the CRDs of the resources. The
+// namespaces of the clusters are created once they join, and are left once
+// they leave.
+type ClientTarget struct {
+	KubeClient    kubernetes.Interface
+	ClusterClient clusterclient.Interface
+	AddOnClient   addonclient.Interface
+	WorkClient    workclient.Interface
+	DynamicClient dynamic.Interface
+}
+
+// NewClientTarget returns a new ClientTarget with the clients of the given config.
+func NewClientTarget(config *rest.Config) (*ClientTarget, error) {
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	clusterClient, err := clusterclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	addOnClient, err := addonclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	workClient, err := workclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientTarget{
+		KubeClient:    kubeClient,
+		ClusterClient: clusterClient,
+		AddOnClient:   addOnClient,
+		WorkClient:    workClient,
+		DynamicClient: dynamicClient,
+	}, nil
+}
+
+// Apply creates, updates or deletes the object of the event on the hub, and
+// updates its status. The objects which are already deleted are ignored.
+func (t *ClientTarget) Apply(ctx context.Context, event Event) error {
+	switch obj := event.Object.(type) {
+	case *mcv1.ManagedCluster:
+		if event.Type == watch.Added {
+			if err := t.createNamespace(ctx, obj.Name); err != nil {
+				return err
+			}
+		}
+		c := t.ClusterClient.ClusterV1().ManagedClusters()
+		return applyEvent(ctx, event.Type, obj, resourceClient[*mcv1.ManagedCluster]{
+			get: c.Get, create: c.Create, update: c.Update, updateStatus: c.UpdateStatus, delete: c.Delete,
+		})
+	case *addonv1alpha1.ManagedClusterAddOn:
+		c := t.AddOnClient.AddonV1alpha1().ManagedClusterAddOns(obj.Namespace)
+		return applyEvent(ctx, event.Type, obj, resourceClient[*addonv1alpha1.ManagedClusterAddOn]{
+			get: c.Get, create: c.Create, update: c.Update, updateStatus: c.UpdateStatus, delete: c.Delete,
+		})
+	case *workv1.ManifestWork:
+		c := t.WorkClient.WorkV1().ManifestWorks(obj.Namespace)
+		return applyEvent(ctx, event.Type, obj, resourceClient[*workv1.ManifestWork]{
+			get: c.Get, create: c.Create, update: c.Update, updateStatus: c.UpdateStatus, delete: c.Delete,
+		})
+	case *unstructured.Unstructured:
+		c := t.DynamicClient.Resource(clusterDeploymentGVR).Namespace(obj.GetNamespace())
+		return applyEvent(ctx, event.Type, obj, resourceClient[*unstructured.Unstructured]{
+			get: func(ctx context.Context, name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+				return c.Get(ctx, name, opts)
+			},
+			create: func(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions) (*unstructured.Unstructured, error) {
+				return c.Create(ctx, obj, opts)
+			},
+			update: func(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+				return c.Update(ctx, obj, opts)
+			},
+			updateStatus: c.UpdateStatus,
+			delete: func(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+				return c.Delete(ctx, name, opts)
+			},
+		})
+	}
+	return fmt.Errorf("unexpected object type: %T", event.Object)
+}
+
+func (t *ClientTarget) createNamespace(ctx context.Context, name string) error {
+	_, err := t.KubeClient.CoreV1().Namespaces().Create(ctx,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// resourceClient are the functions of the typed client of a resource.
+type resourceClient[T metav1.Object] struct {
+	get          func(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	create       func(ctx context.Context, obj T, opts metav1.CreateOptions) (T, error)
+	update       func(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+	updateStatus func(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+	delete       func(ctx context.Context, name string, opts metav1.DeleteOptions) error
+}
+
+// applyEvent creates or updates the object with its status, or deletes it.
+// The resource version of the object is set to the one on the hub, so the
+// simulated object overrides it.
+func applyEvent[T metav1.Object](ctx context.Context, eventType watch.EventType, obj T, c resourceClient[T]) error {
+	switch eventType {
+	case watch.Added:
+		obj.SetUID("")
+		created, err := c.create(ctx, obj, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		obj.SetResourceVersion(created.GetResourceVersion())
+		_, err = c.updateStatus(ctx, obj, metav1.UpdateOptions{})
+		return err
+	case watch.Modified:
+		current, err := c.get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		obj.SetUID(current.GetUID())
+		obj.SetResourceVersion(current.GetResourceVersion())
+		updated, err := c.update(ctx, obj, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		obj.SetResourceVersion(updated.GetResourceVersion())
+		_, err = c.updateStatus(ctx, obj, metav1.UpdateOptions{})
+		return err
+	case watch.Deleted:
+		err := c.delete(ctx, obj.GetName(), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unexpected event type %q", eventType)
+}
@@ -0,0 +1,159 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kube-state-metrics/pkg/metric"
+	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
+	mcv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+)
+
+// updateGoldenEnv is the environment variable regenerating the golden files
+// instead of comparing them once set to 1, with
+// UPDATE_GOLDEN=1 go test ./pkg/generators/...
+// An environment variable is read instead of a flag, since a flag is only
+// defined in the test binaries importing this package.
+const updateGoldenEnv = "UPDATE_GOLDEN"
+
+var goldenDecoder runtime.Decoder
+
+func init() {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(mcv1.Install(scheme))
+	utilruntime.Must(addonv1alpha1.Install(scheme))
+	utilruntime.Must(workv1.Install(scheme))
+	goldenDecoder = serializer.NewCodecFactory(scheme).UniversalDeserializer()
+}
+
+// RunGoldenTests renders the metric families of the objects of each YAML file
+// of dir, and compares them with the golden file of the given name beside it:
+// the metrics of input.yaml are compared with input.name.golden. The golden
+// files are the exact exposition text, so a change of the order of the
+// labels fails the test. The golden files are regenerated once UPDATE_GOLDEN
+// is set to 1.
+func RunGoldenTests(t *testing.T, dir, name string, families []metric.FamilyGenerator) {
+	t.Helper()
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no YAML file in %s", dir)
+	}
+
+	for _, input := range inputs {
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			objects, err := DecodeObjects(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := RenderMetrics(families, objects)
+
+			golden := strings.TrimSuffix(input, ".yaml") + "." + name + ".golden"
+			if os.Getenv(updateGoldenEnv) == "1" {
+				if err := os.WriteFile(golden, got, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden) // #nosec G304 -- the golden files are in testdata
+			if err != nil {
+				t.Fatalf("%v, run the test with %s=1 to generate the golden file", err, updateGoldenEnv)
+			}
+			if !bytes.Equal(want, got) {
+				diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+					A:        difflib.SplitLines(string(want)),
+					B:        difflib.SplitLines(string(got)),
+					FromFile: golden,
+					ToFile:   "got",
+					Context:  2,
+				})
+				t.Errorf("unexpected metrics, run the test with %s=1 if expected:\n%s", updateGoldenEnv, diff)
+			}
+		})
+	}
+}
+
+// RenderMetrics renders the metric families of the objects in the exposition
+// text format, as the metrics stores write them: the header of each family is
+// followed by its metrics for each object in order.
+func RenderMetrics(families []metric.FamilyGenerator, objects []interface{}) []byte {
+	headers := metric.ExtractMetricFamilyHeaders(families)
+	generateFunc := metric.ComposeMetricGenFuncs(families)
+
+	generated := [][][]byte{}
+	for _, obj := range objects {
+		objFamilies := [][]byte{}
+		for _, f := range generateFunc(obj) {
+			objFamilies = append(objFamilies, f.ByteSlice())
+		}
+		generated = append(generated, objFamilies)
+	}
+
+	buf := &bytes.Buffer{}
+	for i, header := range headers {
+		buf.WriteString(header)
+		buf.WriteByte('\n')
+		for _, objFamilies := range generated {
+			buf.Write(objFamilies[i])
+		}
+	}
+	return buf.Bytes()
+}
+
+// DecodeObjects decodes the objects of a multi-document YAML file. The
+// ManagedClusters, ManagedClusterAddOns and ManifestWorks are decoded to
+// their types, and the other objects to unstructured objects.
+func DecodeObjects(path string) ([]interface{}, error) {
+	file, err := os.Open(path) // #nosec G304 -- the inputs are in testdata
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	objects := []interface{}{}
+	reader := yaml.NewYAMLReader(bufio.NewReader(file))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		obj, _, err := goldenDecoder.Decode(doc, nil, nil)
+		if runtime.IsNotRegisteredError(err) {
+			u := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal(doc, &u.Object); err != nil {
+				return nil, fmt.Errorf("cannot decode %s: %v", path, err)
+			}
+			obj, err = u, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode %s: %v", path, err)
+		}
+		objects = append(objects, obj)
+	}
+}